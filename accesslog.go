@@ -0,0 +1,116 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// accessLogFormats are the regexps of the predefined log formats. The
+// combined format extends the common one, whose regexp matches both.
+var accessLogFormats = map[string]string{
+	"common":   `^\S+ \S+ \S+ \[(?P<time>[^\]]+)\] "(?P<method>[A-Z]+) (?P<path>\S+)[^"]*" \d{3} \S+`,
+	"combined": `^\S+ \S+ \S+ \[(?P<time>[^\]]+)\] "(?P<method>[A-Z]+) (?P<path>\S+)[^"]*" \d{3} \S+ "[^"]*" "[^"]*"`,
+}
+
+// accessLogTime is the layout of the times of the common log format.
+const accessLogTime = "02/Jan/2006:15:04:05 -0700"
+
+// parseAccessLog reconstructs the requests of the access log read from
+// r, whose lines are matched by re. Its named groups are path, the
+// request URI, and optionally method and time, in the common log format
+// or RFC 3339. The requests are sent to base, copies of tmpl, and
+// scheduled at the pace of the log divided by speed. Identical requests
+// share a target, and the lines not matching re are skipped.
+func parseAccessLog(r io.Reader, re *regexp.Regexp, base string, speed float64, tmpl *fasthttp.Request) ([]boomer.Target, []boomer.Dispatch, error) {
+	groups := make(map[string]int)
+	for i, name := range re.SubexpNames() {
+		groups[name] = i
+	}
+	if _, ok := groups["path"]; !ok {
+		return nil, nil, fmt.Errorf("the access log regexp has no path group")
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	var targets []boomer.Target
+	var schedule []boomer.Dispatch
+	index := make(map[string]int)
+	var first time.Time
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		m := re.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		method, path := "GET", m[groups["path"]]
+		if i, ok := groups["method"]; ok && m[i] != "" {
+			method = strings.ToUpper(m[i])
+		}
+		var offset time.Duration
+		if i, ok := groups["time"]; ok {
+			t, err := time.Parse(accessLogTime, m[i])
+			if err != nil {
+				if t, err = time.Parse(time.RFC3339Nano, m[i]); err != nil {
+					return nil, nil, fmt.Errorf("access log line %d: invalid time %q", line, m[i])
+				}
+			}
+			if first.IsZero() {
+				first = t
+			}
+			// Out of order lines are sent right away.
+			if offset = time.Duration(float64(t.Sub(first)) / speed); offset < 0 {
+				offset = 0
+			}
+		}
+		key := method + " " + path
+		i, ok := index[key]
+		if !ok {
+			t := boomer.Target{Request: &fasthttp.Request{}}
+			tmpl.CopyTo(t.Request)
+			t.Request.Header.SetMethod(method)
+			t.Request.SetRequestURI(base + path)
+			i = len(targets)
+			index[key] = i
+			targets = append(targets, t)
+		}
+		schedule = append(schedule, boomer.Dispatch{Target: i, Offset: offset})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(schedule) == 0 {
+		return nil, nil, fmt.Errorf("no requests found in the access log")
+	}
+	return targets, schedule, nil
+}
+
+// parseSpeed parses a replay speed, e.g. 2x or 0.5.
+func parseSpeed(input string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(input), "x"), 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("could not parse the provided speed; input = %v", input)
+	}
+	return v, nil
+}
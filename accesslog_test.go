@@ -0,0 +1,77 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseAccessLog(t *testing.T) {
+	log := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326 "http://example.com/" "Mozilla/4.08"
+garbage
+127.0.0.1 - - [10/Oct/2000:13:55:38 -0700] "POST /form HTTP/1.1" 302 - "-" "curl/7.0"
+127.0.0.1 - - [10/Oct/2000:13:55:40 -0700] "GET /a.gif HTTP/1.1" 304 0 "-" "curl/7.0"
+`
+	re := regexp.MustCompile(accessLogFormats["combined"])
+	targets, schedule, err := parseAccessLog(strings.NewReader(log), re, "http://staging/", 2, &fasthttp.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0].Request.URI().String() != "http://staging/a.gif" || string(targets[1].Request.Header.Method()) != "POST" {
+		t.Errorf("Unexpected targets %v", targets)
+	}
+	expected := []boomer.Dispatch{{Target: 0}, {Target: 1, Offset: time.Second}, {Target: 0, Offset: 2 * time.Second}}
+	if len(schedule) != len(expected) {
+		t.Fatalf("Expected %v, found %v", expected, schedule)
+	}
+	for i := range expected {
+		if schedule[i] != expected[i] {
+			t.Errorf("Expected %v, found %v", expected, schedule)
+		}
+	}
+
+	// A custom regexp without time replays as fast as possible.
+	re = regexp.MustCompile(`^(?P<method>\w+) (?P<path>\S+)$`)
+	targets, schedule, err = parseAccessLog(strings.NewReader("GET /x\nDELETE /y\n"), re, "http://staging", 1, &fasthttp.Request{})
+	if err != nil || len(targets) != 2 || schedule[1].Offset != 0 {
+		t.Errorf("Unexpected replay of a custom log %v %v %v", targets, schedule, err)
+	}
+
+	if _, _, err := parseAccessLog(strings.NewReader("GET /x\n"), regexp.MustCompile(`(?P<method>\w+)`), "", 1, &fasthttp.Request{}); err == nil {
+		t.Errorf("Expected an error for a regexp without path")
+	}
+	if _, _, err := parseAccessLog(strings.NewReader("nothing\n"), re, "", 1, &fasthttp.Request{}); err == nil {
+		t.Errorf("Expected an error for a log without requests")
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	for input, expected := range map[string]float64{"2x": 2, "0.5": 0.5, "1x": 1} {
+		if v, err := parseSpeed(input); err != nil || v != expected {
+			t.Errorf("Expected %v for %q, found %v and %v", expected, input, v, err)
+		}
+	}
+	for _, bad := range []string{"0x", "-1", "fast"} {
+		if _, err := parseSpeed(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
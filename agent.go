@@ -0,0 +1,234 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sschepens/pla/boomer"
+)
+
+// defaultAgentAddr is the address `pla agent` listens on if none is given.
+const defaultAgentAddr = "localhost:7070"
+
+// agentResult is a boomer.Result on the wire, one JSON object per line of
+// the response of an agent.
+type agentResult struct {
+	Start         time.Time     `json:"start"`
+	Duration      time.Duration `json:"duration"`
+	StatusCode    int           `json:"status_code,omitempty"`
+	ContentLength int           `json:"content_length,omitempty"`
+	Err           string        `json:"error,omitempty"`
+	Attempts      int           `json:"attempts,omitempty"`
+	Shed          bool          `json:"shed,omitempty"`
+}
+
+// agent makes the runs posted by a controller to /run, with the
+// parameters of the rpc run method, and streams back their results.
+// Runs are made one at a time. With a token, the runs must be posted with
+// it as bearer token.
+type agent struct {
+	mu    sync.Mutex
+	token string
+}
+
+func (a *agent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/run" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if a.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+a.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var params rpcRunParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if params.Method == "" {
+		params.Method = "GET"
+	}
+	b, err := params.boomer()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	results := b.Results()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.Run()
+	}()
+	go func() {
+		// The controller went away, there is no one to report to.
+		select {
+		case <-r.Context().Done():
+			b.Stop()
+		case <-done:
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for res := range results {
+		ar := agentResult{
+			Start:         res.Start,
+			Duration:      res.Duration,
+			StatusCode:    res.StatusCode,
+			ContentLength: res.ContentLength,
+			Attempts:      res.Attempts,
+			Shed:          res.Shed,
+		}
+		if res.Err != nil {
+			ar.Err = res.Err.Error()
+		}
+		// Keep draining the results on write errors, the run waits for
+		// them to be received.
+		enc.Encode(ar)
+	}
+	<-done
+}
+
+// serveAgent listens on addr for the runs of a controller holding token.
+// Anyone reaching the agent could make it send requests anywhere: it only
+// listens beyond the loopback interface with a token.
+func serveAgent(addr, token string) error {
+	if token == "" && !loopbackAddr(addr) {
+		return fmt.Errorf("agent: listening on %s requires -agent-token", addr)
+	}
+	fmt.Fprintf(os.Stderr, "pla agent listening on %s\n", addr)
+	return http.ListenAndServe(addr, &agent{token: token})
+}
+
+// loopbackAddr reports whether addr, as "host:port", only listens on the
+// loopback interface.
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// split divides total into n parts differing by one at most.
+func split(total, n int) []int {
+	parts := make([]int, n)
+	for i := range parts {
+		parts[i] = total / n
+		if i < total%n {
+			parts[i]++
+		}
+	}
+	return parts
+}
+
+// runAgents fans the requests, the concurrency and the rate limit of
+// params out across the agents at addrs, authenticated with token, and
+// aggregates their results in the report of b.
+func runAgents(b *boomer.Boomer, addrs []string, token string, params rpcRunParams) *boomer.Report {
+	ns, cs, qs := split(params.N, len(addrs)), split(params.C, len(addrs)), split(params.Qps, len(addrs))
+	results := make(chan boomer.Result, params.C)
+	var wg sync.WaitGroup
+	wg.Add(len(addrs))
+	for i, addr := range addrs {
+		p := params
+		p.N, p.C, p.Qps = ns[i], cs[i], qs[i]
+		go func(addr string) {
+			defer wg.Done()
+			if err := streamAgent(addr, token, p, results); err != nil {
+				fmt.Fprintf(os.Stderr, "agent %s: %v\n", addr, err)
+			}
+		}(addr)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return b.Aggregate(results)
+}
+
+// streamAgent posts a run to the agent at addr and sends its results to
+// results.
+func streamAgent(addr, token string, params rpcRunParams, results chan<- boomer.Result) error {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(addr, "/")+"/run", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ar agentResult
+		if err := dec.Decode(&ar); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		res := boomer.Result{
+			Start:         ar.Start,
+			Duration:      ar.Duration,
+			StatusCode:    ar.StatusCode,
+			ContentLength: ar.ContentLength,
+			Attempts:      ar.Attempts,
+			Shed:          ar.Shed,
+		}
+		if ar.Err != "" {
+			res.Err = errors.New(ar.Err)
+		}
+		results <- res
+	}
+}
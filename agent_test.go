@@ -0,0 +1,103 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sschepens/pla/boomer"
+)
+
+func TestSplit(t *testing.T) {
+	if parts := split(10, 3); !reflect.DeepEqual(parts, []int{4, 3, 3}) {
+		t.Errorf("Expected [4 3 3], found %v", parts)
+	}
+	if parts := split(0, 2); !reflect.DeepEqual(parts, []int{0, 0}) {
+		t.Errorf("Expected [0 0], found %v", parts)
+	}
+}
+
+func TestRunAgents(t *testing.T) {
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+		if r.Header.Get("X-Test") != "yes" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	var addrs []string
+	for i := 0; i < 2; i++ {
+		a := httptest.NewServer(&agent{token: "s3cret"})
+		defer a.Close()
+		addrs = append(addrs, strings.TrimPrefix(a.URL, "http://"))
+	}
+	b := &boomer.Boomer{
+		N:        25,
+		C:        4,
+		Output:   "json",
+		Renderer: boomer.RendererFunc(func(io.Writer, *boomer.Report) error { return nil }),
+	}
+	report := runAgents(b, addrs, "s3cret", rpcRunParams{
+		URL:     server.URL,
+		Method:  "GET",
+		Headers: map[string]string{"X-Test": "yes"},
+		N:       25,
+		C:       4,
+	})
+	if count != 25 || report.StatusCodeDist[http.StatusOK] != 25 {
+		t.Errorf("Expected 25 successful requests, found %d sent and %v", count, report.StatusCodeDist)
+	}
+
+	// A failing agent does not prevent the others from reporting.
+	count = 0
+	report = runAgents(b, append(addrs[:1], "127.0.0.1:1"), "s3cret", rpcRunParams{URL: server.URL, Method: "GET", N: 25, C: 4})
+	if report.StatusCodeDist[http.StatusBadRequest] != 13 {
+		t.Errorf("Expected 13 requests from the reachable agent, found %v", report.StatusCodeDist)
+	}
+
+	// Agents refuse the runs of controllers without their token.
+	count = 0
+	report = runAgents(b, addrs, "wrong", rpcRunParams{URL: server.URL, Method: "GET", N: 25, C: 4})
+	if count != 0 || len(report.StatusCodeDist) != 0 {
+		t.Errorf("Expected no requests with a wrong token, found %d sent and %v", count, report.StatusCodeDist)
+	}
+}
+
+func TestLoopbackAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"localhost:7070": true,
+		"127.0.0.1:7070": true,
+		"[::1]:7070":     true,
+		":7070":          false,
+		"0.0.0.0:7070":   false,
+		"10.0.0.1:7070":  false,
+		"7070":           false,
+	} {
+		if got := loopbackAddr(addr); got != want {
+			t.Errorf("loopbackAddr(%q) = %v, expected %v", addr, got, want)
+		}
+	}
+	if err := serveAgent(":0", ""); err == nil {
+		t.Error("Expected an agent listening on every interface without a token to be refused")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// ABRenderer writes the summary of the report in the format of
+// ApacheBench, for the scripts parsing its output. The server and
+// document lines are printed if URL is set, the concurrency level and
+// the time per request if Concurrency is.
+type ABRenderer struct {
+	URL         string
+	Concurrency int
+}
+
+// Render implements Renderer.
+func (a ABRenderer) Render(w io.Writer, r *Report) error {
+	bw := bufio.NewWriter(w)
+	errs := errorCount(r)
+	var docLength int64
+	if r.Count > 0 {
+		docLength = r.SizeTotal / r.Count
+	}
+	if u, err := url.Parse(a.URL); a.URL != "" && err == nil {
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+		path := u.RequestURI()
+		fmt.Fprintf(bw, "Server Hostname:        %s\n", u.Hostname())
+		fmt.Fprintf(bw, "Server Port:            %s\n", port)
+		fmt.Fprintf(bw, "\nDocument Path:          %s\n", path)
+		fmt.Fprintf(bw, "Document Length:        %d bytes\n\n", docLength)
+	}
+	if a.Concurrency > 0 {
+		fmt.Fprintf(bw, "Concurrency Level:      %d\n", a.Concurrency)
+	}
+	fmt.Fprintf(bw, "Time taken for tests:   %.3f seconds\n", r.Total.Seconds())
+	fmt.Fprintf(bw, "Complete requests:      %d\n", r.Count+int64(errs))
+	fmt.Fprintf(bw, "Failed requests:        %d\n", errs)
+	non2xx := 0
+	for code, n := range r.StatusCodeDist {
+		if code < 200 || code >= 300 {
+			non2xx += n
+		}
+	}
+	if non2xx > 0 {
+		fmt.Fprintf(bw, "Non-2xx responses:      %d\n", non2xx)
+	}
+	fmt.Fprintf(bw, "Total transferred:      %d bytes\n", r.SizeTotal)
+	fmt.Fprintf(bw, "HTML transferred:       %d bytes\n", r.SizeTotal)
+	fmt.Fprintf(bw, "Requests per second:    %.2f [#/sec] (mean)\n", r.RPS)
+	if r.Count > 0 {
+		perRequest := r.Total.Seconds() * 1e3 / float64(r.Count)
+		if a.Concurrency > 0 {
+			fmt.Fprintf(bw, "Time per request:       %.3f [ms] (mean)\n", perRequest*float64(a.Concurrency))
+		}
+		fmt.Fprintf(bw, "Time per request:       %.3f [ms] (mean, across all concurrent requests)\n", perRequest)
+	}
+	var rate float64
+	if sec := r.Total.Seconds(); sec > 0 {
+		rate = float64(r.SizeTotal) / 1024 / sec
+	}
+	fmt.Fprintf(bw, "Transfer rate:          %.2f [Kbytes/sec] received\n", rate)
+	if r.Count == 0 {
+		return bw.Flush()
+	}
+
+	ms := func(d time.Duration) int64 { return d.Round(time.Millisecond).Milliseconds() }
+	stdev, _ := latencyStdev(r)
+	median := r.Average
+	for _, l := range r.Latencies {
+		if l.Percentage == 50 {
+			median = l.Latency
+		}
+	}
+	fmt.Fprintf(bw, "\nConnection Times (ms)\n")
+	fmt.Fprintf(bw, "              min  mean[+/-sd] median   max\n")
+	fmt.Fprintf(bw, "Total:      %5d %4d %5.1f %6d %7d\n", ms(r.Fastest), ms(r.Average),
+		float64(stdev)/float64(time.Millisecond), ms(median), ms(r.Slowest))
+	fmt.Fprintf(bw, "\nPercentage of the requests served within a certain time (ms)\n")
+	for _, l := range r.Latencies {
+		fmt.Fprintf(bw, " %3d%% %6d\n", l.Percentage, ms(l.Latency))
+	}
+	fmt.Fprintf(bw, " 100%% %6d (longest request)\n", ms(r.Slowest))
+	return bw.Flush()
+}
@@ -0,0 +1,83 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultAbortWindow is the sliding window over which the error rate
+	// is evaluated, unless Boomer.AbortWindow is set.
+	defaultAbortWindow = 10 * time.Second
+
+	// abortMinRequests is the number of requests the window must hold
+	// before its error rate is trusted.
+	abortMinRequests = 20
+)
+
+// abortWindow cancels a run once the share of failed requests, errors
+// and 5xx responses, over the last seconds exceeds a threshold.
+type abortWindow struct {
+	rate     float64
+	seconds  int
+	counts   []int64
+	failures []int64
+	cancel   context.CancelFunc
+	reason   string
+}
+
+func newAbortWindow(rate float64, window time.Duration, cancel context.CancelFunc) *abortWindow {
+	if window <= 0 {
+		window = defaultAbortWindow
+	}
+	seconds := int(window / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &abortWindow{rate: rate, seconds: seconds, cancel: cancel}
+}
+
+// add accounts a result started in the i-th second of the run.
+func (a *abortWindow) add(i int, res *result) {
+	if a.reason != "" {
+		return
+	}
+	for len(a.counts) <= i {
+		a.counts = append(a.counts, 0)
+		a.failures = append(a.failures, 0)
+	}
+	a.counts[i]++
+	if res.err == nil && res.statusCode < 500 {
+		return
+	}
+	a.failures[i]++
+
+	var count, failures int64
+	for j := i; j >= 0 && j > i-a.seconds; j-- {
+		count += a.counts[j]
+		failures += a.failures[j]
+	}
+	if count < abortMinRequests {
+		return
+	}
+	if r := float64(failures) / float64(count); r > a.rate {
+		a.reason = fmt.Sprintf("error rate %.1f%% over the last %v, above %.1f%%",
+			r*100, time.Duration(a.seconds)*time.Second, a.rate*100)
+		a.cancel()
+	}
+}
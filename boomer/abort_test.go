@@ -0,0 +1,76 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestAbortWindow(t *testing.T) {
+	cancelled := false
+	a := newAbortWindow(0.1, 0, func() { cancelled = true })
+	ok, failed := &result{statusCode: 200}, &result{err: errors.New("reset")}
+	for i := 0; i < 100; i++ {
+		a.add(0, ok)
+	}
+	for i := 0; i < 5; i++ {
+		a.add(1, failed)
+	}
+	if cancelled {
+		t.Fatalf("Expected 5 failures out of 105 requests not to abort")
+	}
+	// The first second leaves the window.
+	for i := 0; i < 20; i++ {
+		a.add(10, ok)
+	}
+	a.add(10, &result{statusCode: 503})
+	if !cancelled || a.reason == "" {
+		t.Errorf("Expected 6 failures out of 26 requests to abort")
+	}
+}
+
+func TestAbortErrorRate(t *testing.T) {
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&count, 1) > 30 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:        req,
+		N:              100000,
+		C:              4,
+		AbortErrorRate: 0.5,
+		Renderer:       RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Aborted == "" {
+		t.Fatalf("Expected the run to be aborted")
+	}
+	if n := rep.StatusCodeDist[http.StatusInternalServerError]; n == 0 || n > 1000 {
+		t.Errorf("Expected the run to stop shortly after failing, found %v", rep.StatusCodeDist)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// Throttling describes how the rate limit was adapted to the health of
+// the target, see Boomer.AdaptiveQps.
+type Throttling struct {
+	// MaxSustainable is the highest number of responses per second of a
+	// healthy interval, an estimate of the maximum sustainable
+	// throughput. It is zero if no interval was healthy.
+	MaxSustainable float64
+
+	// Steps holds the decisions of the controller, one per interval.
+	Steps []ThrottlingStep
+}
+
+// ThrottlingStep is an interval of the rate controller.
+type ThrottlingStep struct {
+	Offset    time.Duration
+	Qps       float64
+	RPS       float64
+	ErrorRate float64
+	P99       time.Duration
+	Healthy   bool
+}
+
+// rateController paces the dispatching of the requests, backing off
+// multiplicatively when an interval is unhealthy, with too many errors
+// or a too high latency, and ramping back up to max additively once it
+// recovers.
+type rateController struct {
+	max       float64
+	errorRate float64
+	latency   time.Duration
+	interval  time.Duration
+
+	ticker *time.Ticker
+	rate   float64
+
+	mu         sync.Mutex
+	latencies  reservoir
+	failures   int
+	throttling Throttling
+}
+
+func newRateController(qps int, errorRate float64, latency, interval time.Duration) *rateController {
+	if interval <= 0 {
+		interval = defaultTuneInterval
+	}
+	c := &rateController{
+		max:       float64(qps),
+		rate:      float64(qps),
+		errorRate: errorRate,
+		latency:   latency,
+		interval:  interval,
+	}
+	c.ticker = time.NewTicker(c.period())
+	return c
+}
+
+func (c *rateController) period() time.Duration {
+	return time.Duration(float64(time.Second) / c.rate)
+}
+
+// observe accounts a request that took d. Failures are errors and 5xx
+// responses.
+func (c *rateController) observe(d time.Duration, failed bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if failed {
+		c.failures++
+	}
+	c.latencies.add(d)
+}
+
+// run adjusts the rate every interval until ctx is done.
+func (c *rateController) run(ctx context.Context) {
+	defer c.ticker.Stop()
+	start := time.Now()
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			c.adjust(now.Sub(start))
+		}
+	}
+}
+
+func (c *rateController) adjust(offset time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lats, n := c.latencies.take()
+	failures := c.failures
+	c.failures = 0
+	if n == 0 {
+		return
+	}
+	step := ThrottlingStep{
+		Offset:    offset,
+		Qps:       c.rate,
+		RPS:       float64(n) / c.interval.Seconds(),
+		ErrorRate: float64(failures) / float64(n),
+		P99:       p99(lats),
+	}
+	step.Healthy = step.ErrorRate <= c.errorRate && (c.latency == 0 || step.P99 <= c.latency)
+	c.throttling.Steps = append(c.throttling.Steps, step)
+	if step.Healthy {
+		c.throttling.MaxSustainable = math.Max(c.throttling.MaxSustainable, step.RPS)
+		c.rate = math.Min(c.max, c.rate+c.max/20)
+	} else {
+		c.rate = math.Max(1, c.rate*0.7)
+	}
+	c.ticker.Reset(c.period())
+}
+
+func (c *rateController) result() *Throttling {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	throttling := c.throttling
+	return &throttling
+}
+
+func printThrottling(w io.Writer, t *Throttling) {
+	fmt.Fprintf(w, "\nAdaptive rate:\n")
+	if t.MaxSustainable == 0 {
+		fmt.Fprintf(w, "  The target was never healthy.\n")
+	} else {
+		fmt.Fprintf(w, "  Max sustainable throughput:\t%s requests/sec\n", formatCount(t.MaxSustainable))
+	}
+	for _, s := range t.Steps {
+		health := "healthy"
+		if !s.Healthy {
+			health = "backing off"
+		}
+		fmt.Fprintf(w, "  %v\t%s qps\t%s requests/sec\t%.1f%% errors\tp99 %s\t%s\n", s.Offset.Truncate(time.Second),
+			formatCount(s.Qps), formatCount(s.RPS), s.ErrorRate*100, formatSeconds(s.P99.Seconds()), health)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestRateController(t *testing.T) {
+	c := newRateController(100, 0.1, 0, time.Second)
+	defer c.ticker.Stop()
+	for i := 0; i < 10; i++ {
+		c.observe(time.Millisecond, i < 5)
+	}
+	c.adjust(time.Second)
+	if c.rate != 70 {
+		t.Errorf("Expected the rate to back off on errors, found %v", c.rate)
+	}
+	for i := 0; i < 10; i++ {
+		c.observe(time.Millisecond, false)
+	}
+	c.adjust(2 * time.Second)
+	if c.rate != 75 {
+		t.Errorf("Expected the rate to ramp up once recovered, found %v", c.rate)
+	}
+	if c.throttling.MaxSustainable != 10 {
+		t.Errorf("Expected a sustainable throughput of 10, found %v", c.throttling.MaxSustainable)
+	}
+}
+
+func TestAdaptiveQps(t *testing.T) {
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail every other request past the first 50.
+		if n := atomic.AddInt64(&count, 1); n > 50 && n%2 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:           req,
+		N:                 1000000,
+		C:                 4,
+		Qps:               500,
+		AdaptiveQps:       true,
+		AdaptiveErrorRate: 0.1,
+		TuneInterval:      100 * time.Millisecond,
+		Renderer:          RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	throttling := boomer.RunContext(ctx).Throttling
+	if throttling == nil || len(throttling.Steps) < 5 {
+		t.Fatalf("Expected the rate to be adapted, found %+v", throttling)
+	}
+	last := throttling.Steps[len(throttling.Steps)-1]
+	if last.Qps >= 500 {
+		t.Errorf("Expected the rate to back off, found %v qps", last.Qps)
+	}
+}
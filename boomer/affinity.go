@@ -0,0 +1,51 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// affinity pins the requests of a worker to a backend, with a header or a
+// cookie whose value is the same for all the requests of the worker.
+type affinity struct {
+	header string
+	cookie string
+	value  string
+}
+
+// newAffinity returns the affinity of a worker. The values of the workers
+// are unique to a run.
+func (b *Boomer) newAffinity(user int) *affinity {
+	if b.AffinityHeader == "" && b.AffinityCookie == "" {
+		return nil
+	}
+	return &affinity{
+		header: b.AffinityHeader,
+		cookie: b.AffinityCookie,
+		value:  b.affinityPrefix + "-" + strconv.Itoa(user),
+	}
+}
+
+func (a *affinity) apply(req *fasthttp.Request) {
+	if a.header != "" {
+		req.Header.Set(a.header, a.value)
+	}
+	if a.cookie != "" {
+		req.Header.SetCookie(a.cookie, a.value)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestAffinity(t *testing.T) {
+	var mu sync.Mutex
+	values := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("backend")
+		if err != nil || c.Value != r.Header.Get("X-Affinity") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		values[c.Value]++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	boomer := &Boomer{
+		Request:        newGet(server.URL),
+		N:              40,
+		C:              4,
+		AffinityHeader: "X-Affinity",
+		AffinityCookie: "backend",
+		Renderer:       RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.StatusCodeDist[http.StatusOK] != 40 {
+		t.Fatalf("Expected 40 pinned requests, found %v", rep.StatusCodeDist)
+	}
+	if len(values) != 4 {
+		t.Errorf("Expected a value per worker, found %v", values)
+	}
+}
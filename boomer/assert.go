@@ -0,0 +1,203 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Assertion checks the responses, see Boomer.Assertions.
+type Assertion interface {
+	// Check returns an error describing why resp fails the assertion, or
+	// nil. Its messages are accounted in the error distribution, and
+	// should not depend on the response beyond a few values.
+	Check(resp *fasthttp.Response) error
+}
+
+// AssertionFunc is an adapter to use a function as an Assertion.
+type AssertionFunc func(resp *fasthttp.Response) error
+
+// Check calls f(resp).
+func (f AssertionFunc) Check(resp *fasthttp.Response) error {
+	return f(resp)
+}
+
+// AssertionError is the error of a response failing an assertion.
+type AssertionError struct {
+	Reason string
+}
+
+func (e *AssertionError) Error() string {
+	return "assertion failed: " + e.Reason
+}
+
+// ExpectStatus asserts the status code of the responses is one of codes.
+func ExpectStatus(codes ...int) Assertion {
+	return AssertionFunc(func(resp *fasthttp.Response) error {
+		code := resp.StatusCode()
+		for _, c := range codes {
+			if code == c {
+				return nil
+			}
+		}
+		return &AssertionError{fmt.Sprintf("status %d, expected %s", code, joinInts(codes, " or "))}
+	})
+}
+
+// ExpectBodyRegexp asserts the body of the responses matches re.
+func ExpectBodyRegexp(re *regexp.Regexp) Assertion {
+	return AssertionFunc(func(resp *fasthttp.Response) error {
+		body, err := decodedBody(resp)
+		if err != nil {
+			return err
+		}
+		if !re.Match(body) {
+			return &AssertionError{fmt.Sprintf("body does not match %s", re)}
+		}
+		return nil
+	})
+}
+
+// ExpectSHA256 asserts the SHA-256 checksum of the body of the responses
+// is sum, e.g. to detect corrupted or truncated static assets.
+func ExpectSHA256(sum []byte) Assertion {
+	return AssertionFunc(func(resp *fasthttp.Response) error {
+		body, err := decodedBody(resp)
+		if err != nil {
+			return err
+		}
+		if got := sha256.Sum256(body); !bytes.Equal(got[:], sum) {
+			return &AssertionError{"body checksum mismatch"}
+		}
+		return nil
+	})
+}
+
+// ExpectSize asserts the body of the responses is size bytes long.
+func ExpectSize(size int) Assertion {
+	return AssertionFunc(func(resp *fasthttp.Response) error {
+		body, err := decodedBody(resp)
+		if err != nil {
+			return err
+		}
+		if len(body) != size {
+			return &AssertionError{fmt.Sprintf("body size %d, expected %d", len(body), size)}
+		}
+		return nil
+	})
+}
+
+// decodedBody returns the body of resp, decompressed per its
+// Content-Encoding.
+func decodedBody(resp *fasthttp.Response) ([]byte, error) {
+	body, err := DecompressedBody(resp)
+	if err != nil {
+		return nil, &AssertionError{"body can not be decompressed"}
+	}
+	return body, nil
+}
+
+var jsonAssertionRegexp = regexp.MustCompile(`^\s*([^\s=!<>]+)\s*(==|!=|<=|>=|<|>)\s*(.+?)\s*$`)
+
+// ParseJSONAssertion parses an assertion on a field of JSON bodies, as
+// PATH OP VALUE, e.g. data.id != null or items.0.price < 10. PATH is a
+// dot separated list of object keys and array indexes, OP one of ==, !=,
+// <, <=, > and >=, and VALUE a JSON value, or else a string. Missing
+// fields are null, and only numbers are ordered.
+func ParseJSONAssertion(expr string) (Assertion, error) {
+	m := jsonAssertionRegexp.FindStringSubmatch(expr)
+	if m == nil {
+		return nil, fmt.Errorf("could not parse the JSON assertion %q", expr)
+	}
+	path, op := strings.Split(m[1], "."), m[2]
+	var want interface{}
+	if err := json.Unmarshal([]byte(m[3]), &want); err != nil {
+		want = m[3]
+	}
+	expr = strings.TrimSpace(expr)
+	return AssertionFunc(func(resp *fasthttp.Response) error {
+		body, err := decodedBody(resp)
+		if err != nil {
+			return err
+		}
+		var doc interface{}
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(&doc); err != nil {
+			return &AssertionError{"body is not JSON"}
+		}
+		if !compareJSON(jsonField(doc, path), op, want) {
+			return &AssertionError{expr}
+		}
+		return nil
+	}), nil
+}
+
+// jsonField returns the field of doc at path, nil if missing.
+func jsonField(doc interface{}, path []string) interface{} {
+	for _, key := range path {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			doc = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			doc = v[i]
+		default:
+			return nil
+		}
+	}
+	return doc
+}
+
+func compareJSON(got interface{}, op string, want interface{}) bool {
+	switch op {
+	case "==":
+		return reflect.DeepEqual(got, want)
+	case "!=":
+		return !reflect.DeepEqual(got, want)
+	}
+	g, ok1 := got.(float64)
+	w, ok2 := want.(float64)
+	if !ok1 || !ok2 {
+		return false
+	}
+	switch op {
+	case "<":
+		return g < w
+	case "<=":
+		return g <= w
+	case ">":
+		return g > w
+	}
+	return g >= w
+}
+
+func joinInts(v []int, sep string) string {
+	s := make([]string, len(v))
+	for i, n := range v {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, sep)
+}
@@ -0,0 +1,130 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestJSONAssertion(t *testing.T) {
+	resp := &fasthttp.Response{}
+	resp.SetBodyString(`{"data": {"id": 7, "name": "a b", "tags": ["x"], "gone": null}}`)
+	for expr, ok := range map[string]bool{
+		"data.id != null":      true,
+		"data.id == 7":         true,
+		"data.id > 6":          true,
+		"data.id <= 6":         false,
+		`data.name == "a b"`:   true,
+		"data.tags.0 == x":     true,
+		"data.tags.1 != null":  false,
+		"data.gone == null":    true,
+		"data.missing == null": true,
+		"data.name > 1":        false,
+	} {
+		a, err := ParseJSONAssertion(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Check(resp); (err == nil) != ok {
+			t.Errorf("Expected %q to hold: %v, found %v", expr, ok, err)
+		}
+	}
+	if _, err := ParseJSONAssertion("data.id"); err == nil {
+		t.Errorf("Expected an error for an assertion without operator")
+	}
+	a, _ := ParseJSONAssertion("id == 1")
+	resp.SetBodyString("<html>")
+	if err := a.Check(resp); err == nil || err.Error() != "assertion failed: body is not JSON" {
+		t.Errorf("Expected a non JSON body to fail, found %v", err)
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	var count int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		switch {
+		case count%4 == 0:
+			w.WriteHeader(http.StatusNotFound)
+		case count%4 == 1:
+			w.Write([]byte("error"))
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:    req,
+		N:          20,
+		C:          1,
+		Assertions: []Assertion{ExpectStatus(200), ExpectBodyRegexp(regexp.MustCompile("^ok$"))},
+		Renderer:   RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.StatusCodeDist[200] != 10 {
+		t.Errorf("Expected 10 successes, found %v", rep.StatusCodeDist)
+	}
+	if rep.ErrorDist["assertion failed: status 404, expected 200"] != 5 || rep.ErrorDist["assertion failed: body does not match ^ok$"] != 5 {
+		t.Errorf("Expected the assertion failures as errors, found %v", rep.ErrorDist)
+	}
+}
+
+func TestBodyChecksum(t *testing.T) {
+	asset := []byte("static asset")
+	sum := sha256.Sum256(asset)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write(asset)
+			gz.Close()
+		case "/truncated":
+			w.Write(asset[:6])
+		default:
+			w.Write(asset)
+		}
+	}))
+	defer server.Close()
+
+	for path, ok := range map[string]bool{"/": true, "/gzip": true, "/truncated": false} {
+		resp := &fasthttp.Response{}
+		if err := fasthttp.Do(newGet(server.URL+path), resp); err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range []Assertion{ExpectSHA256(sum[:]), ExpectSize(len(asset))} {
+			if err := a.Check(resp); (err == nil) != ok {
+				t.Errorf("Expected %s to pass: %v, found %v", path, ok, err)
+			}
+		}
+	}
+}
+
+func newGet(uri string) *fasthttp.Request {
+	req := &fasthttp.Request{}
+	req.SetRequestURI(uri)
+	return req
+}
@@ -0,0 +1,68 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// authMixer alternates the Authorization header of a worker's request
+// between its valid value and an invalid one, in the given ratio.
+type authMixer struct {
+	ratio float64
+	valid []byte
+	bad   string
+	n     int
+}
+
+// newAuthMixer returns nil if no invalid requests are wanted.
+func newAuthMixer(req *fasthttp.Request, ratio float64, bad string) *authMixer {
+	if ratio <= 0 {
+		return nil
+	}
+	valid := append([]byte(nil), req.Header.Peek("Authorization")...)
+	if bad == "" {
+		scheme := "Bearer"
+		if i := strings.IndexByte(string(valid), ' '); i > 0 {
+			scheme = string(valid[:i])
+		}
+		bad = scheme + " invalid"
+	}
+	return &authMixer{ratio: ratio, valid: valid, bad: bad}
+}
+
+// next sets the Authorization header for the following request and
+// returns its class and whether it is valid. Invalid requests are spread
+// evenly.
+func (m *authMixer) next(req *fasthttp.Request) (label, bool) {
+	m.n++
+	if int(float64(m.n)*m.ratio) > int(float64(m.n-1)*m.ratio) {
+		req.Header.Set("Authorization", m.bad)
+		return label{"auth", "invalid"}, false
+	}
+	if len(m.valid) > 0 {
+		req.Header.SetBytesV("Authorization", m.valid)
+	} else {
+		req.Header.Del("Authorization")
+	}
+	return label{"auth", "valid"}, true
+}
+
+func basicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
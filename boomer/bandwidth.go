@@ -0,0 +1,127 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// limiter paces the bytes transferred in one direction to a bandwidth.
+type limiter struct {
+	mu   sync.Mutex
+	rate float64
+	next time.Time
+}
+
+func newLimiter(bytesPerSec int64) *limiter {
+	return &limiter{rate: float64(bytesPerSec)}
+}
+
+// reserve accounts n bytes and returns how long to wait for them to fit
+// in the bandwidth.
+func (l *limiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	return l.next.Sub(now)
+}
+
+// link is a bandwidth limit, applied in each direction.
+type link struct {
+	read, write *limiter
+}
+
+func newLink(bytesPerSec int64) *link {
+	return &link{read: newLimiter(bytesPerSec), write: newLimiter(bytesPerSec)}
+}
+
+// throttle returns conn limited to the bandwidths of the client options.
+func (b *Boomer) throttle(conn net.Conn) net.Conn {
+	var links []*link
+	rate := b.Client.MaxBandwidth
+	if b.bandwidth != nil {
+		links = append(links, b.bandwidth)
+	}
+	if r := b.Client.MaxConnBandwidth; r > 0 {
+		links = append(links, newLink(r))
+		if rate == 0 || r < rate {
+			rate = r
+		}
+	}
+	if len(links) == 0 {
+		return conn
+	}
+	// Transfers are split in chunks of 20ms for the pace to be smooth.
+	chunk := int(rate / 50)
+	if chunk < 1 {
+		chunk = 1
+	}
+	return &throttledConn{Conn: conn, links: links, chunk: chunk}
+}
+
+type throttledConn struct {
+	net.Conn
+	links []*link
+	chunk int
+}
+
+func (c *throttledConn) Read(p []byte) (int, error) {
+	if len(p) > c.chunk {
+		p = p[:c.chunk]
+	}
+	n, err := c.Conn.Read(p)
+	c.wait(n, func(l *link) *limiter { return l.read })
+	return n, err
+}
+
+func (c *throttledConn) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		n := len(p)
+		if n > c.chunk {
+			n = c.chunk
+		}
+		c.wait(n, func(l *link) *limiter { return l.write })
+		m, err := c.Conn.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// wait blocks until n bytes fit in the most constrained of the links.
+func (c *throttledConn) wait(n int, direction func(*link) *limiter) {
+	if n <= 0 {
+		return
+	}
+	var d time.Duration
+	for _, l := range c.links {
+		if w := direction(l).reserve(n); w > d {
+			d = w
+		}
+	}
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestBandwidth(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 20000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:  req,
+		N:        2,
+		C:        2,
+		Client:   ClientOptions{MaxConnBandwidth: 100000},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	// Each connection reads its 20kB in about 200ms.
+	rep := boomer.Run()
+	if rep.Count != 2 || rep.Fastest < 150*time.Millisecond || rep.Slowest > time.Second {
+		t.Errorf("Expected 200ms per request, found %s to %s (%v)", rep.Fastest, rep.Slowest, rep.ErrorDist)
+	}
+
+	// The connections share 100kB/s, and take about 400ms for both.
+	boomer.Client = ClientOptions{MaxBandwidth: 100000}
+	rep = boomer.Run()
+	if rep.Count != 2 || rep.Slowest < 300*time.Millisecond || rep.Slowest > 2*time.Second {
+		t.Errorf("Expected 400ms for both requests, found %s (%v)", rep.Slowest, rep.ErrorDist)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	batchBoundary    = "pla-batch-boundary"
+	batchPlaceholder = "{{ops}}"
+)
+
+// BatchBody packs n copies of the operation op into a single batch request
+// body and returns it along with its content type.
+//
+// The "json" format joins the operations with commas and substitutes them
+// for {{ops}} in envelope, which defaults to a JSON array. The "multipart"
+// format builds a multipart/mixed body with one part per operation.
+func BatchBody(format, envelope string, op []byte, opContentType string, n int) ([]byte, string, error) {
+	if n < 1 {
+		return nil, "", fmt.Errorf("batch size cannot be smaller than 1")
+	}
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		if envelope == "" {
+			envelope = "[" + batchPlaceholder + "]"
+		}
+		i := strings.Index(envelope, batchPlaceholder)
+		if i < 0 {
+			return nil, "", fmt.Errorf("batch envelope must contain %s", batchPlaceholder)
+		}
+		buf.WriteString(envelope[:i])
+		for j := 0; j < n; j++ {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(op)
+		}
+		buf.WriteString(envelope[i+len(batchPlaceholder):])
+		return buf.Bytes(), "application/json", nil
+	case "multipart":
+		for j := 0; j < n; j++ {
+			fmt.Fprintf(&buf, "--%s\r\nContent-Type: %s\r\n\r\n", batchBoundary, opContentType)
+			buf.Write(op)
+			buf.WriteString("\r\n")
+		}
+		fmt.Fprintf(&buf, "--%s--\r\n", batchBoundary)
+		return buf.Bytes(), "multipart/mixed; boundary=" + batchBoundary, nil
+	}
+	return nil, "", fmt.Errorf("unknown batch format %q; only json and multipart are supported", format)
+}
@@ -0,0 +1,52 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"testing"
+)
+
+func TestBatchBody(t *testing.T) {
+	body, ct, err := BatchBody("json", "", []byte(`{"a":1}`), "application/json", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `[{"a":1},{"a":1},{"a":1}]` || ct != "application/json" {
+		t.Errorf("Unexpected json batch %q (%s)", body, ct)
+	}
+
+	body, _, err = BatchBody("json", `{"requests":{{ops}}}`, []byte(`1`), "", 2)
+	if err != nil || string(body) != `{"requests":1,1}` {
+		t.Errorf("Unexpected enveloped batch %q: %v", body, err)
+	}
+
+	body, ct, err = BatchBody("multipart", "", []byte("op"), "text/plain", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--pla-batch-boundary\r\nContent-Type: text/plain\r\n\r\nop\r\n" +
+		"--pla-batch-boundary\r\nContent-Type: text/plain\r\n\r\nop\r\n" +
+		"--pla-batch-boundary--\r\n"
+	if string(body) != want || ct != "multipart/mixed; boundary=pla-batch-boundary" {
+		t.Errorf("Unexpected multipart batch %q (%s)", body, ct)
+	}
+
+	if _, _, err := BatchBody("json", "no placeholder", nil, "", 2); err == nil {
+		t.Errorf("An envelope without placeholder was accepted")
+	}
+	if _, _, err := BatchBody("xml", "", nil, "", 2); err == nil {
+		t.Errorf("An unknown batch format was accepted")
+	}
+}
@@ -16,33 +16,115 @@
 package boomer
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"github.com/valyala/fasthttp"
+	"io"
+	"math/rand"
+	"net"
 	"net/url"
 	"os"
-	"os/signal"
 	"sync"
 	"time"
 
 	"github.com/sschepens/pb"
 )
 
-var client *fasthttp.Client
-
 type result struct {
 	err           error
+	start         time.Time
 	statusCode    int
 	duration      time.Duration
 	contentLength int
+	identity      string
+	forwardedFor  string
+	labels        []label
+	tags          map[string]string
+	serverTiming  []serverMetric
+	attempts      int
+	user          int
+	method        string
+	url           string
+	ttfb          time.Duration
+	truncated     bool
+	encoding      string
+	wireSize      int
+	decodedSize   int
+	uploaded      int64
+	uploadTime    time.Duration
+	expect        expectOutcome
+	shed          bool
+}
+
+// ClientOptions tunes the fasthttp client used by the workers. Zero
+// values keep the fasthttp defaults.
+type ClientOptions struct {
+	ReadBufferSize                int
+	WriteBufferSize               int
+	MaxIdleConnDuration           time.Duration
+	MaxResponseBodySize           int
+	DisableHeaderNamesNormalizing bool
+
+	// MaxConnsPerHost is the number of connections opened per host, twice
+	// C if zero.
+	MaxConnsPerHost int
+
+	// DialTimeout is the time allowed to open a connection, 3 seconds if
+	// zero.
+	DialTimeout time.Duration
+
+	// Resolver resolves the hosts of the requests, see DNSResolver. The
+	// system resolver is used if nil.
+	Resolver *net.Resolver
+
+	// DNSCacheDuration is how long the addresses of a host are used
+	// before it is resolved again, a minute if zero. The connections are
+	// spread across the addresses. See Boomer.Resolve to pin them instead.
+	DNSCacheDuration time.Duration
+
+	// IPVersion, 4 or 6, restricts the connections to IPv4 or IPv6. The
+	// connections are made over IPv4 if zero, as fasthttp does.
+	IPVersion int
+
+	// LocalAddrs are the source addresses of the connections, used in
+	// turn, e.g. to open more connections to a single server than the
+	// ephemeral ports of one address allow.
+	LocalAddrs []net.IP
+
+	// MaxBandwidth and MaxConnBandwidth limit the transfers of all the
+	// connections, and of every connection, to this many bytes per second
+	// in each direction, e.g. to emulate mobile clients or slow readers.
+	MaxBandwidth     int64
+	MaxConnBandwidth int64
+
+	// DisableKeepAlive closes the connection after every request, for
+	// every request to pay for the connection and TLS handshake as a
+	// cold client does.
+	DisableKeepAlive bool
 }
 
 type Boomer struct {
 	// Request is the request to be made.
 	Request *fasthttp.Request
 
+	// Targets, if set, replaces Request with a mix of requests.
+	Targets []Target
+
+	// Schedule, if set, dispatches the requests at given times rather
+	// than in turn and at the pace of Qps: the i-th request, up to N, is
+	// sent to the target of Schedule[i] at its offset from the start of
+	// the run, as soon as a worker is free.
+	Schedule []Dispatch
+
 	// N is the total number of requests to make.
 	N int
 
+	// Skip is the number of requests already made, e.g. by an interrupted
+	// run being resumed: the run makes the N next ones, going on with the
+	// targets, Schedule and Spike from there.
+	Skip int
+
 	// C is the concurrency level, the number of concurrent workers to run.
 	C int
 
@@ -52,28 +134,360 @@ type Boomer struct {
 	// Qps is the rate limit.
 	Qps int
 
+	// Burst, if set, dispatches the requests in bursts of this many
+	// requests at once, a burst every BurstInterval, rather than at a
+	// steady pace, like clients woken up together. C must be at least
+	// Burst for the requests of a burst to be sent together.
+	Burst         int
+	BurstInterval time.Duration
+
+	// Spike, if set, paces the requests by a spike test profile rather
+	// than at the pace of Qps, and breaks the report down into the
+	// windows before, during and after the spike.
+	Spike *Spike
+
 	// AllowInsecure is an option to allow insecure TLS/SSL certificates.
 	AllowInsecure bool
 
+	// RootCAs, if set, is the set of certificate authorities trusted to
+	// verify the server certificates, instead of the system ones.
+	RootCAs *x509.CertPool
+
+	// ServerName, if set, is sent as TLS SNI and used to verify the server
+	// certificate instead of the host of the request URL.
+	ServerName string
+
+	// Certificates are the client certificates presented to TLS servers.
+	// If more than one is given, each worker presents a single one, in a
+	// round robin fashion, using its own connection pool.
+	Certificates []tls.Certificate
+
 	// Output represents the output type. If "csv" is provided, the
 	// output will be dumped as a csv stream.
 	Output string
 
-	// ProxyAddr is the address of HTTP proxy server in the format on "host:port".
-	// Optional.
+	// IntervalReport, if set, prints the throughput, error rate and
+	// latency of the requests completed during every interval of this
+	// duration while the run goes on, to IntervalWriter or else to
+	// stderr, for long runs to show a gradual degradation of the target.
+	IntervalReport time.Duration
+	IntervalWriter io.Writer
+
+	// ProxyAddr is the URL of the proxy server, e.g. http://host:port or
+	// socks5://host:port. Connections are tunneled through it. Optional.
 	ProxyAddr *url.URL
 
+	// Resolve maps "host:port" addresses to the "ip:port" addresses to
+	// connect to instead. The Host header and TLS SNI are left untouched.
+	Resolve map[string]string
+
+	// UnixSocket, if set, is the path of a unix domain socket every
+	// connection is made to, whatever the host of the request.
+	UnixSocket string
+
 	// ReadAll determines whether the body of the response needs
 	// to be fully consumed.
 	ReadAll bool
 
+	// StreamBody reads the response bodies as they arrive, discarding
+	// them, instead of buffering them. The report then tells the bytes
+	// actually received and the time to first byte apart from the
+	// transfer time. The assertions and the AfterResponse hook see empty
+	// bodies.
+	StreamBody bool
+
+	// Decompress decompresses the response bodies, out of the measured
+	// time, for the assertions to check them. The report tells the
+	// compression of the bodies.
+	Decompress bool
+
+	// MaxBodySize, if set along with StreamBody, is the number of bytes
+	// read from every body. The connection is closed on larger bodies.
+	MaxBodySize int64
+
+	// RequestBody, if set, returns the body of every request, replacing
+	// the body of Request. The body is sent with chunked transfer
+	// encoding as it is read, rather than held in memory, and closed once
+	// sent if it is an io.Closer. See FileBody. The report tells the
+	// upload throughput.
+	RequestBody func() (io.Reader, error)
+
+	// ExpectContinue sends the requests having a body with an
+	// "Expect: 100-continue" header. The body is sent once the server
+	// answers "100 Continue", or after ExpectContinueTimeout, a second if
+	// zero, and not at all if the server answers with a final response.
+	// The report tells the latency of the interim responses.
+	ExpectContinue        bool
+	ExpectContinueTimeout time.Duration
+
+	// Form, if set, replaces the body of every request with a
+	// multipart/form-data body, built for every request.
+	Form *Form
+
+	// Pipeline, if set, pipelines this many requests on every connection:
+	// they are sent without waiting for the responses to the previous
+	// ones, over fewer connections. The latency of a request includes
+	// the wait for the responses queued before it on its connection. The
+	// client options, apart from the buffer sizes and idle duration, are
+	// not used, nor is KeepConnections.
+	Pipeline int
+
+	// Cookies enables a cookie jar per worker. Cookies set by responses
+	// are sent back on the following requests of the same worker to
+	// their domain and path, until they expire.
+	Cookies bool
+
+	// ThinkTime is the pause of a worker between two of its requests,
+	// modelling the pacing of a user. It is not part of the measured
+	// durations.
+	ThinkTime time.Duration
+
+	// ThinkTimeJitter spreads the think time uniformly over ThinkTime
+	// plus or minus the jitter.
+	ThinkTimeJitter time.Duration
+
+	// MaxIterations, if set, recycles the virtual user simulated by a
+	// worker after this many requests: its cookies and authentication
+	// state are dropped and a new session starts.
+	MaxIterations int
+
+	// FollowRedirects is the maximum number of redirects to follow for
+	// every request. The whole chain is timed and the status code of the
+	// last hop is reported. Zero disables redirect following.
+	FollowRedirects int
+
+	// CacheBust, if set, is the name of a query parameter set to a
+	// unique random value on every request, for caches and CDNs to miss.
+	CacheBust string
+
+	// RequestIDHeaders are set to a new random UUID on every request,
+	// the same for all of them, e.g. Idempotency-Key or X-Request-ID, for
+	// deduplicating servers to handle every request. Retries keep the
+	// UUID of the request they retry.
+	RequestIDHeaders []string
+
+	// AffinityHeader and AffinityCookie, if set, are a header and a
+	// cookie whose value is the same for all the requests of a worker,
+	// and different for every worker, for load balancers hashing them to
+	// pin every worker to a backend for the whole run. The cookie
+	// overrides the one the load balancer may set. See IdentityHeader to
+	// tell the backends apart.
+	AffinityHeader string
+	AffinityCookie string
+
+	// RotateHeaders give headers a different value on every request.
+	RotateHeaders []HeaderRotation
+
+	// ForwardedFor, if set, is a network whose addresses are sent in turn
+	// in the X-Forwarded-For and Forwarded headers, simulating clients
+	// behind a trusted proxy. The report counts the requests per address.
+	ForwardedFor *net.IPNet
+
+	// AbortErrorRate, if set, stops the run once the share of errors and
+	// 5xx responses over the last AbortWindow exceeds it, e.g. 0.05. The
+	// report of the requests made so far is returned.
+	AbortErrorRate float64
+
+	// AbortWindow is the sliding window of AbortErrorRate, 10 seconds if
+	// zero.
+	AbortWindow time.Duration
+
+	// StopTimeout, if set, bounds the wait for the in-flight requests
+	// once the run is stopped: the report is then rendered without the
+	// requests still in flight, for it to be printed in time, e.g. before
+	// the process is killed.
+	StopTimeout time.Duration
+
+	// Guard, GuardWarn or GuardAbort, monitors the CPU usage and the open
+	// file descriptors of the process during the run, the results no
+	// longer reflecting the target once the load generator saturates.
+	// The report describes the usage in Saturation, a saturation is
+	// logged and, with GuardAbort, stops the run.
+	Guard string
+
+	// TargetP99, if set, tunes the number of busy workers, up to C, for
+	// the 99th percentile latency to stay under it. The limit is
+	// adjusted every TuneInterval, a second by default, and the report
+	// tells the maximum throughput reached under the target.
+	TargetP99    time.Duration
+	TuneInterval time.Duration
+
+	// AdaptiveQps, if set along with Qps, lowers the rate limit when the
+	// share of errors and 5xx responses of an interval exceeds
+	// AdaptiveErrorRate, or its 99th percentile latency exceeds
+	// AdaptiveP99 if set, and ramps it back up to Qps once the target
+	// recovers. The interval is TuneInterval, and the report estimates the
+	// maximum sustainable throughput.
+	AdaptiveQps       bool
+	AdaptiveErrorRate float64
+	AdaptiveP99       time.Duration
+
+	// Retries is the number of times a request is repeated after a
+	// connection reset or a 502 or 503 response. The measured duration
+	// spans all the attempts.
+	Retries int
+
+	// RetryBackoff is the wait before the first retry, doubled for every
+	// following one.
+	RetryBackoff time.Duration
+
+	// ServerTiming, if set, parses the Server-Timing header of the
+	// responses and reports the time spent in every server component.
+	ServerTiming bool
+
+	// Digest, if set, answers HTTP Digest authentication challenges. Each
+	// worker tracks its own nonce: a request is repeated once with the
+	// credentials when challenged, the following ones authenticate
+	// directly. The challenge round trip is part of the measured time.
+	Digest *DigestAuth
+
+	// OAuth2, if set, provides the Bearer token of every request,
+	// refreshing it when it expires. The token requests go through the
+	// proxy and the address overrides of the run.
+	OAuth2 *OAuth2
+
+	// SigV4, if set, signs every request with AWS Signature Version 4
+	// right before it is sent.
+	SigV4 *SigV4
+
+	// BatchSize is the number of logical operations packed in every
+	// request, see BatchBody. It is used to report amortized per operation
+	// statistics.
+	BatchSize int
+
+	// BadAuthRatio is the ratio, between 0 and 1, of requests sent with
+	// BadAuthorization instead of their Authorization header. The report
+	// breaks down these requests under the "auth" dimension.
+	BadAuthRatio float64
+
+	// BadAuthorization is the Authorization header value of the
+	// deliberately invalid requests. It defaults to the scheme of the
+	// valid header followed by an invalid credential.
+	BadAuthorization string
+
+	// Verbosity selects how much detail the report holds, by default
+	// depending on N.
+	Verbosity Verbosity
+
+	// SampleRate, if set below 1, keeps only this random share of the
+	// requests in the samples of detailed reports and in the stream of
+	// Results, e.g. 0.1, for the fastest runs. Every request is still
+	// accounted in the statistics.
+	SampleRate float64
+
+	// Assertions check every response, the failures are accounted as
+	// errors.
+	Assertions []Assertion
+
+	// Percentiles are the latency percentiles of the report,
+	// DefaultPercentiles if nil.
+	Percentiles []int
+
+	// Sketch, if set, computes the percentiles with a Sketch of this
+	// relative accuracy, e.g. 0.01, rather than from a sample of the
+	// latencies: exact to the accuracy whatever the number of requests,
+	// and mergeable, see Report.Sketch.
+	Sketch float64
+
+	// SlowestRequests is the number of slowest requests detailed in the
+	// report, with their url and outcome, e.g. to look them up in the
+	// server logs.
+	SlowestRequests int
+
+	// Drift enables the drift report, a linear regression of latency and
+	// error rate over time meant for long soak runs.
+	Drift bool
+
+	// IdentityHeader is the name of a response header identifying the
+	// target process, e.g. a hostname or instance id. It is sampled
+	// every second by every worker and reported along with the drift.
+	IdentityHeader string
+
+	// Client tunes the HTTP client.
+	Client ClientOptions
+
+	// KeepConnections keeps the connection pools open at the end of a
+	// run so that the next run of the same Boomer starts with warm
+	// connections. The report tells whether the connections were warm.
+	// Call ResetConnections to explicitly start from cold pools.
+	KeepConnections bool
+
+	// BeforeRequest, if set, is called with every request before it is
+	// signed and sent, e.g. to give it a unique id. AfterResponse, if
+	// set, is called once the response, or the error, of a request is
+	// known. Both are called concurrently by the workers, and the time
+	// they take is not measured.
+	BeforeRequest func(req *fasthttp.Request)
+	AfterResponse func(req *fasthttp.Request, resp *fasthttp.Response, err error)
+
+	// Doer, if set, makes the requests of all the workers instead of the
+	// built-in fasthttp clients. The TLS, dialing and client options are
+	// then left to it, as is KeepConnections.
+	Doer Doer
+
+	// Renderer, if set, replaces the built-in output selected by Output.
+	Renderer Renderer
+
+	// ReportWriter, if set, receives the report instead of stdout. The
+	// progress bar is then shown whatever the Output.
+	ReportWriter io.Writer
+
+	// Quiet hides the progress bar.
+	Quiet bool
+
+	// PlainProgress prints the progress as a line a second instead of a
+	// bar redrawn in place. It is the default on the Windows consoles
+	// not handling the bar.
+	PlainProgress bool
+
+	// ProgressFunc, if set, replaces the progress bar. It is called every
+	// second and at the end of the run with the number of requests done
+	// and failed so far, total being the number of requests of the run,
+	// or 0 if it is timed.
+	ProgressFunc func(done, total, errors int)
+
+	// Logger, if set, receives the warnings of the runs.
+	Logger Logger
+
 	bar     *pb.ProgressBar
+	live    *liveStats
 	results chan *result
-	stop    chan struct{}
+	// abandoned is closed once the run stopped waiting for its workers,
+	// for those still in flight not to block on its results.
+	abandoned chan struct{}
+	xff       *addrPool
+	buster    *cacheBuster
+	rotate    []*headerRotator
+
+	affinityPrefix string
+
+	clients []*fasthttp.Client
+	warm    bool
+
+	targetList   []Target
+	targetRR     *weightedRoundRobin
+	targetLabels [][]label
+
+	conns      *connStats
+	expect     *expectTransport
+	dialers    []*fasthttp.TCPDialer
+	nextDialer uint32
+	bandwidth  *link
+	tuner      *tuner
+	rate       *rateController
+	mu         sync.Mutex
+	cancel     context.CancelFunc
+	stream     chan Result
+	pause      pauser
 }
 
 func (b *Boomer) startProgress() {
-	if b.Output != "" {
+	b.bar, b.live = nil, nil
+	if b.ProgressFunc != nil {
+		b.live = newLiveStats(nil, b.N, b.ProgressFunc)
+		return
+	}
+	if b.Output != "" && b.ReportWriter == nil || b.N == 0 || b.Quiet {
 		return
 	}
 	b.bar = pb.New(b.N)
@@ -83,128 +497,699 @@ func (b *Boomer) startProgress() {
 	b.bar.Empty = " "
 	b.bar.Current = "a"
 	b.bar.CurrentN = "a"
+	b.bar.Output = os.Stderr
+	b.bar.ShowTimeLeft = false
+	if b.PlainProgress || plainTerminal() {
+		plainProgress(b.bar, os.Stderr)
+	}
 	b.bar.Start()
+	b.live = newLiveStats(b.bar, b.N, nil)
 }
 
 func (b *Boomer) finalizeProgress() {
-	if b.Output != "" {
+	if b.bar == nil {
 		return
 	}
 	b.bar.Finish()
 }
 
 func (b *Boomer) incProgress() {
-	if b.Output != "" {
+	if b.bar == nil {
 		return
 	}
 	b.bar.Increment()
 }
 
-// Run makes all the requests, prints the summary. It blocks until
-// all work is done.
-func (b *Boomer) Run() {
-	var shutdownTimer *time.Timer
-	b.results = make(chan *result, b.C)
-	b.stop = make(chan struct{})
-	b.startProgress()
+// Run makes all the requests, prints the summary and returns it. It
+// blocks until all work is done or Stop is called.
+func (b *Boomer) Run() *Report {
+	return b.RunContext(context.Background())
+}
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+// RunContext is like Run, but also stops when ctx is cancelled. The
+// in-flight requests are completed and the report of the requests made
+// so far is printed and returned.
+func (b *Boomer) RunContext(ctx context.Context) *Report {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	b.mu.Lock()
+	b.cancel = cancel
+	b.mu.Unlock()
+	r := b.run(ctx, cancel)
+	b.mu.Lock()
+	b.cancel = nil
+	b.mu.Unlock()
+	return r
+}
 
-	go func() {
-		<-c
-		shutdownTimer = time.AfterFunc(10*time.Second, func() {
-			b.finalizeProgress()
-			close(b.stop)
-			os.Exit(1)
-		})
-		b.finalizeProgress()
-		close(b.stop)
-	}()
+// Stop stops the current run, if any, as if its context was cancelled.
+// It can be called from any goroutine.
+func (b *Boomer) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cancel != nil {
+		b.cancel()
+	}
+}
 
-	r := newReport(b.N, b.results, b.Output)
-	b.runWorkers()
-	if shutdownTimer != nil {
-		shutdownTimer.Stop()
+// run makes the requests until all of them are done or ctx is cancelled,
+// whichever happens first. cancel cancels ctx, for the run to stop
+// itself.
+func (b *Boomer) run(ctx context.Context, cancel context.CancelFunc) *Report {
+	b.results = make(chan *result, b.C)
+	b.abandoned = make(chan struct{})
+	b.xff = nil
+	if b.ForwardedFor != nil {
+		b.xff = newAddrPool(b.ForwardedFor)
+	}
+	b.buster = nil
+	if b.CacheBust != "" {
+		b.buster = newCacheBuster(b.CacheBust)
+	}
+	b.rotate = newHeaderRotators(b.RotateHeaders, b.C)
+	if b.OAuth2 != nil {
+		b.OAuth2.setClient(b.oauth2Client())
+	}
+	b.affinityPrefix = newUUID()[:8]
+	b.targetList = b.targets()
+	b.targetRR, b.targetLabels = schedule(b.targetList)
+	b.startProgress()
+
+	r := newReport(b.N, b.results, b.Output, b.Renderer)
+	r.live = b.live
+	r.logf = b.logf
+	r.w = b.ReportWriter
+	r.drift = b.Drift
+	r.batchSize = b.BatchSize
+	r.pipeline = b.Pipeline
+	r.burst, r.burstInterval = b.Burst, b.BurstInterval
+	r.maxIterations = b.MaxIterations
+	r.users = make([]VirtualUser, b.C)
+	r.detailed = b.Verbosity.detailed(sampledSize(b.N, b.SampleRate))
+	r.sampler = newSampler(b.SampleRate)
+	r.percentiles = b.Percentiles
+	if b.Sketch > 0 {
+		r.lats = sketchSample{NewSketch(b.Sketch)}
+	}
+	r.stream = b.takeStream()
+	if b.StreamBody {
+		r.transfers = newTransfers()
+	}
+	if b.Decompress {
+		r.compressions = newCompressions()
+	}
+	if b.RequestBody != nil {
+		r.uploads = &uploads{}
+	}
+	if b.ExpectContinue {
+		r.interims = newInterims()
+	}
+	if b.Spike != nil {
+		r.spikes = newSpikeWindows(b.Spike, r.start)
+	}
+	r.intervals = b.newIntervalReporter()
+	if b.SlowestRequests > 0 {
+		r.slowRequests = &slowRequests{k: b.SlowestRequests}
+	}
+	if b.AbortErrorRate > 0 {
+		r.abort = newAbortWindow(b.AbortErrorRate, b.AbortWindow, cancel)
+	}
+	r.guard = b.newResourceGuard(cancel)
+	if b.conns == nil {
+		b.conns = &connStats{}
+	}
+	dialed := b.conns.start()
+	paused := b.pause.total()
+	b.dialers = b.newDialers()
+	b.bandwidth = nil
+	if b.Client.MaxBandwidth > 0 {
+		b.bandwidth = newLink(b.Client.MaxBandwidth)
+	}
+	b.tuner = nil
+	if b.TargetP99 > 0 {
+		b.tuner = newTuner(b.TargetP99, b.TuneInterval, b.C)
+	}
+	b.rate = nil
+	if b.AdaptiveQps && b.Qps > 0 {
+		b.rate = newRateController(b.Qps, b.AdaptiveErrorRate, b.AdaptiveP99, b.TuneInterval)
+	}
+	if !b.runWorkers(ctx) {
+		r.abandoned = true
+	}
+	if ctx.Err() != nil {
+		r.interrupted = true
+	}
+	if b.tuner != nil {
+		r.tuning = b.tuner.result()
+	}
+	if b.rate != nil {
+		r.throttling = b.rate.result()
+	}
+	r.warm = b.warm
+	r.paused = b.pause.total() - paused
+	if b.Doer == nil {
+		conns := b.conns.since(dialed)
+		r.conns = &conns
+	}
+	if r.abandoned {
+		// The workers still in flight may yet send their results, the
+		// report stops at this marker instead, and their next sends fall
+		// through.
+		b.results <- nil
+		close(b.abandoned)
+	} else {
+		close(b.results)
 	}
-	close(b.results)
 	b.finalizeProgress()
-	r.finalize()
+	return r.finalize()
 }
 
-func (b *Boomer) runWorker(wg *sync.WaitGroup, ch chan struct{}) {
+// workerTarget is the state of a worker for one of the targets.
+type workerTarget struct {
+	req  *fasthttp.Request
+	auth *authMixer
+}
+
+func (b *Boomer) runWorker(ctx context.Context, wg *sync.WaitGroup, user int, ch chan int, client Doer) {
+	defer wg.Done()
+	// A worker left in flight by StopTimeout must not send its result to
+	// the next run, nor block on the results of its own.
+	results, abandoned := b.results, b.abandoned
 	resp := fasthttp.AcquireResponse()
-	req := fasthttp.AcquireRequest()
-	b.Request.CopyTo(req)
-	for range ch {
-		s := time.Now()
+	defer fasthttp.ReleaseResponse(resp)
+	targets := make([]workerTarget, len(b.targetList))
+	for i, t := range b.targetList {
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		t.Request.CopyTo(req)
+		targets[i] = workerTarget{
+			req:  req,
+			auth: newAuthMixer(req, b.BadAuthRatio, b.BadAuthorization),
+		}
+	}
+	var redirect *fasthttp.Request
+	if b.FollowRedirects > 0 {
+		redirect = fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(redirect)
+	}
+	sess := b.newSession()
+	affinity := b.newAffinity(user)
+	var iterations int
+	var lastSample time.Time
+	for {
+		if !b.tuner.acquire() {
+			return
+		}
+		var i int
+		select {
+		case <-ctx.Done():
+			b.tuner.leave()
+			return
+		case j, ok := <-ch:
+			if !ok {
+				b.tuner.leave()
+				return
+			}
+			i = j
+		}
+		// Thinking once the next request is known keeps the last one from
+		// delaying the end of the run.
+		if iterations > 0 && !b.think(ctx) {
+			b.tuner.leave()
+			return
+		}
+		// The requests already dispatched are held too.
+		if _, ok := b.pause.wait(ctx); !ok {
+			b.tuner.leave()
+			return
+		}
+		if b.MaxIterations > 0 && iterations > 0 && iterations%b.MaxIterations == 0 {
+			// The requests carry the cookies and credentials of the
+			// previous session.
+			for j := range targets {
+				b.targetList[j].Request.CopyTo(targets[j].req)
+			}
+			sess = b.newSession()
+		}
+		iterations++
+		jar, digest := sess.jar, sess.digest
+		t := &targets[i]
+		req := t.req
 
 		var code int
 		var size int
+		var identity string
+		var timing []serverMetric
+		var forwardedFor string
+		labels := b.targetLabels[i]
+		validAuth := true
+		if t.auth != nil {
+			var l label
+			l, validAuth = t.auth.next(req)
+			labels = append(labels[:len(labels):len(labels)], l)
+		}
+		var err error
+		if b.OAuth2 != nil && validAuth {
+			var token string
+			if token, err = b.OAuth2.Token(); err == nil {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		if b.xff != nil {
+			forwardedFor = b.xff.next().String()
+			setForwarded(req, forwardedFor)
+		}
+		if b.buster != nil {
+			b.buster.apply(req)
+		}
+		for _, r := range b.rotate {
+			r.apply(req, user)
+		}
+		if len(b.RequestIDHeaders) > 0 {
+			id := newUUID()
+			for _, h := range b.RequestIDHeaders {
+				req.Header.Set(h, id)
+			}
+		}
+		if jar != nil {
+			jar.apply(req)
+		}
+		if affinity != nil {
+			affinity.apply(req)
+		}
+		if b.Client.DisableKeepAlive {
+			req.SetConnectionClose()
+		}
+		if b.Form != nil && err == nil {
+			err = b.Form.apply(req)
+		}
+		var upload *uploadStream
+		if b.RequestBody != nil && err == nil {
+			upload, err = b.streamBody(req)
+		}
+		if b.BeforeRequest != nil {
+			b.BeforeRequest(req)
+		}
+		s := time.Now()
+		if b.SigV4 != nil {
+			b.SigV4.Sign(req, s)
+		}
+		if digest != nil {
+			digest.authorize(req)
+		}
 
 		resp.Reset()
-		var err error
-		if b.Timeout > 0 {
-			err = client.DoTimeout(req, resp, b.Timeout)
-		} else {
-			err = client.Do(req, resp)
+		attempts := 0
+		if err == nil {
+			err = b.doFollow(client, req, redirect, resp)
+			attempts = 1
+		}
+		if err == nil && digest != nil && resp.StatusCode() == fasthttp.StatusUnauthorized && digest.challenge(resp) {
+			digest.authorize(req)
+			err = b.doFollow(client, req, redirect, resp)
+		}
+		if attempts > 0 && b.Retries > 0 {
+			attempts, err = b.retry(ctx, client, req, redirect, resp, err)
 		}
 		if err == nil {
 			size = resp.Header.ContentLength()
 			code = resp.Header.StatusCode()
+			if jar != nil {
+				jar.update(req, resp)
+			}
+			if b.IdentityHeader != "" && s.Sub(lastSample) >= time.Second {
+				identity = string(resp.Header.Peek(b.IdentityHeader))
+				lastSample = s
+			}
+			if b.ServerTiming {
+				timing = parseServerTiming(resp)
+			}
 		}
 
+		var ttfb time.Duration
+		var truncated bool
+		if err == nil && b.StreamBody {
+			ttfb = time.Now().Sub(s)
+			var n int64
+			n, truncated, err = b.readBody(resp)
+			size = int(n)
+		}
 		if b.ReadAll {
 			resp.Body()
 		}
+		duration := time.Now().Sub(s)
+		var method, uri string
+		if b.SlowestRequests > 0 {
+			method, uri = string(req.Header.Method()), req.URI().String()
+		}
+		var uploaded int64
+		var uploadTime time.Duration
+		if upload != nil {
+			uploaded = upload.n
+			if !upload.done.IsZero() {
+				uploadTime = upload.done.Sub(s)
+			}
+		}
+		var expect expectOutcome
+		if b.expect != nil {
+			expect = b.expect.outcome(req)
+		}
+		var encoding string
+		var wireSize, decodedSize int
+		if err == nil && b.Decompress {
+			encoding, wireSize, decodedSize, err = decompress(resp)
+		}
+		// Assertions are not timed.
+		for _, a := range b.Assertions {
+			if err != nil {
+				break
+			}
+			err = a.Check(resp)
+		}
+		if err != nil {
+			b.tuner.release(-1)
+		} else {
+			b.tuner.release(duration)
+		}
+		b.rate.observe(duration, err != nil || code >= 500)
+		if b.AfterResponse != nil {
+			b.AfterResponse(req, resp, err)
+		}
 
 		b.incProgress()
-		b.results <- &result{
+		res := &result{
 			statusCode:    code,
-			duration:      time.Now().Sub(s),
+			duration:      duration,
 			err:           err,
+			start:         s,
 			contentLength: size,
+			identity:      identity,
+			forwardedFor:  forwardedFor,
+			labels:        labels,
+			tags:          b.targetList[i].Tags,
+			serverTiming:  timing,
+			attempts:      attempts,
+			user:          user,
+			method:        method,
+			url:           uri,
+			ttfb:          ttfb,
+			truncated:     truncated,
+			encoding:      encoding,
+			wireSize:      wireSize,
+			decodedSize:   decodedSize,
+			uploaded:      uploaded,
+			uploadTime:    uploadTime,
+			expect:        expect,
+		}
+		select {
+		case results <- res:
+		case <-abandoned:
+			return
 		}
 	}
-	fasthttp.ReleaseResponse(resp)
-	fasthttp.ReleaseRequest(req)
-	wg.Done()
 }
 
-func (b *Boomer) runWorkers() {
-	client = &fasthttp.Client{
-		TLSConfig: &tls.Config{
-			InsecureSkipVerify: b.AllowInsecure,
-		},
-		MaxConnsPerHost: b.C * 2,
+// think pauses a worker for the think time. It returns false if ctx was
+// cancelled meanwhile.
+func (b *Boomer) think(ctx context.Context) bool {
+	d := b.ThinkTime
+	if b.ThinkTimeJitter > 0 {
+		d += time.Duration(rand.Int63n(int64(2*b.ThinkTimeJitter)+1)) - b.ThinkTimeJitter
+	}
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// newClient returns a client presenting the given client certificates.
+func (b *Boomer) newClient(certs []tls.Certificate) *fasthttp.Client {
+	var transport fasthttp.RoundTripper
+	if b.ExpectContinue {
+		if b.expect == nil {
+			b.expect = &expectTransport{timeout: b.Timeout, wait: b.ExpectContinueTimeout}
+		}
+		transport = b.expect
+	}
+	return &fasthttp.Client{
+		Transport:                     transport,
+		TLSConfig:                     b.tlsConfig(certs),
+		MaxConnsPerHost:               b.maxConnsPerHost(),
+		Dial:                          b.dial,
+		ReadBufferSize:                b.Client.ReadBufferSize,
+		WriteBufferSize:               b.Client.WriteBufferSize,
+		MaxIdleConnDuration:           b.Client.MaxIdleConnDuration,
+		MaxResponseBodySize:           b.Client.MaxResponseBodySize,
+		DisableHeaderNamesNormalizing: b.Client.DisableHeaderNamesNormalizing,
+	}
+}
+
+// oauth2Client returns the client of the token requests of OAuth2. They
+// go through the address overrides and the proxy, and trust the same
+// certificates, as the requests of the run, without being part of its
+// connection statistics.
+func (b *Boomer) oauth2Client() *fasthttp.Client {
+	return &fasthttp.Client{
+		TLSConfig: &tls.Config{InsecureSkipVerify: b.AllowInsecure, RootCAs: b.RootCAs},
+		Dial:      b.dialHost,
+	}
+}
+
+// tlsConfig returns the TLS configuration of a client presenting the
+// given client certificates.
+func (b *Boomer) tlsConfig(certs []tls.Certificate) *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify: b.AllowInsecure,
+		RootCAs:            b.RootCAs,
+		ServerName:         b.ServerName,
+		Certificates:       certs,
+	}
+}
+
+func (b *Boomer) maxConnsPerHost() int {
+	if b.Client.MaxConnsPerHost > 0 {
+		return b.Client.MaxConnsPerHost
+	}
+	return b.C * 2
+}
+
+// workerClients returns the client of every worker. The clients of the
+// previous run are reused if KeepConnections is set.
+func (b *Boomer) workerClients() []Doer {
+	doers := make([]Doer, b.C)
+	if b.Doer != nil {
+		b.warm = false
+		for i := range doers {
+			doers[i] = b.Doer
+		}
+		return doers
+	}
+	if b.Pipeline > 0 {
+		b.warm = false
+		d := b.newPipelineDoer()
+		for i := range doers {
+			doers[i] = d
+		}
+		return doers
+	}
+	b.warm = b.KeepConnections && len(b.clients) == b.C
+	clients := b.clients
+	if !b.warm {
+		b.ResetConnections()
+		client := b.newClient(b.Certificates)
+		clients = make([]*fasthttp.Client, b.C)
+		for i := range clients {
+			clients[i] = client
+			if n := len(b.Certificates); n > 1 {
+				clients[i] = b.newClient(b.Certificates[i%n : i%n+1])
+			}
+		}
+		if b.KeepConnections {
+			b.clients = clients
+		}
 	}
+	for i, c := range clients {
+		doers[i] = c
+	}
+	return doers
+}
+
+// ResetConnections closes the connections kept from previous runs, see
+// KeepConnections.
+func (b *Boomer) ResetConnections() {
+	for _, c := range b.clients {
+		c.CloseIdleConnections()
+	}
+	b.clients = nil
+	b.expect = nil
+}
+
+// runWorkers dispatches the requests to the workers and waits for all of
+// them to exit. Cancelling ctx stops the dispatching; the workers finish
+// their in-flight request and exit. It returns false if StopTimeout
+// elapsed before they did.
+func (b *Boomer) runWorkers(ctx context.Context) bool {
+	clients := b.workerClients()
 	var wg sync.WaitGroup
 	wg.Add(b.C)
 
 	var throttle <-chan time.Time
-	if b.Qps > 0 {
-		throttle = time.Tick(time.Duration(1e6/(b.Qps)) * time.Microsecond)
+	if b.rate != nil {
+		rctx, stop := context.WithCancel(ctx)
+		defer stop()
+		go b.rate.run(rctx)
+		throttle = b.rate.ticker.C
+	} else if b.Qps > 0 {
+		ticker := time.NewTicker(time.Duration(1e6/(b.Qps)) * time.Microsecond)
+		defer ticker.Stop()
+		throttle = ticker.C
 	}
 
-	jobsch := make(chan struct{}, b.C)
+	if b.tuner != nil {
+		tctx, stop := context.WithCancel(ctx)
+		defer stop()
+		go b.tuner.run(tctx)
+	}
+
+	jobsch := make(chan int, b.C)
 	for i := 0; i < b.C; i++ {
-		go b.runWorker(&wg, jobsch)
+		go b.runWorker(ctx, &wg, i, jobsch, clients[i])
+	}
+
+	maxPriority := b.targetList[0].Priority
+	for _, t := range b.targetList {
+		if t.Priority > maxPriority {
+			maxPriority = t.Priority
+		}
+	}
+
+	start := time.Now()
+	var timer *time.Timer
+	var skipped time.Duration
+	if len(b.Schedule) > 0 || b.Spike != nil {
+		timer = time.NewTimer(0)
+		defer timer.Stop()
+		if b.Spike != nil {
+			skipped = b.Spike.offset(b.Skip)
+		} else {
+			skipped = b.Schedule[b.Skip%len(b.Schedule)].Offset
+		}
+	}
+	b.targetRR.skip(b.Skip)
+	behind := false
+	var bursts <-chan time.Time
+	if b.Burst > 0 {
+		ticker := time.NewTicker(b.BurstInterval)
+		defer ticker.Stop()
+		bursts = ticker.C
 	}
 
 Loop:
 	for i := 0; i < b.N; i++ {
-		if b.Qps > 0 {
-			<-throttle
+		// The schedules and the rate limit are shifted by the pauses.
+		paused, ok := b.pause.wait(ctx)
+		if !ok {
+			break Loop
+		}
+		start = start.Add(paused)
+		seq := b.Skip + i
+		target := b.targetRR.next()
+		if timer != nil {
+			var offset time.Duration
+			if b.Spike != nil {
+				offset = b.Spike.offset(seq)
+			} else {
+				d := b.Schedule[seq%len(b.Schedule)]
+				target, offset = d.Target, d.Offset
+			}
+			if wait := time.Until(start.Add(offset - skipped)); wait > 0 {
+				timer.Reset(wait)
+				select {
+				case <-ctx.Done():
+					break Loop
+				case <-timer.C:
+				}
+			}
+		} else if b.Burst > 0 {
+			// The first burst is sent right away.
+			if i > 0 && i%b.Burst == 0 {
+				select {
+				case <-ctx.Done():
+					break Loop
+				case <-bursts:
+				}
+			}
+		} else if b.Qps > 0 {
+			select {
+			case <-ctx.Done():
+				break Loop
+			case <-throttle:
+			}
+			// The ticker drops the ticks missed while all the workers
+			// were busy.
+			if lag := int(time.Since(start).Seconds()*float64(b.Qps)) - i; b.rate == nil && !behind && lag > b.Qps {
+				behind = true
+				b.logf("the rate limit of %d qps is not met, %d requests behind: all %d workers are busy", b.Qps, lag, b.C)
+			}
+			// Lower priority requests are shed rather than delaying the
+			// following ones when all the workers are busy.
+			if b.targetList[target].Priority < maxPriority {
+				select {
+				case jobsch <- target:
+				default:
+					b.incProgress()
+					b.results <- &result{
+						shed:   true,
+						start:  time.Now(),
+						labels: b.targetLabels[target],
+						tags:   b.targetList[target].Tags,
+					}
+				}
+				continue
+			}
 		}
 		select {
-		case <-b.stop:
+		case <-ctx.Done():
 			break Loop
-		case jobsch <- struct{}{}:
-			continue
+		case jobsch <- target:
 		}
 	}
 	close(jobsch)
-	wg.Wait()
+	return b.waitWorkers(ctx, &wg)
+}
+
+// waitWorkers waits for the workers of wg to end, for up to StopTimeout
+// once ctx is done. It returns false if they did not.
+func (b *Boomer) waitWorkers(ctx context.Context, wg *sync.WaitGroup) bool {
+	if b.StopTimeout <= 0 {
+		wg.Wait()
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+	}
+	t := time.NewTimer(b.StopTimeout)
+	defer t.Stop()
+	select {
+	case <-done:
+		return true
+	case <-t.C:
+		return false
+	}
 }
 
 // cloneRequest returns a clone of the provided *http.Request.
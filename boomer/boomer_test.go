@@ -15,11 +15,29 @@
 package boomer
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
+	"fmt"
 	"github.com/valyala/fasthttp"
+	"io"
 	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -101,6 +119,9 @@ func TestRequest(t *testing.T) {
 		C:       1,
 	}
 	boomer.Run()
+	if method != "GET" {
+		t.Errorf("Method is expected to be GET, %v is found", method)
+	}
 	if uri != "/" {
 		t.Errorf("Uri is expected to be /, %v is found", uri)
 	}
@@ -111,7 +132,7 @@ func TestRequest(t *testing.T) {
 		t.Errorf("X-some header is expected to be value, %v is found", some)
 	}
 	if auth != "Basic dXNlcm5hbWU6cGFzc3dvcmQ=" {
-		t.Errorf("Basic authorization is not properly set: " + auth)
+		t.Errorf("Basic authorization is not properly set: %v", auth)
 	}
 }
 
@@ -140,3 +161,926 @@ func TestBody(t *testing.T) {
 		t.Errorf("Expected to boom 10 times, found %v", count)
 	}
 }
+
+func TestCookies(t *testing.T) {
+	var count int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err == nil && c.Value == "abc" {
+			atomic.AddInt64(&count, 1)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := &Boomer{
+		Request: req,
+		N:       10,
+		C:       1,
+		Cookies: true,
+	}
+	boomer.Run()
+	if count != 9 {
+		t.Errorf("Expected 9 requests to replay the session cookie, found %v", count)
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	var rendered *Report
+	boomer := &Boomer{
+		Request: req,
+		N:       10,
+		C:       2,
+		Renderer: RendererFunc(func(w io.Writer, r *Report) error {
+			rendered = r
+			return nil
+		}),
+	}
+	rep := boomer.Run()
+	if rendered != rep {
+		t.Fatalf("Expected the custom renderer to receive the returned report")
+	}
+	if rep.Count != 10 || rep.StatusCodeDist[200] != 10 {
+		t.Errorf("Expected 10 successful responses, found %v", rep.StatusCodeDist)
+	}
+	if len(rep.Histogram) == 0 || len(rep.TimeSeries) == 0 {
+		t.Errorf("Expected histogram and time series to be populated")
+	}
+}
+
+func TestFollowRedirects(t *testing.T) {
+	var count int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/a", http.StatusFound)
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		default:
+			atomic.AddInt64(&count, 1)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := &Boomer{
+		Request:         req,
+		N:               5,
+		C:               1,
+		FollowRedirects: 2,
+		Renderer:        RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if count != 5 || rep.StatusCodeDist[http.StatusAccepted] != 5 {
+		t.Errorf("Expected 5 followed requests, found %v with status codes %v", count, rep.StatusCodeDist)
+	}
+
+	boomer.FollowRedirects = 1
+	rep = boomer.Run()
+	if rep.StatusCodeDist[http.StatusMovedPermanently] != 5 {
+		t.Errorf("Expected to stop after one hop, found status codes %v", rep.StatusCodeDist)
+	}
+}
+
+// testCertificate returns a self-signed certificate for the given common
+// name, valid for localhost.
+func testCertificate(t *testing.T, cn string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestClientCertificates(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range r.TLS.PeerCertificates {
+			seen[c.Subject.CommonName]++
+		}
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(handler))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := &Boomer{
+		Request:       req,
+		N:             10,
+		C:             2,
+		AllowInsecure: true,
+		Certificates:  []tls.Certificate{testCertificate(t, "one"), testCertificate(t, "two")},
+		Renderer:      RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Count != 10 {
+		t.Fatalf("Expected 10 successful requests, found %v: %v", rep.Count, rep.ErrorDist)
+	}
+	if seen["one"] == 0 || seen["two"] == 0 || seen["one"]+seen["two"] != 10 {
+		t.Errorf("Expected both client certificates to be used, found %v", seen)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	var count int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+		time.Sleep(time.Millisecond)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := &Boomer{
+		Request:  req,
+		N:        1000000,
+		C:        4,
+		Output:   "csv",
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	done := make(chan *Report)
+	go func() {
+		done <- boomer.RunContext(ctx)
+	}()
+	select {
+	case rep := <-done:
+		if rep.Count == 0 || rep.Count >= int64(boomer.N) {
+			t.Errorf("Expected a partial run, found %v requests", rep.Count)
+		}
+		if rep.Count != atomic.LoadInt64(&count) {
+			t.Errorf("Expected every request made to be reported, found %v of %v", rep.Count, count)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cancelled run did not return")
+	}
+}
+
+func TestStop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		time.Sleep(time.Millisecond)
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:  req,
+		N:        1000000,
+		C:        4,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	boomer.Stop()
+	time.AfterFunc(100*time.Millisecond, boomer.Stop)
+
+	done := make(chan *Report)
+	go func() {
+		done <- boomer.Run()
+	}()
+	select {
+	case rep := <-done:
+		if rep.Count == 0 || rep.Count >= int64(boomer.N) {
+			t.Errorf("Expected a partial run, found %v requests", rep.Count)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stopped run did not return")
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	boomer := &Boomer{
+		Request:     newGet(server.URL),
+		N:           10,
+		C:           2,
+		StopTimeout: 50 * time.Millisecond,
+		Renderer:    RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	time.AfterFunc(50*time.Millisecond, boomer.Stop)
+
+	done := make(chan *Report)
+	go func() {
+		done <- boomer.Run()
+	}()
+	select {
+	case rep := <-done:
+		if rep.Count != 0 {
+			t.Errorf("Expected the requests in flight to be left out, found %v", rep.Count)
+		}
+		if !strings.HasPrefix(rep.Aborted, "interrupted") {
+			t.Errorf("Expected the report to be marked as interrupted, found %q", rep.Aborted)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stopped run did not return past StopTimeout")
+	}
+}
+
+func TestRootCAsAndServerName(t *testing.T) {
+	var serverName atomic.Value
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		serverName.Store(r.TLS.ServerName)
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := &Boomer{
+		Request:    req,
+		N:          2,
+		C:          1,
+		RootCAs:    pool,
+		ServerName: "example.com",
+		Renderer:   RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Count != 2 {
+		t.Fatalf("Expected the server to be trusted, found errors %v", rep.ErrorDist)
+	}
+	if name := serverName.Load(); name != "example.com" {
+		t.Errorf("Expected SNI to be example.com, found %v", name)
+	}
+
+	boomer.RootCAs = nil
+	rep = boomer.Run()
+	if rep.Count != 0 {
+		t.Errorf("Expected the server not to be trusted without the CA")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	var host atomic.Value
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		host.Store(r.Host)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://example.com:8080/")
+	req.Header.SetMethod("GET")
+	boomer := &Boomer{
+		Request:  req,
+		N:        1,
+		C:        1,
+		Resolve:  map[string]string{"example.com:8080": server.Listener.Addr().String()},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Count != 1 {
+		t.Fatalf("Expected the request to reach the server, found errors %v", rep.ErrorDist)
+	}
+	if h := host.Load(); h != "example.com:8080" {
+		t.Errorf("Expected the Host header to be kept, found %v", h)
+	}
+}
+
+func TestBadAuthRatio(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	req.Header.Set("Authorization", "Bearer secret")
+	boomer := &Boomer{
+		Request:      req,
+		N:            100,
+		C:            1,
+		BadAuthRatio: 0.05,
+		Renderer:     RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	invalid, valid := rep.Breakdowns["auth"]["invalid"], rep.Breakdowns["auth"]["valid"]
+	if invalid == nil || valid == nil {
+		t.Fatalf("Expected an auth breakdown, found %v", rep.Breakdowns)
+	}
+	if invalid.StatusCodeDist[http.StatusUnauthorized] != 5 || valid.StatusCodeDist[http.StatusOK] != 95 {
+		t.Errorf("Expected 5 unauthorized and 95 ok responses, found %v and %v", invalid.StatusCodeDist, valid.StatusCodeDist)
+	}
+}
+
+func TestStatusBreakdown(t *testing.T) {
+	var n int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every other request is shed quickly, the others are slow.
+		if atomic.AddInt32(&n, 1)%2 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:  req,
+		N:        10,
+		C:        1,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	ok, unavailable := rep.Breakdowns["status"]["2xx"], rep.Breakdowns["status"]["5xx"]
+	if ok == nil || unavailable == nil {
+		t.Fatalf("Expected a status breakdown, found %v", rep.Breakdowns)
+	}
+	if ok.Count != 5 || unavailable.Count != 5 || unavailable.StatusCodeDist[http.StatusServiceUnavailable] != 5 {
+		t.Errorf("Expected 5 ok and 5 unavailable responses, found %+v and %+v", ok, unavailable)
+	}
+	if unavailable.Average >= ok.Average || ok.Average < 10*time.Millisecond {
+		t.Errorf("Expected fast 5xx and slow 2xx responses, found %s and %s", unavailable.Average, ok.Average)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+	var count int64
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+	})}
+	go server.Serve(l)
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://app/")
+	req.Header.SetMethod("GET")
+	boomer := &Boomer{
+		Request:    req,
+		N:          10,
+		C:          2,
+		UnixSocket: path,
+		Renderer:   RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	boomer.Run()
+	if count != 10 {
+		t.Errorf("Expected to boom 10 times over the unix socket, found %v", count)
+	}
+}
+
+func TestPriorityShedding(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	high := fasthttp.AcquireRequest()
+	high.SetRequestURI(server.URL + "/high")
+	low := fasthttp.AcquireRequest()
+	low.SetRequestURI(server.URL + "/low")
+	boomer := &Boomer{
+		Targets: []Target{
+			{Request: high, Priority: 1},
+			{Request: low},
+		},
+		N:        40,
+		C:        1,
+		Qps:      1000,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	hi, lo := rep.Breakdowns["priority"]["1"], rep.Breakdowns["priority"]["0"]
+	if hi == nil || lo == nil {
+		t.Fatalf("Expected a priority breakdown, found %v", rep.Breakdowns)
+	}
+	if hi.Count != 20 || hi.Shed != 0 {
+		t.Errorf("Expected every high priority request to be sent, found %+v", hi)
+	}
+	if lo.Shed == 0 || lo.Shed != rep.Shed || lo.Count+lo.Shed != 20 {
+		t.Errorf("Expected low priority requests to be shed, found %+v", lo)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	a := fasthttp.AcquireRequest()
+	a.SetRequestURI(server.URL + "/a")
+	b := fasthttp.AcquireRequest()
+	b.SetRequestURI(server.URL + "/b")
+	boomer := &Boomer{
+		Targets:  []Target{{Request: a}, {Request: b}},
+		Schedule: []Dispatch{{1, 0}, {1, 50 * time.Millisecond}, {0, 100 * time.Millisecond}},
+		N:        3,
+		C:        1,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	start := time.Now()
+	boomer.Run()
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected the requests to be paced by the schedule, the run took %v", elapsed)
+	}
+	if strings.Join(paths, ",") != "/b,/b,/a" {
+		t.Errorf("Expected the targets of the schedule in order, found %v", paths)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	a := fasthttp.AcquireRequest()
+	a.SetRequestURI(server.URL + "/a")
+	b := fasthttp.AcquireRequest()
+	b.SetRequestURI(server.URL + "/b")
+	boomer := &Boomer{
+		Targets: []Target{{Request: a}, {Request: b}},
+		Schedule: []Dispatch{
+			{0, 0}, {0, 0}, {1, 200 * time.Millisecond}, {0, 300 * time.Millisecond},
+		},
+		N:        2,
+		Skip:     2,
+		C:        1,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	start := time.Now()
+	boomer.Run()
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 250*time.Millisecond {
+		t.Errorf("Expected the schedule to go on from the skipped requests, the run took %v", elapsed)
+	}
+	if strings.Join(paths, ",") != "/b,/a" {
+		t.Errorf("Expected the tail of the schedule, found %v", paths)
+	}
+}
+
+func TestEndpointBreakdown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var targets []Target
+	for _, uri := range []string{"/items?id=1", "/items?id=2", "/search"} {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(server.URL + uri)
+		targets = append(targets, Target{Request: req})
+	}
+	targets[2].Name = "search"
+	boomer := &Boomer{
+		Targets:  targets,
+		N:        30,
+		C:        1,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	items, search := rep.Breakdowns["endpoint"]["GET /items"], rep.Breakdowns["endpoint"]["search"]
+	if items == nil || search == nil || len(rep.Breakdowns["endpoint"]) != 2 {
+		t.Fatalf("Expected an endpoint breakdown, found %v", rep.Breakdowns)
+	}
+	if items.Count != 20 || search.Count != 10 || len(items.Latencies) == 0 {
+		t.Errorf("Expected the targets of a path to be grouped, found %+v and %+v", items, search)
+	}
+}
+
+func TestPathTemplate(t *testing.T) {
+	for path, want := range map[string]string{
+		"/users/42/orders": "/users/{id}/orders",
+		"/users/3f2504e0-4f89-11d3-9a0c-0305e82c3301": "/users/{id}",
+		"/v2/items": "/v2/items",
+		"/":         "/",
+	} {
+		if got := PathTemplate(path); got != want {
+			t.Errorf("PathTemplate(%q) = %q, expected %q", path, got, want)
+		}
+	}
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	r := newWeightedRoundRobin([]Target{{Weight: 6}, {Weight: 3}, {}})
+	// A prefix shorter than a cycle of 10 already gets the mix.
+	var prefix []int
+	counts := make([]int, 3)
+	for i := 0; i < 5; i++ {
+		prefix = append(prefix, r.next())
+		counts[prefix[i]]++
+	}
+	if !reflect.DeepEqual(prefix, []int{0, 1, 0, 0, 1}) {
+		t.Errorf("Expected the targets to be interleaved, found %v in the first 5 picks", prefix)
+	}
+	for i := 5; i < 100; i++ {
+		counts[r.next()]++
+	}
+	if !reflect.DeepEqual(counts, []int{60, 30, 10}) {
+		t.Errorf("Expected the picks to follow the weights, found %v", counts)
+	}
+
+	// Skipping goes on with the same sequence.
+	a, b := newWeightedRoundRobin([]Target{{Weight: 2}, {Weight: 1}}), newWeightedRoundRobin([]Target{{Weight: 2}, {Weight: 1}})
+	for i := 0; i < 4; i++ {
+		a.next()
+	}
+	b.skip(4)
+	for i := 0; i < 6; i++ {
+		if x, y := a.next(), b.next(); x != y {
+			t.Fatalf("Expected the skipped sequence to go on as the picked one, found %d and %d", y, x)
+		}
+	}
+}
+
+func TestBreakdownClassesCap(t *testing.T) {
+	bs := make(breakdowns)
+	for i := 0; i < maxClasses+10; i++ {
+		bs.add(&result{labels: []label{{endpointDimension, strconv.Itoa(i)}}})
+	}
+	classes := bs[endpointDimension]
+	if len(classes) != maxClasses+1 || classes[otherClass] == nil || classes[otherClass].Count != 10 {
+		t.Errorf("Expected %d classes and the results of the next ones in %s, found %d", maxClasses, otherClass, len(classes))
+	}
+}
+
+func TestTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	eu := fasthttp.AcquireRequest()
+	eu.SetRequestURI(server.URL + "/eu")
+	us := fasthttp.AcquireRequest()
+	us.SetRequestURI(server.URL + "/us")
+	boomer := &Boomer{
+		Targets: []Target{
+			{Request: eu, Tags: map[string]string{"region": "eu", "tier": "web"}},
+			{Request: us, Tags: map[string]string{"region": "us", "tier": "web"}},
+		},
+		N:         10,
+		C:         1,
+		Verbosity: VerbosityDetailed,
+		Renderer:  RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	results := boomer.Results()
+	tagged := make(chan int)
+	go func() {
+		n := 0
+		for res := range results {
+			if res.Tags["tier"] == "web" {
+				n++
+			}
+		}
+		tagged <- n
+	}()
+	rep := boomer.Run()
+	if n := <-tagged; n != 10 {
+		t.Errorf("Expected every result to carry its tags, found %d of 10", n)
+	}
+	regions := rep.Breakdowns["region"]
+	if regions["eu"] == nil || regions["eu"].Count != 5 || regions["us"] == nil || regions["us"].Count != 5 {
+		t.Errorf("Expected a breakdown per region, found %v", regions)
+	}
+	if tier := rep.Breakdowns["tier"]["web"]; tier == nil || tier.Count != 10 {
+		t.Errorf("Expected a breakdown per tier, found %v", rep.Breakdowns["tier"])
+	}
+	if len(rep.Samples) != 10 || rep.Samples[0].Tags["region"] == "" {
+		t.Errorf("Expected the samples to carry their tags, found %+v", rep.Samples)
+	}
+	var csv bytes.Buffer
+	if err := (CSVRenderer{Fields: []string{"tags"}}).Render(&csv, rep); err != nil {
+		t.Fatal(err)
+	}
+	if want := `"region=eu;tier=web"`; !strings.Contains(csv.String(), want) {
+		t.Errorf("Expected %s in the tags column, found %q", want, csv.String())
+	}
+}
+
+func TestBurst(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:       req,
+		N:             12,
+		C:             4,
+		Burst:         4,
+		BurstInterval: 50 * time.Millisecond,
+		Renderer:      RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Burst != 4 || rep.BurstInterval != 50*time.Millisecond {
+		t.Errorf("Expected the bursts in the report, found %d every %v", rep.Burst, rep.BurstInterval)
+	}
+	if len(times) != 12 {
+		t.Fatalf("Expected 12 requests, found %d", len(times))
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	if elapsed := times[11].Sub(times[0]); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected three bursts 50ms apart, the requests took %v", elapsed)
+	}
+	for i := 4; i < 12; i += 4 {
+		if gap := times[i].Sub(times[i-1]); gap < 40*time.Millisecond {
+			t.Errorf("Expected a pause before burst %d, found %v", i/4, gap)
+		}
+	}
+}
+
+func TestKeepConnections(t *testing.T) {
+	var conns int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:         req,
+		N:               10,
+		C:               1,
+		KeepConnections: true,
+		Renderer:        RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	if rep := boomer.Run(); rep.WarmConnections {
+		t.Errorf("Expected the first run to start cold")
+	}
+	if rep := boomer.Run(); !rep.WarmConnections {
+		t.Errorf("Expected the second run to start warm")
+	}
+	if n := atomic.LoadInt64(&conns); n != 1 {
+		t.Errorf("Expected a single connection across runs, found %v", n)
+	}
+	boomer.ResetConnections()
+	if rep := boomer.Run(); rep.WarmConnections {
+		t.Errorf("Expected a run after a reset to start cold")
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 1024))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:  req,
+		N:        3,
+		C:        1,
+		Client:   ClientOptions{MaxResponseBodySize: 100},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Count != 0 || len(rep.ErrorDist) == 0 {
+		t.Errorf("Expected responses over the maximum body size to fail, found %v responses", rep.Count)
+	}
+}
+
+func TestOAuth2(t *testing.T) {
+	var issued int64
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if r.FormValue("grant_type") != "client_credentials" || user != "id" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt64(&issued, 1)
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":1}`, n)
+	}))
+	defer tokenServer.Close()
+
+	var authorized int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+			atomic.AddInt64(&authorized, 1)
+		}
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:  req,
+		N:        15,
+		C:        1,
+		Qps:      10,
+		OAuth2:   &OAuth2{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	boomer.Run()
+	if authorized != 15 {
+		t.Errorf("Expected every request to carry a token, found %v", authorized)
+	}
+	if n := atomic.LoadInt64(&issued); n < 2 {
+		t.Errorf("Expected the token to be refreshed during the run, found %v tokens issued", n)
+	}
+}
+
+func TestOAuth2Client(t *testing.T) {
+	var requested int64
+	failing := int32(1)
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requested, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"token"}`)
+	}))
+	defer tokenServer.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request: req,
+		N:       20,
+		C:       1,
+		// The token host only resolves through the overrides of the run.
+		Resolve:  map[string]string{"tokens.invalid:80": strings.TrimPrefix(tokenServer.URL, "http://")},
+		OAuth2:   &OAuth2{TokenURL: "http://tokens.invalid/token"},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if atomic.LoadInt64(&requested) != 1 || rep.ErrorDist == nil {
+		t.Errorf("Expected a single token request to fail and the next ones to back off, found %d requests", requested)
+	}
+
+	// The check before the run neither needs the run, nor delays the
+	// token requests of the run once it failed.
+	boomer.OAuth2 = &OAuth2{TokenURL: "http://tokens.invalid/token"}
+	if err := boomer.CheckOAuth2(); err == nil {
+		t.Errorf("Expected the check to fail")
+	}
+	atomic.StoreInt32(&failing, 0)
+	if err := boomer.CheckOAuth2(); err != nil {
+		t.Errorf("Expected the check to go through the overrides, found %v", err)
+	}
+	if token, err := boomer.OAuth2.Token(); token != "token" || err != nil {
+		t.Errorf("Expected the token of the check, found %q and %v", token, err)
+	}
+	boomer.OAuth2 = &OAuth2{TokenURL: "http://tokens.invalid/token"}
+	if rep := boomer.Run(); rep.Count != 20 {
+		t.Errorf("Expected the token to be requested through the overrides, found %d responses and errors %v", rep.Count, rep.ErrorDist)
+	}
+}
+
+func TestOAuth2Refresh(t *testing.T) {
+	release := make(chan struct{})
+	var issued int64
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := atomic.AddInt64(&issued, 1); n > 1 {
+			<-release
+		}
+		fmt.Fprint(w, `{"access_token":"token","expires_in":100}`)
+	}))
+	defer tokenServer.Close()
+	defer close(release)
+
+	o := &OAuth2{TokenURL: tokenServer.URL}
+	if _, err := o.Token(); err != nil {
+		t.Fatal(err)
+	}
+	// Due for a refresh, but not expired.
+	o.mu.Lock()
+	o.refreshAt = time.Now()
+	o.mu.Unlock()
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if token, err := o.Token(); token != "token" || err != nil {
+			t.Fatalf("Expected the previous token during the refresh, found %q and %v", token, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the refresh not to block the callers, took %v", elapsed)
+	}
+	if n := atomic.LoadInt64(&issued); n > 2 {
+		t.Errorf("Expected a single refresh at a time, found %d token requests", n)
+	}
+}
+
+func TestThinkTime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:         req,
+		N:               4,
+		C:               2,
+		ThinkTime:       50 * time.Millisecond,
+		ThinkTimeJitter: 10 * time.Millisecond,
+		Renderer:        RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Total < 40*time.Millisecond || rep.Total > 500*time.Millisecond {
+		t.Errorf("Expected a single think time per worker, the run took %v", rep.Total)
+	}
+	if rep.Slowest > 40*time.Millisecond {
+		t.Errorf("Expected the think time not to be measured, found %v", rep.Slowest)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Echo", r.Header.Get("X-Request-Id"))
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	var sent, echoed int64
+	boomer := &Boomer{
+		Request: req,
+		N:       20,
+		C:       4,
+		BeforeRequest: func(req *fasthttp.Request) {
+			req.Header.Set("X-Request-Id", strconv.FormatInt(atomic.AddInt64(&sent, 1), 10))
+		},
+		AfterResponse: func(req *fasthttp.Request, resp *fasthttp.Response, err error) {
+			if err == nil && bytes.Equal(resp.Header.Peek("X-Echo"), req.Header.Peek("X-Request-Id")) {
+				atomic.AddInt64(&echoed, 1)
+			}
+		},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	boomer.Run()
+	if sent != 20 || echoed != 20 {
+		t.Errorf("Expected the hooks to see every request, found %d sent and %d echoed", sent, echoed)
+	}
+}
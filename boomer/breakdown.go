@@ -0,0 +1,198 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// label classifies a result along a dimension, e.g. "auth" is "invalid".
+type label struct {
+	dimension string
+	value     string
+}
+
+// Breakdown holds the statistics of a class of requests.
+type Breakdown struct {
+	Count          int64
+	Errors         int64
+	Shed           int64
+	Fastest        time.Duration
+	Slowest        time.Duration
+	Average        time.Duration
+	StatusCodeDist map[int]int
+	Latencies      []LatencyDistribution
+}
+
+// breakdown accumulates the results of a class of requests.
+type breakdown struct {
+	Breakdown
+	total time.Duration
+	lats  reservoir
+}
+
+func newBreakdown() *breakdown {
+	return &breakdown{
+		Breakdown: Breakdown{StatusCodeDist: make(map[int]int)},
+	}
+}
+
+func (b *breakdown) add(res *result) {
+	if res.shed {
+		b.Shed++
+		return
+	}
+	if res.err != nil {
+		b.Errors++
+		return
+	}
+	b.Count++
+	b.total += res.duration
+	if b.Fastest == 0 || res.duration < b.Fastest {
+		b.Fastest = res.duration
+	}
+	if res.duration > b.Slowest {
+		b.Slowest = res.duration
+	}
+	b.StatusCodeDist[res.statusCode]++
+	b.lats.add(res.duration)
+}
+
+func (b *breakdown) build(pctls []int) *Breakdown {
+	out := b.Breakdown
+	if out.Count > 0 {
+		out.Average = b.total / time.Duration(out.Count)
+		out.Latencies = quantiles(&b.lats, pctls)
+	}
+	return &out
+}
+
+// statusDimension breaks down the responses per status class, e.g. for
+// the fast 503s of a circuit breaker not to hide in the overall latency.
+const statusDimension = "status"
+
+// statusClass returns the class of a status code, e.g. "5xx".
+func statusClass(code int) string {
+	return fmt.Sprintf("%dxx", code/100)
+}
+
+// maxClasses is the number of classes of a dimension broken down, e.g.
+// the endpoints of the targets imported from an access log. The results
+// of the next classes are accumulated in otherClass.
+const maxClasses = 100
+
+// otherClass is the class of the results beyond maxClasses.
+const otherClass = "(other)"
+
+// breakdowns accumulates results per dimension and class.
+type breakdowns map[string]map[string]*breakdown
+
+func (bs breakdowns) add(res *result) {
+	for _, l := range res.labels {
+		bs.class(l.dimension, l.value).add(res)
+	}
+}
+
+// class returns the breakdown of a class, creating it if needed, or the
+// breakdown of otherClass once the dimension has maxClasses.
+func (bs breakdowns) class(dimension, value string) *breakdown {
+	classes, ok := bs[dimension]
+	if !ok {
+		classes = make(map[string]*breakdown)
+		bs[dimension] = classes
+	}
+	b, ok := classes[value]
+	if !ok {
+		if len(classes) >= maxClasses {
+			value = otherClass
+			if b, ok = classes[value]; ok {
+				return b
+			}
+		}
+		b = newBreakdown()
+		classes[value] = b
+	}
+	return b
+}
+
+func (bs breakdowns) build(pctls []int) map[string]map[string]*Breakdown {
+	if len(bs) == 0 {
+		return nil
+	}
+	out := make(map[string]map[string]*Breakdown, len(bs))
+	for dim, classes := range bs {
+		out[dim] = make(map[string]*Breakdown, len(classes))
+		for class, b := range classes {
+			out[dim][class] = b.build(pctls)
+		}
+	}
+	return out
+}
+
+func printBreakdowns(w io.Writer, all map[string]map[string]*Breakdown) {
+	for _, dim := range sortedKeys(all) {
+		classes := all[dim]
+		if dim == statusDimension && len(classes) < 2 {
+			// A single class is the summary.
+			continue
+		}
+		fmt.Fprintf(w, "\nBreakdown by %s:\n", dim)
+		names := make([]string, 0, len(classes))
+		for class := range classes {
+			names = append(names, class)
+		}
+		sort.Strings(names)
+		for _, class := range names {
+			b := classes[class]
+			fmt.Fprintf(w, "  [%s]\t%s responses, %s errors", class, formatCount(float64(b.Count)), formatCount(float64(b.Errors)))
+			if b.Errors > 0 {
+				fmt.Fprintf(w, " (%.1f%%)", float64(b.Errors)/float64(b.Count+b.Errors)*100)
+			}
+			if b.Shed > 0 {
+				fmt.Fprintf(w, ", %s shed", formatCount(float64(b.Shed)))
+			}
+			if b.Count > 0 {
+				fmt.Fprintf(w, ", average %s", formatSeconds(b.Average.Seconds()))
+				for _, l := range b.Latencies {
+					if l.Percentage == 50 || l.Percentage == 99 {
+						fmt.Fprintf(w, ", p%d %s", l.Percentage, formatSeconds(l.Latency.Seconds()))
+					}
+				}
+			}
+			fmt.Fprintln(w)
+			codes := make([]string, 0, len(b.StatusCodeDist))
+			for code, num := range b.StatusCodeDist {
+				codes = append(codes, fmt.Sprintf("[%d] %s", code, formatCount(float64(num))))
+			}
+			sort.Strings(codes)
+			if len(codes) > 0 {
+				fmt.Fprintf(w, "  \t%s\n", strings.Join(codes, ", "))
+			}
+		}
+	}
+}
+
+func sortedKeys(m map[string]map[string]*Breakdown) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
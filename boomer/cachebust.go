@@ -0,0 +1,46 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// cacheBuster sets a query parameter of the requests to a value unique to
+// every request: a random prefix, drawn for every run, followed by the
+// number of the request. It is safe for concurrent use.
+type cacheBuster struct {
+	name   string
+	prefix string
+	n      uint64
+}
+
+func newCacheBuster(name string) *cacheBuster {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return &cacheBuster{name: name, prefix: hex.EncodeToString(b)}
+}
+
+// apply sets the parameter of req, replacing the value of the previous
+// request.
+func (c *cacheBuster) apply(req *fasthttp.Request) {
+	n := atomic.AddUint64(&c.n, 1)
+	req.URI().QueryArgs().Set(c.name, c.prefix+strconv.FormatUint(n, 36))
+}
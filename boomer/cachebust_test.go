@@ -0,0 +1,53 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestCacheBust(t *testing.T) {
+	var mu sync.Mutex
+	values := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "a b" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		values[r.URL.Query().Get("cb")] = true
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	boomer := &Boomer{
+		Request:   newGet(server.URL + "/?q=a+b"),
+		N:         20,
+		C:         2,
+		CacheBust: "cb",
+		Renderer:  RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.StatusCodeDist[http.StatusOK] != 20 {
+		t.Fatalf("Expected the query to be kept, found %v %v", rep.StatusCodeDist, rep.ErrorDist)
+	}
+	if len(values) != 20 || values[""] {
+		t.Errorf("Expected 20 unique values, found %d", len(values))
+	}
+}
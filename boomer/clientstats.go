@@ -0,0 +1,40 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import "runtime/metrics"
+
+const (
+	heapMetric      = "/memory/classes/heap/objects:bytes"
+	goroutineMetric = "/sched/goroutines:goroutines"
+)
+
+// ClientStats are the resources held by the load generator itself, to
+// tell its own growth from the degradation of the target.
+type ClientStats struct {
+	// HeapBytes is the memory of the live and not yet swept heap objects.
+	HeapBytes uint64
+
+	// Goroutines is the number of live goroutines.
+	Goroutines uint64
+}
+
+// ReadClientStats reads the ClientStats of the process. Unlike
+// runtime.ReadMemStats, it does not stop the world.
+func ReadClientStats() ClientStats {
+	samples := []metrics.Sample{{Name: heapMetric}, {Name: goroutineMetric}}
+	metrics.Read(samples)
+	return ClientStats{HeapBytes: samples[0].Value.Uint64(), Goroutines: samples[1].Value.Uint64()}
+}
@@ -0,0 +1,106 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"io"
+)
+
+// Tolerance bounds how much worse than a baseline a run can be before
+// Compare reports a regression.
+type Tolerance struct {
+	// Latency is the relative increase allowed for the latencies, e.g.
+	// 0.1 for 10%.
+	Latency float64
+
+	// Throughput is the relative decrease allowed for the number of
+	// responses per second.
+	Throughput float64
+
+	// ErrorRate is the increase allowed for the share of errors and 5xx
+	// responses, e.g. 0.01 for 1 point.
+	ErrorRate float64
+}
+
+// Change is the change of a metric from a baseline report to a new one.
+type Change struct {
+	// Metric is rps, error_rate, avg, or a latency percentile, e.g. p99,
+	// as for thresholds.
+	Metric string
+
+	// Base and Head are the values of the metric, in seconds for the
+	// latencies.
+	Base float64
+	Head float64
+
+	// Delta is the relative change from Base to Head, zero if Base is.
+	Delta float64
+
+	// Regression is set if the change exceeds the Tolerance.
+	Regression bool
+
+	base, head string
+}
+
+// Compare returns the changes of the throughput, the error rate and the
+// latencies from base to head. Latency percentiles are compared if both
+// reports hold them.
+func Compare(base, head *Report, tol Tolerance) []Change {
+	metrics := []string{"rps", "error_rate", "avg"}
+	for _, l := range base.Latencies {
+		for _, hl := range head.Latencies {
+			if l.Percentage == hl.Percentage {
+				metrics = append(metrics, fmt.Sprintf("p%d", l.Percentage))
+			}
+		}
+	}
+	var changes []Change
+	for _, m := range metrics {
+		t := Threshold{Metric: m}
+		c := Change{Metric: m}
+		c.Base, c.base = t.measure(base)
+		c.Head, c.head = t.measure(head)
+		if c.Base != 0 {
+			c.Delta = (c.Head - c.Base) / c.Base
+		}
+		switch m {
+		case "rps":
+			c.Regression = c.Delta < -tol.Throughput
+		case "error_rate":
+			c.Regression = c.Head-c.Base > tol.ErrorRate
+		default:
+			c.Regression = c.Delta > tol.Latency
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// PrintComparison writes the changes returned by Compare as a table.
+func PrintComparison(w io.Writer, changes []Change) {
+	fmt.Fprintf(w, "\nComparison:\n")
+	for _, c := range changes {
+		verdict := ""
+		if c.Regression {
+			verdict = "\tregression"
+		}
+		delta := "n/a"
+		if c.Base != 0 {
+			delta = fmt.Sprintf("%+.1f%%", c.Delta*100)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t-> %s\t%s%s\n", c.Metric, c.base, c.head, delta, verdict)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	base := &Report{
+		RPS:            1000,
+		Average:        10 * time.Millisecond,
+		Count:          100,
+		StatusCodeDist: map[int]int{200: 100},
+		Latencies:      []LatencyDistribution{{Percentage: 50, Latency: 10 * time.Millisecond}, {Percentage: 99, Latency: 20 * time.Millisecond}},
+	}
+	head := &Report{
+		RPS:            950,
+		Average:        10 * time.Millisecond,
+		Count:          100,
+		StatusCodeDist: map[int]int{200: 98, 503: 2},
+		Latencies:      []LatencyDistribution{{Percentage: 99, Latency: 30 * time.Millisecond}},
+	}
+	changes := Compare(base, head, Tolerance{Latency: 0.1, Throughput: 0.1, ErrorRate: 0.01})
+	regressions := map[string]bool{}
+	for _, c := range changes {
+		regressions[c.Metric] = c.Regression
+	}
+	expected := map[string]bool{"rps": false, "error_rate": true, "avg": false, "p99": true}
+	if len(regressions) != len(expected) {
+		t.Fatalf("Expected the metrics of both reports only, found %v", regressions)
+	}
+	for m, r := range expected {
+		if regressions[m] != r {
+			t.Errorf("Expected regression %v for %s, found %v", r, m, regressions[m])
+		}
+	}
+
+	var buf bytes.Buffer
+	PrintComparison(&buf, changes)
+	if !strings.Contains(buf.String(), "p99\t20.000 ms\t-> 30.000 ms\t+50.0%\tregression") {
+		t.Errorf("Unexpected comparison:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Compression describes the compression of the response bodies, if
+// Boomer.Decompress is set.
+type Compression struct {
+	// Encodings counts the responses per Content-Encoding, "identity"
+	// for the uncompressed ones.
+	Encodings map[string]int64
+
+	// WireBytes is the size of the bodies as received, DecodedBytes once
+	// decompressed, and Ratio the second over the first.
+	WireBytes    int64
+	DecodedBytes int64
+	Ratio        float64
+}
+
+// DecompressedBody returns the body of resp, decompressed per its
+// Content-Encoding: gzip, deflate, br or zstd.
+func DecompressedBody(resp *fasthttp.Response) ([]byte, error) {
+	switch string(bytes.ToLower(resp.Header.Peek("Content-Encoding"))) {
+	case "gzip":
+		return resp.BodyGunzip()
+	case "deflate":
+		return resp.BodyInflate()
+	case "br":
+		return resp.BodyUnbrotli()
+	case "zstd":
+		return resp.BodyUnzstd()
+	}
+	return resp.Body(), nil
+}
+
+// decompress replaces the body of resp by its decompressed version. It
+// returns the Content-Encoding and the sizes of the body before and
+// after.
+func decompress(resp *fasthttp.Response) (string, int, int, error) {
+	encoding := string(bytes.ToLower(resp.Header.Peek("Content-Encoding")))
+	wire := len(resp.Body())
+	if encoding == "" || encoding == "identity" {
+		return "identity", wire, wire, nil
+	}
+	body, err := DecompressedBody(resp)
+	if err != nil {
+		return encoding, wire, 0, fmt.Errorf("could not decompress the %s body: %v", encoding, err)
+	}
+	resp.SetBody(body)
+	resp.Header.Del("Content-Encoding")
+	return encoding, wire, len(body), nil
+}
+
+// compressions accumulates the compression of the successful responses.
+type compressions struct {
+	Compression
+}
+
+func newCompressions() *compressions {
+	return &compressions{Compression{Encodings: make(map[string]int64)}}
+}
+
+func (c *compressions) add(res *result) {
+	c.Encodings[res.encoding]++
+	c.WireBytes += int64(res.wireSize)
+	c.DecodedBytes += int64(res.decodedSize)
+}
+
+func (c *compressions) build() *Compression {
+	out := c.Compression
+	if out.WireBytes > 0 {
+		out.Ratio = float64(out.DecodedBytes) / float64(out.WireBytes)
+	}
+	return &out
+}
+
+func printCompression(w io.Writer, c *Compression) {
+	fmt.Fprintf(w, "\nCompression:\n")
+	for _, enc := range sortedCounts(c.Encodings) {
+		fmt.Fprintf(w, "  [%s]\t%s responses\n", enc, formatCount(float64(c.Encodings[enc])))
+	}
+	fmt.Fprintf(w, "  Wire size:\t%s.\n", formatBytes(c.WireBytes))
+	fmt.Fprintf(w, "  Decompressed size:\t%s.\n", formatBytes(c.DecodedBytes))
+	fmt.Fprintf(w, "  Ratio:\t%.2f\n", c.Ratio)
+}
+
+func sortedCounts(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
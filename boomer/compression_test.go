@@ -0,0 +1,69 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestDecompress(t *testing.T) {
+	body := bytes.Repeat([]byte("compressible "), 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(fasthttp.AppendGzipBytes(nil, body))
+		case "/br":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write(fasthttp.AppendBrotliBytes(nil, body))
+		default:
+			w.Write(body)
+		}
+	}))
+	defer server.Close()
+
+	var targets []Target
+	for _, path := range []string{"/gzip", "/br", "/"} {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(server.URL + path)
+		targets = append(targets, Target{Request: req})
+	}
+	boomer := &Boomer{
+		Targets:    targets,
+		N:          6,
+		C:          1,
+		Decompress: true,
+		Assertions: []Assertion{ExpectBodyRegexp(regexp.MustCompile("^compressible"))},
+		Renderer:   RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Count != 6 {
+		t.Fatalf("Expected the bodies to be decompressed, found errors %v", rep.ErrorDist)
+	}
+	c := rep.Compression
+	if c.Encodings["gzip"] != 2 || c.Encodings["br"] != 2 || c.Encodings["identity"] != 2 {
+		t.Errorf("Expected 2 responses per encoding, found %v", c.Encodings)
+	}
+	if c.DecodedBytes != int64(6*len(body)) || c.WireBytes >= c.DecodedBytes/2 || c.Ratio < 2 {
+		t.Errorf("Expected the compressed bodies to be smaller, found %+v", c)
+	}
+}
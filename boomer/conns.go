@@ -0,0 +1,117 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"net"
+	"sync"
+)
+
+// Connections describes the connections of the clients during a run. It
+// is not known when Boomer.Doer is set.
+type Connections struct {
+	// Dialed is the number of connections opened.
+	Dialed int64
+
+	// DialsPerSec is the rate at which they were opened.
+	DialsPerSec float64
+
+	// Peak is the highest number of connections open at once, including
+	// those kept from a previous run.
+	Peak int64
+
+	// ReuseRate is the estimated share, between 0 and 1, of the requests
+	// sent on a connection opened for a previous request.
+	ReuseRate float64
+
+	// IPv4 and IPv6 are the number of connections dialed per address
+	// family, not counting unix sockets.
+	IPv4 int64
+	IPv6 int64
+}
+
+// connStats counts the connections dialed by the clients of a Boomer,
+// across its runs.
+type connStats struct {
+	mu     sync.Mutex
+	dialed int64
+	open   int64
+	peak   int64
+	ipv4   int64
+	ipv6   int64
+}
+
+// track returns conn, accounted as open until it is closed.
+func (s *connStats) track(conn net.Conn) net.Conn {
+	if s == nil {
+		return conn
+	}
+	var ip net.IP
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		ip = addr.IP
+	}
+	s.mu.Lock()
+	switch {
+	case ip == nil:
+	case ip.To4() != nil:
+		s.ipv4++
+	default:
+		s.ipv6++
+	}
+	s.dialed++
+	s.open++
+	if s.open > s.peak {
+		s.peak = s.open
+	}
+	s.mu.Unlock()
+	return &trackedConn{Conn: conn, stats: s}
+}
+
+// start begins the statistics of a run, returning the counts of the
+// connections dialed so far.
+func (s *connStats) start() Connections {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peak = s.open
+	return Connections{Dialed: s.dialed, IPv4: s.ipv4, IPv6: s.ipv6}
+}
+
+// since returns the counts of the connections dialed since the start of
+// the run, and the peak of open connections.
+func (s *connStats) since(start Connections) Connections {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Connections{
+		Dialed: s.dialed - start.Dialed,
+		IPv4:   s.ipv4 - start.IPv4,
+		IPv6:   s.ipv6 - start.IPv6,
+		Peak:   s.peak,
+	}
+}
+
+type trackedConn struct {
+	net.Conn
+	stats *connStats
+	once  sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() {
+		c.stats.mu.Lock()
+		c.stats.open--
+		c.stats.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,122 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestConnections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/close" {
+			w.Header().Set("Connection", "close")
+		}
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:         req,
+		N:               20,
+		C:               2,
+		KeepConnections: true,
+		Renderer:        RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	c := boomer.Run().Connections
+	if c == nil || c.Dialed < 1 || c.Dialed > 2 || c.Peak > 2 || c.ReuseRate < 0.9 || c.DialsPerSec <= 0 {
+		t.Fatalf("Expected at most 2 connections reused for 20 requests, found %+v", c)
+	}
+	if c.IPv4 != c.Dialed || c.IPv6 != 0 {
+		t.Errorf("Expected IPv4 connections only, found %+v", c)
+	}
+	// The second run starts with the connections of the first one.
+	if c := boomer.Run().Connections; c.Dialed != 0 || c.Peak < 1 || c.ReuseRate != 1 {
+		t.Errorf("Expected no new connection, found %+v", c)
+	}
+
+	boomer.ResetConnections()
+	req.SetRequestURI(server.URL + "/close")
+	if c := boomer.Run().Connections; c.Dialed != 20 || c.ReuseRate != 0 {
+		t.Errorf("Expected a new connection per request, found %+v", c)
+	}
+
+	req.SetRequestURI(server.URL)
+	boomer.Client.DisableKeepAlive = true
+	if c := boomer.Run().Connections; c.Dialed != 20 || c.ReuseRate != 0 {
+		t.Errorf("Expected keep-alive to be disabled, found %+v", c)
+	}
+}
+
+func TestMaxConnsPerHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:  req,
+		N:        20,
+		C:        4,
+		Client:   ClientOptions{MaxConnsPerHost: 1},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Connections.Peak != 1 {
+		t.Errorf("Expected a single connection, found %+v", rep.Connections)
+	}
+	if rep.ErrorCategories[ErrorTooManyConnections] == 0 {
+		t.Errorf("Expected the workers to run out of connections, found %v", rep.ErrorCategories)
+	}
+}
+
+func TestIPVersion(t *testing.T) {
+	v4 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer v4.Close()
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(v4.URL)
+	boomer := &Boomer{
+		Request:  req,
+		N:        2,
+		C:        1,
+		Client:   ClientOptions{IPVersion: 6},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	if rep := boomer.Run(); rep.Count != 0 || rep.ErrorCategories[ErrorDNS] != 2 {
+		t.Errorf("Expected an IPv4 address not to be dialed, found %v", rep.ErrorDist)
+	}
+
+	l, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 is not available")
+	}
+	v6 := &httptest.Server{Listener: l, Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}}
+	v6.Start()
+	defer v6.Close()
+	req.SetRequestURI(v6.URL)
+	rep := boomer.Run()
+	if rep.Count != 2 || rep.Connections.IPv6 != 1 || rep.Connections.IPv4 != 0 {
+		t.Errorf("Expected an IPv6 connection, found %+v and %v", rep.Connections, rep.ErrorDist)
+	}
+}
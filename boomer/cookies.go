@@ -0,0 +1,156 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// cookieJar stores the cookies received by a single worker so that they
+// are replayed on the following requests of the worker to their domain
+// and path.
+type cookieJar struct {
+	cookies map[cookieKey]*jarCookie
+	// names are the names of all the cookies stored, removed from the
+	// requests they don't apply to anymore.
+	names map[string]bool
+}
+
+// cookieKey identifies a cookie of the jar, as a response setting a
+// cookie of the same name, domain and path replaces it.
+type cookieKey struct {
+	name, domain, path string
+}
+
+type jarCookie struct {
+	value string
+	// hostOnly cookies, set without a domain, are only sent to their
+	// domain and not to its subdomains.
+	hostOnly bool
+	secure   bool
+	// expire is zero for the cookies lasting as long as the session.
+	expire time.Time
+}
+
+func newCookieJar() *cookieJar {
+	return &cookieJar{cookies: make(map[cookieKey]*jarCookie), names: make(map[string]bool)}
+}
+
+// update stores the Set-Cookie headers of resp, the response to req.
+// Cookies expired, e.g. by a Max-Age of zero, are removed from the jar,
+// and those set for another domain than the one of req are ignored.
+func (j *cookieJar) update(req *fasthttp.Request, resp *fasthttp.Response) {
+	var values []string
+	resp.Header.VisitAllCookie(func(_, value []byte) {
+		values = append(values, string(value))
+	})
+	if len(values) == 0 {
+		return
+	}
+	now := time.Now()
+	host := cookieHost(req.URI())
+	for _, c := range (&http.Response{Header: http.Header{"Set-Cookie": values}}).Cookies() {
+		domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+		hostOnly := domain == ""
+		if hostOnly {
+			domain = host
+		} else if !domainMatch(host, domain) {
+			continue
+		}
+		path := c.Path
+		if !strings.HasPrefix(path, "/") {
+			path = defaultCookiePath(string(req.URI().Path()))
+		}
+		key := cookieKey{c.Name, domain, path}
+		// net/http reads a Max-Age of zero or less as a negative MaxAge.
+		if c.MaxAge < 0 || (c.MaxAge == 0 && !c.Expires.IsZero() && !c.Expires.After(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+		jc := &jarCookie{value: c.Value, hostOnly: hostOnly, secure: c.Secure, expire: c.Expires}
+		if c.MaxAge > 0 {
+			jc.expire = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		j.cookies[key] = jc
+		j.names[c.Name] = true
+	}
+}
+
+// apply sets the cookies of the jar matching the domain and path of req
+// on req, that of the longest path among those of the same name, and
+// removes the others.
+func (j *cookieJar) apply(req *fasthttp.Request) {
+	for name := range j.names {
+		req.Header.DelCookie(name)
+	}
+	now := time.Now()
+	uri := req.URI()
+	host, path, https := cookieHost(uri), string(uri.Path()), string(uri.Scheme()) == "https"
+	paths := make(map[string]string)
+	for key, c := range j.cookies {
+		if !c.expire.IsZero() && !c.expire.After(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		if (c.hostOnly && host != key.domain) || !domainMatch(host, key.domain) || !pathMatch(path, key.path) || (c.secure && !https) {
+			continue
+		}
+		if p, ok := paths[key.name]; ok && len(p) >= len(key.path) {
+			continue
+		}
+		paths[key.name] = key.path
+		req.Header.SetCookie(key.name, c.value)
+	}
+}
+
+// cookieHost returns the host of uri, without its port, as cookies are
+// shared across the ports of a host.
+func cookieHost(uri *fasthttp.URI) string {
+	host := string(uri.Host())
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// domainMatch reports whether the cookies of domain are sent to host, its
+// subdomains included unless host is an IP address.
+func domainMatch(host, domain string) bool {
+	return host == domain || (strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil)
+}
+
+// pathMatch reports whether the cookies of cookiePath are sent to path,
+// its subpaths included.
+func pathMatch(path, cookiePath string) bool {
+	if !strings.HasPrefix(path, cookiePath) {
+		return false
+	}
+	return len(path) == len(cookiePath) || strings.HasSuffix(cookiePath, "/") || path[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath returns the path of the cookies set without one by
+// the response to path: its directory.
+func defaultCookiePath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
@@ -0,0 +1,71 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestCookieJarScope(t *testing.T) {
+	jar := newCookieJar()
+	set := func(uri string, cookies ...string) {
+		req, resp := &fasthttp.Request{}, &fasthttp.Response{}
+		req.SetRequestURI(uri)
+		for _, c := range cookies {
+			resp.Header.Add("Set-Cookie", c)
+		}
+		jar.update(req, resp)
+	}
+	sent := func(uri, name string) string {
+		req := &fasthttp.Request{}
+		req.SetRequestURI(uri)
+		req.Header.SetCookie(name, "template")
+		jar.apply(req)
+		return string(req.Header.Cookie(name))
+	}
+
+	set("http://api.example.com/v1/login",
+		"session=abc",
+		"shared=1; Domain=example.com",
+		"admin=yes; Path=/admin",
+		"other=no; Domain=other.com")
+	for _, c := range []struct{ uri, name, want string }{
+		{"http://api.example.com:8080/v1/items", "session", "abc"},
+		{"http://api.example.com/v2", "session", ""},
+		{"http://www.example.com/v1/items", "session", ""},
+		{"http://www.example.com/v1", "shared", "1"},
+		{"http://api.example.com/admin/users", "admin", "yes"},
+		{"http://api.example.com/administrators", "admin", ""},
+		{"http://other.com/", "other", "template"},
+	} {
+		if got := sent(c.uri, c.name); got != c.want {
+			t.Errorf("Expected %s of %s to be %q, found %q", c.name, c.uri, c.want, got)
+		}
+	}
+
+	set("http://api.example.com/v1/login", "session=; Max-Age=0", "shared=2; Domain=example.com; Max-Age=-1")
+	if got := sent("http://api.example.com/v1/items", "session"); got != "" {
+		t.Errorf("Expected a Max-Age of zero to delete the cookie, found %q", got)
+	}
+	if got := sent("http://api.example.com/v1", "shared"); got != "" {
+		t.Errorf("Expected a negative Max-Age to delete the cookie, found %q", got)
+	}
+	set("https://api.example.com/", "token=t; Secure; Max-Age=3600")
+	if sent("http://api.example.com/", "token") != "" || sent("https://api.example.com/", "token") != "t" {
+		t.Errorf("Expected a secure cookie to only be sent over https")
+	}
+}
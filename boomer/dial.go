@@ -0,0 +1,245 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// dial opens the connections of the client, applying the address
+// overrides of the boomer, and accounts them in the connection
+// statistics.
+func (b *Boomer) dial(addr string) (net.Conn, error) {
+	conn, err := b.dialAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return b.conns.track(b.throttle(conn)), nil
+}
+
+func (b *Boomer) dialAddr(addr string) (net.Conn, error) {
+	if b.UnixSocket != "" {
+		return net.DialTimeout("unix", b.UnixSocket, b.Client.DialTimeout)
+	}
+	return b.dialHost(addr)
+}
+
+// dialHost opens a connection to addr through the address overrides and
+// the proxy of the boomer.
+func (b *Boomer) dialHost(addr string) (net.Conn, error) {
+	if override, ok := b.Resolve[addr]; ok {
+		addr = override
+	}
+	if b.ProxyAddr != nil {
+		return b.dialProxy(addr)
+	}
+	return b.dialTCP(addr)
+}
+
+// dialTCP opens a TCP connection to addr, within Client.DialTimeout if
+// set.
+func (b *Boomer) dialTCP(addr string) (net.Conn, error) {
+	dialers := b.dialers
+	if dialers == nil {
+		dialers = b.newDialers()
+	}
+	d := dialers[0]
+	if len(dialers) > 1 {
+		d = dialers[atomic.AddUint32(&b.nextDialer, 1)%uint32(len(dialers))]
+	}
+	// The IPv6 addresses, e.g. pinned by Resolve, are only dialed as dual
+	// stack.
+	if b.Client.IPVersion == 6 || ipv6Literal(addr) {
+		if b.Client.DialTimeout > 0 {
+			return d.DialDualStackTimeout(addr, b.Client.DialTimeout)
+		}
+		return d.DialDualStack(addr)
+	}
+	if b.Client.DialTimeout > 0 {
+		return d.DialTimeout(addr, b.Client.DialTimeout)
+	}
+	return d.Dial(addr)
+}
+
+// ipv6Literal reports whether the host of addr, as "host:port", is an
+// IPv6 address.
+func ipv6Literal(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.To4() == nil
+}
+
+// newDialers returns the dialers of a run, one per local address. They
+// cache the addresses of the hosts as configured by the client options.
+func (b *Boomer) newDialers() []*fasthttp.TCPDialer {
+	var resolver fasthttp.Resolver
+	if b.Client.Resolver != nil {
+		resolver = b.Client.Resolver
+	}
+	if b.Client.IPVersion == 6 {
+		// IPv6 is dialed as dual stack, with the IPv4 addresses left out.
+		r := b.Client.Resolver
+		if r == nil {
+			r = net.DefaultResolver
+		}
+		resolver = ipv6Resolver{r}
+	}
+	locals := b.Client.LocalAddrs
+	if len(locals) == 0 {
+		locals = []net.IP{nil}
+	}
+	dialers := make([]*fasthttp.TCPDialer, len(locals))
+	for i, ip := range locals {
+		// The concurrency of the default fasthttp dialer.
+		dialers[i] = &fasthttp.TCPDialer{
+			Concurrency:      1000,
+			DNSCacheDuration: b.Client.DNSCacheDuration,
+			Resolver:         resolver,
+		}
+		if ip != nil {
+			dialers[i].LocalAddr = &net.TCPAddr{IP: ip}
+		}
+	}
+	return dialers
+}
+
+// ipv6Resolver only returns the IPv6 addresses of the hosts.
+type ipv6Resolver struct {
+	*net.Resolver
+}
+
+func (r ipv6Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := r.Resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var v6 []net.IPAddr
+	for _, a := range addrs {
+		if a.IP.To4() == nil {
+			v6 = append(v6, a)
+		}
+	}
+	if len(v6) == 0 {
+		return nil, &net.DNSError{Err: "no IPv6 address", Name: host}
+	}
+	return v6, nil
+}
+
+// DNSResolver returns a resolver querying the DNS server at addr, as
+// "ip" or "ip:port", instead of the system's.
+func DNSResolver(addr string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+	var d net.Dialer
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// ResolveHosts concurrently resolves the given "host:port" addresses and
+// returns a map suitable for Boomer.Resolve, pinning every address to the
+// first IP found. Addresses with an IP as host are skipped.
+func ResolveHosts(addrs []string) (map[string]string, error) {
+	return ResolveHostsWith(net.DefaultResolver, addrs)
+}
+
+// ResolveHostsWith is like ResolveHosts, resolving the addresses with r.
+func ResolveHostsWith(r *net.Resolver, addrs []string) (map[string]string, error) {
+	return ResolveHostsVersion(r, addrs, 0)
+}
+
+// ResolveHostsVersion is like ResolveHostsWith, pinning the addresses to
+// an IP of the version given as in ClientOptions.IPVersion: 4 or 6, or
+// any if 0, an IPv4 address being preferred.
+func ResolveHostsVersion(r *net.Resolver, addrs []string, version int) (map[string]string, error) {
+	network := "ip"
+	switch version {
+	case 4:
+		network = "ip4"
+	case 6:
+		network = "ip6"
+	}
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	resolved := make(map[string]string, len(addrs))
+	for _, addr := range addrs {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(addr, host, port string) {
+			defer wg.Done()
+			ips, err := r.LookupIP(context.Background(), network, host)
+			if err == nil && len(ips) == 0 {
+				err = fmt.Errorf("no address found for %s", host)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			ip := ips[0]
+			for _, v4 := range ips {
+				if v4.To4() != nil {
+					ip = v4
+					break
+				}
+			}
+			resolved[addr] = net.JoinHostPort(ip.String(), port)
+		}(addr, host, port)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return resolved, nil
+}
+
+// RequestAddr returns the "host:port" address req is sent to.
+func RequestAddr(req *fasthttp.Request) string {
+	uri := req.URI()
+	host := string(uri.Host())
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	port := "80"
+	if string(uri.Scheme()) == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,198 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestResolveHosts(t *testing.T) {
+	resolved, err := ResolveHosts([]string{"localhost:8080", "127.0.0.1:80"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 1 || resolved["localhost:8080"] == "" {
+		t.Errorf("Expected only localhost to be resolved, found %v", resolved)
+	}
+	if _, err := ResolveHosts([]string{"nonexistent.invalid:80"}); err == nil {
+		t.Errorf("Expected an unresolvable host to fail")
+	}
+}
+
+func TestResolveHostsVersion(t *testing.T) {
+	resolved, err := ResolveHostsVersion(net.DefaultResolver, []string{"localhost:80"}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := net.ParseIP(strings.Trim(strings.TrimSuffix(resolved["localhost:80"], ":80"), "[]")); ip == nil || ip.To4() == nil {
+		t.Errorf("Expected localhost to be pinned to an IPv4 address, found %v", resolved)
+	}
+}
+
+func TestDialPinnedIPv6(t *testing.T) {
+	l, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not supported: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://pla.test:" + port)
+	boomer := &Boomer{
+		Request:  req,
+		N:        2,
+		C:        1,
+		Resolve:  map[string]string{"pla.test:" + port: l.Addr().String()},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	if rep := boomer.Run(); rep.Count != 2 {
+		t.Errorf("Expected the pinned IPv6 address to be dialed, found errors %v", rep.ErrorDist)
+	}
+}
+
+func TestRequestAddr(t *testing.T) {
+	cases := map[string]string{
+		"http://example.com/a":      "example.com:80",
+		"https://example.com/a":     "example.com:443",
+		"http://example.com:8080/a": "example.com:8080",
+		"https://[::1]:8443/":       "[::1]:8443",
+	}
+	for uri, want := range cases {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(uri)
+		if got := RequestAddr(req); got != want {
+			t.Errorf("RequestAddr(%s) = %s, want %s", uri, got, want)
+		}
+		fasthttp.ReleaseRequest(req)
+	}
+}
+
+func TestDNSResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	dns, queries := fakeDNS(t, net.IPv4(127, 0, 0, 1))
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://pla.test:" + port)
+	boomer := &Boomer{
+		Request: req,
+		N:       4,
+		C:       1,
+		Client: ClientOptions{
+			Resolver:         DNSResolver(dns),
+			DNSCacheDuration: time.Nanosecond,
+			DisableKeepAlive: true,
+		},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	if rep := boomer.Run(); rep.Count != 4 {
+		t.Fatalf("Expected the host to be resolved by the server, found errors %v", rep.ErrorDist)
+	}
+	// Every new connection resolves the host again.
+	if n := atomic.LoadInt32(queries); n < 4 {
+		t.Errorf("Expected the host to be resolved for every connection, found %d queries", n)
+	}
+
+	resolved, err := ResolveHostsWith(DNSResolver(dns), []string{"pla.test:80"})
+	if err != nil || resolved["pla.test:80"] != "127.0.0.1:80" {
+		t.Errorf("Expected pla.test to be pinned to 127.0.0.1, found %v and %v", resolved, err)
+	}
+}
+
+// fakeDNS serves ip as the A record of any name. It returns its address
+// and the number of A queries it answered.
+func fakeDNS(t *testing.T, ip net.IP) (string, *int32) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	var queries int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			// The question follows the 12 bytes header: a name, a type and
+			// a class.
+			end := 12
+			for end < n && buf[end] != 0 {
+				end += int(buf[end]) + 1
+			}
+			end += 5
+			if end > n {
+				continue
+			}
+			isA := buf[end-4] == 0 && buf[end-3] == 1
+			resp := append([]byte{buf[0], buf[1], 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0}, buf[12:end]...)
+			if isA {
+				atomic.AddInt32(&queries, 1)
+				resp[7] = 1
+				resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 0, 0, 4)
+				resp = append(resp, ip.To4()...)
+			}
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String(), &queries
+}
+
+func TestLocalAddrs(t *testing.T) {
+	var mu sync.Mutex
+	sources := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		mu.Lock()
+		sources[host]++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request: req,
+		N:       4,
+		C:       1,
+		Client: ClientOptions{
+			LocalAddrs:       []net.IP{net.IPv4(127, 0, 0, 1), net.IPv4(127, 0, 0, 2)},
+			DisableKeepAlive: true,
+		},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	if rep := boomer.Run(); rep.Count != 4 {
+		t.Skipf("127.0.0.2 is not available: %v", rep.ErrorDist)
+	}
+	if sources["127.0.0.1"] != 2 || sources["127.0.0.2"] != 2 {
+		t.Errorf("Expected the connections to alternate source addresses, found %v", sources)
+	}
+}
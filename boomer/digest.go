@@ -0,0 +1,184 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DigestAuth holds the credentials for HTTP Digest authentication
+// (RFC 7616). The MD5 and SHA-256 algorithms and their session variants
+// are supported, with or without the "auth" quality of protection.
+type DigestAuth struct {
+	Username string
+	Password string
+}
+
+// digestState tracks the server nonce of a worker so that challenges are
+// only answered once per nonce rather than once per request.
+type digestState struct {
+	auth *DigestAuth
+
+	realm     string
+	nonce     string
+	opaque    string
+	qop       string
+	algorithm string
+	newHash   func() hash.Hash
+	cnonce    string
+	nc        uint32
+}
+
+// challenge reads the Digest challenge of a 401 response and returns
+// whether it can be answered. A challenge for the current nonce which is
+// not marked as stale means the credentials were refused.
+func (d *digestState) challenge(resp *fasthttp.Response) bool {
+	var best map[string]string
+	resp.Header.VisitAll(func(k, v []byte) {
+		if !strings.EqualFold(string(k), "WWW-Authenticate") {
+			return
+		}
+		value := string(v)
+		if len(value) < 7 || !strings.EqualFold(value[:7], "Digest ") {
+			return
+		}
+		params := parseAuthParams(value[7:])
+		if best == nil || strings.HasPrefix(strings.ToUpper(params["algorithm"]), "SHA-256") {
+			best = params
+		}
+	})
+	if best == nil || best["nonce"] == "" {
+		return false
+	}
+	if best["nonce"] == d.nonce && !strings.EqualFold(best["stale"], "true") {
+		return false
+	}
+	d.algorithm = best["algorithm"]
+	switch strings.TrimSuffix(strings.ToUpper(d.algorithm), "-SESS") {
+	case "", "MD5":
+		d.newHash = md5.New
+	case "SHA-256":
+		d.newHash = sha256.New
+	default:
+		return false
+	}
+	d.qop = ""
+	for _, q := range strings.Split(best["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			d.qop = "auth"
+		}
+	}
+	d.realm = best["realm"]
+	d.nonce = best["nonce"]
+	d.opaque = best["opaque"]
+	d.nc = 0
+	d.cnonce = randomHex(16)
+	return true
+}
+
+// authorize sets the Authorization header of req, if a nonce is known.
+func (d *digestState) authorize(req *fasthttp.Request) {
+	if d.nonce == "" {
+		return
+	}
+	d.nc++
+	nc := fmt.Sprintf("%08x", d.nc)
+	uri := string(req.URI().RequestURI())
+	ha1 := d.h(d.auth.Username + ":" + d.realm + ":" + d.auth.Password)
+	if strings.HasSuffix(strings.ToUpper(d.algorithm), "-SESS") {
+		ha1 = d.h(ha1 + ":" + d.nonce + ":" + d.cnonce)
+	}
+	ha2 := d.h(string(req.Header.Method()) + ":" + uri)
+	var response string
+	if d.qop != "" {
+		response = d.h(ha1 + ":" + d.nonce + ":" + nc + ":" + d.cnonce + ":" + d.qop + ":" + ha2)
+	} else {
+		response = d.h(ha1 + ":" + d.nonce + ":" + ha2)
+	}
+
+	v := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+		d.auth.Username, d.realm, d.nonce, uri, response)
+	if d.algorithm != "" {
+		v += ", algorithm=" + d.algorithm
+	}
+	if d.opaque != "" {
+		v += fmt.Sprintf(`, opaque="%s"`, d.opaque)
+	}
+	if d.qop != "" {
+		v += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, d.qop, nc, d.cnonce)
+	}
+	req.Header.Set("Authorization", v)
+}
+
+func (d *digestState) h(s string) string {
+	h := d.newHash()
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseAuthParams parses the comma separated key=value parameters of an
+// authentication challenge. Values may be quoted.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " ")
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				value = strings.Replace(s[1:], `\`, "", -1)
+				s = ""
+			} else {
+				value = strings.Replace(s[1:end], `\`, "", -1)
+				s = s[end+1:]
+			}
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = value
+	}
+	return params
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,98 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseAuthParams(t *testing.T) {
+	params := parseAuthParams(`realm="test@example.com", qop="auth,auth-int", algorithm=MD5, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", stale=FALSE`)
+	want := map[string]string{
+		"realm":     "test@example.com",
+		"qop":       "auth,auth-int",
+		"algorithm": "MD5",
+		"nonce":     "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+		"stale":     "FALSE",
+	}
+	for k, v := range want {
+		if params[k] != v {
+			t.Errorf("Parameter %s is %q, want %q", k, params[k], v)
+		}
+	}
+}
+
+func TestDigest(t *testing.T) {
+	var challenges, authorized int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(auth, "Digest ") {
+			p := parseAuthParams(auth[7:])
+			ha1 := md5Hex("user:realm:secret")
+			ha2 := md5Hex(r.Method + ":" + p["uri"])
+			want := md5Hex(ha1 + ":nonce:" + p["nc"] + ":" + p["cnonce"] + ":auth:" + ha2)
+			if p["response"] == want && p["opaque"] == "opaque" {
+				atomic.AddInt64(&authorized, 1)
+				return
+			}
+		}
+		atomic.AddInt64(&challenges, 1)
+		w.Header().Set("WWW-Authenticate", `Digest realm="realm", nonce="nonce", opaque="opaque", qop="auth", algorithm=MD5`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL + "/path?q=1")
+	boomer := &Boomer{
+		Request:  req,
+		N:        10,
+		C:        2,
+		Digest:   &DigestAuth{Username: "user", Password: "secret"},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.StatusCodeDist[http.StatusOK] != 10 || authorized != 10 {
+		t.Errorf("Expected every request to authenticate, found %v", rep.StatusCodeDist)
+	}
+	if challenges != 2 {
+		t.Errorf("Expected a single challenge per worker, found %v", challenges)
+	}
+
+	authorized, challenges = 0, 0
+	boomer.Digest.Password = "wrong"
+	rep = boomer.Run()
+	if rep.StatusCodeDist[http.StatusUnauthorized] != 10 {
+		t.Errorf("Expected wrong credentials to be refused, found %v", rep.StatusCodeDist)
+	}
+	if challenges > 20 {
+		t.Errorf("Expected refused credentials not to loop, found %v challenges", challenges)
+	}
+}
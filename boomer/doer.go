@@ -0,0 +1,90 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Doer makes HTTP requests. *fasthttp.Client is the default one.
+type Doer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+	DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error
+}
+
+// NetHTTPDoer is a Doer sending the requests with a net/http client,
+// e.g. to use HTTP/2. The requests and responses are converted, which
+// adds some overhead to the measurements.
+type NetHTTPDoer struct {
+	// Client is the client to use, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// Do implements Doer.
+func (d NetHTTPDoer) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return d.do(context.Background(), req, resp)
+}
+
+// DoTimeout implements Doer.
+func (d NetHTTPDoer) DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.do(ctx, req, resp)
+}
+
+func (d NetHTTPDoer) do(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response) error {
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	hreq, err := http.NewRequest(string(req.Header.Method()), req.URI().String(), bytes.NewReader(req.Body()))
+	if err != nil {
+		return err
+	}
+	hreq = hreq.WithContext(ctx)
+	req.Header.VisitAll(func(k, v []byte) {
+		switch string(k) {
+		case "Host":
+			hreq.Host = string(v)
+		case "Content-Length":
+		default:
+			hreq.Header.Add(string(k), string(v))
+		}
+	})
+	hresp, err := client.Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer hresp.Body.Close()
+	body, err := ioutil.ReadAll(hresp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Reset()
+	resp.SetStatusCode(hresp.StatusCode)
+	for k, vs := range hresp.Header {
+		for _, v := range vs {
+			resp.Header.Add(k, v)
+		}
+	}
+	resp.SetBody(body)
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+type mockDoer struct {
+	calls int64
+}
+
+func (d *mockDoer) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	if atomic.AddInt64(&d.calls, 1)%5 == 0 {
+		return errors.New("mock error")
+	}
+	resp.SetStatusCode(http.StatusTeapot)
+	return nil
+}
+
+func (d *mockDoer) DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error {
+	return d.Do(req, resp)
+}
+
+func TestDoer(t *testing.T) {
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://example.invalid/")
+	doer := &mockDoer{}
+	boomer := &Boomer{
+		Request:  req,
+		N:        20,
+		C:        2,
+		Doer:     doer,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if doer.calls != 20 || rep.StatusCodeDist[http.StatusTeapot] != 16 || rep.ErrorDist["mock error"] != 4 {
+		t.Errorf("Expected the requests to go through the doer, found %v and %v", rep.StatusCodeDist, rep.ErrorDist)
+	}
+}
+
+func TestNetHTTPDoer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Host", r.Host)
+		w.Header().Set("X-Method", r.Method)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL + "/path")
+	req.Header.SetMethod("POST")
+	req.Header.SetHost("example.com")
+	req.SetBodyString("hello")
+	resp := fasthttp.AcquireResponse()
+	if err := (NetHTTPDoer{}).DoTimeout(req, resp, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusCreated || string(resp.Body()) != "hello" {
+		t.Errorf("Unexpected response %d %q", resp.StatusCode(), resp.Body())
+	}
+	if h := string(resp.Header.Peek("X-Host")); h != "example.com" {
+		t.Errorf("Expected the Host header to be kept, found %q", h)
+	}
+	if m := string(resp.Header.Peek("X-Method")); m != "POST" {
+		t.Errorf("Expected a POST request, found %q", m)
+	}
+}
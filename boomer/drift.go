@@ -0,0 +1,171 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// driftThreshold is the relative latency increase over the whole run,
+// or the absolute error rate increase, above which a run is considered
+// to be degrading.
+const driftThreshold = 0.1
+
+// Drift describes how the target behaved over the course of a run.
+type Drift struct {
+	// LatencySlope is the change of the average latency per hour.
+	LatencySlope time.Duration
+
+	// ErrorRateSlope is the change of the error ratio per hour.
+	ErrorRateSlope float64
+
+	// Degrading is the verdict of the regression: whether latency or
+	// errors grew significantly from the start to the end of the run.
+	Degrading bool
+
+	// HeapSlope is the change of the heap of the load generator, in
+	// bytes per hour, and GoroutineSlope that of its goroutines.
+	HeapSlope      float64
+	GoroutineSlope float64
+
+	// ClientGrowing tells whether the heap or the goroutines of the load
+	// generator grew significantly over the run, in which case it may
+	// be the cause of the degradation rather than the target.
+	ClientGrowing bool
+
+	// Identities are the values sampled from the identity header, in
+	// order of appearance. A new identity usually means the target
+	// process was restarted or replaced.
+	Identities []Identity
+}
+
+// Identity is a value of the identity header seen during the run.
+type Identity struct {
+	Value     string
+	FirstSeen time.Duration
+	LastSeen  time.Duration
+	Samples   int
+}
+
+// computeDrift fits a linear regression on the per-second latency and
+// error ratio of the time series, and on the client stats.
+func computeDrift(series []TimeSeriesPoint, identities map[string]*Identity) *Drift {
+	var xs, lats, xerrs, errs, xclients, heaps, goroutines []float64
+	var latTotal float64
+	for _, p := range series {
+		if p.Client.Goroutines > 0 {
+			xclients = append(xclients, p.Offset.Hours())
+			heaps = append(heaps, float64(p.Client.HeapBytes))
+			goroutines = append(goroutines, float64(p.Client.Goroutines))
+		}
+		total := p.Count + p.Errors
+		if total == 0 {
+			continue
+		}
+		x := p.Offset.Hours()
+		xerrs = append(xerrs, x)
+		errs = append(errs, float64(p.Errors)/float64(total))
+		if p.Count > 0 {
+			xs = append(xs, x)
+			lats = append(lats, p.Average.Seconds())
+			latTotal += p.Average.Seconds()
+		}
+	}
+	d := &Drift{}
+	latSlope := slope(xs, lats)
+	errSlope := slope(xerrs, errs)
+	d.LatencySlope = secondsToDuration(latSlope)
+	d.ErrorRateSlope = errSlope
+	if len(xs) > 1 {
+		span := xs[len(xs)-1] - xs[0]
+		mean := latTotal / float64(len(xs))
+		if mean > 0 && latSlope*span/mean > driftThreshold {
+			d.Degrading = true
+		}
+	}
+	if len(xerrs) > 1 && errSlope*(xerrs[len(xerrs)-1]-xerrs[0]) > driftThreshold {
+		d.Degrading = true
+	}
+	d.HeapSlope = slope(xclients, heaps)
+	d.GoroutineSlope = slope(xclients, goroutines)
+	if len(xclients) > 1 {
+		span := xclients[len(xclients)-1] - xclients[0]
+		d.ClientGrowing = d.HeapSlope*span > driftThreshold*average(heaps) || d.GoroutineSlope*span > driftThreshold*average(goroutines)
+	}
+	for _, id := range identities {
+		d.Identities = append(d.Identities, *id)
+	}
+	sort.Slice(d.Identities, func(i, j int) bool {
+		return d.Identities[i].FirstSeen < d.Identities[j].FirstSeen
+	})
+	return d
+}
+
+// average returns the mean of xs.
+func average(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// slope returns the least squares slope of ys over xs.
+func slope(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	if n < 2 {
+		return 0
+	}
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / den
+}
+
+func printDrift(w io.Writer, d *Drift) {
+	fmt.Fprintf(w, "\nDrift:\n")
+	fmt.Fprintf(w, "  Latency slope:\t%s per hour.\n", formatSeconds(d.LatencySlope.Seconds()))
+	fmt.Fprintf(w, "  Error rate slope:\t%+.4f%% per hour.\n", d.ErrorRateSlope*100)
+	verdict := "stable"
+	if d.Degrading {
+		verdict = "degrading"
+	}
+	fmt.Fprintf(w, "  Verdict:\t%s\n", verdict)
+	if d.HeapSlope != 0 || d.GoroutineSlope != 0 {
+		fmt.Fprintf(w, "  Client heap slope:\t%+.2f MB per hour.\n", d.HeapSlope/(1<<20))
+		fmt.Fprintf(w, "  Client goroutine slope:\t%+.1f per hour.\n", d.GoroutineSlope)
+		if d.ClientGrowing {
+			fmt.Fprintf(w, "  The load generator grew over the run, it may be the bottleneck.\n")
+		}
+	}
+	if len(d.Identities) > 0 {
+		fmt.Fprintf(w, "\nIdentities:\n")
+		for _, id := range d.Identities {
+			fmt.Fprintf(w, "  %s\tfrom %v to %v (%d samples)\n", id.Value,
+				id.FirstSeen.Truncate(time.Second), id.LastSeen.Truncate(time.Second), id.Samples)
+		}
+	}
+}
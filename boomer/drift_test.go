@@ -0,0 +1,92 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestSlope(t *testing.T) {
+	xs := []float64{0, 1, 2, 3}
+	ys := []float64{1, 3, 5, 7}
+	if s := slope(xs, ys); s != 2 {
+		t.Errorf("Expected a slope of 2, found %v", s)
+	}
+	if s := slope(xs[:1], ys[:1]); s != 0 {
+		t.Errorf("Expected a slope of 0 for a single point, found %v", s)
+	}
+}
+
+func TestComputeDrift(t *testing.T) {
+	var stable, degrading []TimeSeriesPoint
+	for i := 0; i < 60; i++ {
+		offset := time.Duration(i) * time.Minute
+		stable = append(stable, TimeSeriesPoint{
+			Offset:  offset,
+			Count:   100,
+			Average: 10 * time.Millisecond,
+		})
+		degrading = append(degrading, TimeSeriesPoint{
+			Offset:  offset,
+			Count:   100,
+			Average: 10*time.Millisecond + time.Duration(i)*100*time.Microsecond,
+		})
+	}
+	if d := computeDrift(stable, nil); d.Degrading || d.LatencySlope != 0 {
+		t.Errorf("Expected a stable run, found %+v", d)
+	}
+	d := computeDrift(degrading, nil)
+	if !d.Degrading {
+		t.Errorf("Expected a degrading run, found %+v", d)
+	}
+	if d.LatencySlope < 5*time.Millisecond || d.LatencySlope > 7*time.Millisecond {
+		t.Errorf("Expected a latency slope of about 6ms per hour, found %v", d.LatencySlope)
+	}
+	if d.ClientGrowing || d.HeapSlope != 0 {
+		t.Errorf("Expected no client growth without client stats, found %+v", d)
+	}
+
+	for i := range degrading {
+		degrading[i].Client = ClientStats{HeapBytes: 1 << 20, Goroutines: 100 + uint64(i)}
+	}
+	d = computeDrift(degrading, nil)
+	if !d.ClientGrowing || d.HeapSlope != 0 || d.GoroutineSlope < 59 || d.GoroutineSlope > 61 {
+		t.Errorf("Expected the goroutines of the client to grow by 60 per hour, found %+v", d)
+	}
+}
+
+func TestDriftClientStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:  req,
+		N:        10,
+		C:        1,
+		Drift:    true,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if len(rep.TimeSeries) == 0 || rep.TimeSeries[0].Client.Goroutines == 0 || rep.TimeSeries[0].Client.HeapBytes == 0 {
+		t.Errorf("Expected the client stats to be sampled in the series, found %+v", rep.TimeSeries)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/valyala/fasthttp"
+)
+
+// The categories of the errors, see Report.ErrorCategories. Too many
+// connections means the load generator ran out of connections, file
+// descriptors or local ports.
+const (
+	ErrorConnectionRefused  = "connection refused"
+	ErrorConnectionReset    = "connection reset"
+	ErrorTimeout            = "timeout"
+	ErrorDNS                = "dns failure"
+	ErrorTLS                = "tls error"
+	ErrorTooManyConnections = "too many connections"
+	ErrorAssertion          = "assertion failed"
+	ErrorOther              = "other"
+)
+
+// errorCategory returns the category of err. The errors of the results of
+// other processes, e.g. agents, only keep their message, which is then
+// matched instead.
+func errorCategory(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var assertErr *AssertionError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certErr x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &assertErr):
+		return ErrorAssertion
+	case errors.Is(err, fasthttp.ErrNoFreeConns), errors.Is(err, syscall.EMFILE),
+		errors.Is(err, syscall.EADDRNOTAVAIL):
+		return ErrorTooManyConnections
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.Is(err, fasthttp.ErrTLSHandshakeTimeout),
+		errors.As(err, &recordErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &certErr):
+		return ErrorTLS
+	case errors.Is(err, fasthttp.ErrTimeout), errors.Is(err, fasthttp.ErrDialTimeout),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorConnectionRefused
+	case errors.Is(err, fasthttp.ErrConnectionClosed), errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE):
+		return ErrorConnectionReset
+	}
+	return errorMessageCategory(err.Error())
+}
+
+// errorMessages maps the messages of the errors to their category, the
+// most specific first.
+var errorMessages = []struct {
+	substr   string
+	category string
+}{
+	{"assertion failed", ErrorAssertion},
+	{fasthttp.ErrNoFreeConns.Error(), ErrorTooManyConnections},
+	{"too many open files", ErrorTooManyConnections},
+	{"cannot assign requested address", ErrorTooManyConnections},
+	{"no such host", ErrorDNS},
+	{"tls", ErrorTLS},
+	{"x509", ErrorTLS},
+	{"timeout", ErrorTimeout},
+	{"timed out", ErrorTimeout},
+	{"connection refused", ErrorConnectionRefused},
+	{"connection reset", ErrorConnectionReset},
+	{"broken pipe", ErrorConnectionReset},
+	{"server closed connection", ErrorConnectionReset},
+	{"EOF", ErrorConnectionReset},
+}
+
+func errorMessageCategory(msg string) string {
+	for _, m := range errorMessages {
+		if strings.Contains(msg, m.substr) {
+			return m.category
+		}
+	}
+	return ErrorOther
+}
+
+// generatorErrors are the categories pointing at the load generator
+// rather than the target.
+var generatorErrors = map[string]bool{
+	ErrorTooManyConnections: true,
+}
@@ -0,0 +1,78 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestErrorCategory(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{refused, ErrorConnectionRefused},
+		{fmt.Errorf("wrapped: %w", syscall.ECONNRESET), ErrorConnectionReset},
+		{fasthttp.ErrConnectionClosed, ErrorConnectionReset},
+		{fasthttp.ErrTimeout, ErrorTimeout},
+		{fasthttp.ErrDialTimeout, ErrorTimeout},
+		{&net.DNSError{Err: "no such host", Name: "nowhere.invalid"}, ErrorDNS},
+		{x509.UnknownAuthorityError{}, ErrorTLS},
+		{fasthttp.ErrTLSHandshakeTimeout, ErrorTLS},
+		{fasthttp.ErrNoFreeConns, ErrorTooManyConnections},
+		{&AssertionError{"status 500"}, ErrorAssertion},
+		// The errors of the agents only keep their message.
+		{errors.New(refused.Error()), ErrorConnectionRefused},
+		{errors.New("dial tcp: lookup nowhere.invalid: no such host"), ErrorDNS},
+		{errors.New(fasthttp.ErrNoFreeConns.Error()), ErrorTooManyConnections},
+		{errors.New("assertion failed: status 500"), ErrorAssertion},
+		{errors.New("something else"), ErrorOther},
+	}
+	for _, test := range tests {
+		if got := errorCategory(test.err); got != test.want {
+			t.Errorf("Expected %q to be a %s, found %s", test.err, test.want, got)
+		}
+	}
+}
+
+func TestErrorCategories(t *testing.T) {
+	server := httptest.NewServer(nil)
+	url := server.URL
+	server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(url)
+	boomer := &Boomer{
+		Request:  req,
+		N:        5,
+		C:        1,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.ErrorCategories[ErrorConnectionRefused] != 5 {
+		t.Errorf("Expected 5 refused connections, found %v", rep.ErrorCategories)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Continue describes the interim responses to the requests sent with
+// "Expect: 100-continue", when Boomer.ExpectContinue is set.
+type Continue struct {
+	// Continued is the number of bodies sent upon a "100 Continue"
+	// response. Latency is the average time until this response, from
+	// the sending of the headers, and Latencies its percentiles.
+	Continued int64
+	Latency   time.Duration
+	Latencies []LatencyDistribution
+
+	// Rejected is the number of requests answered with a final response
+	// instead, whose body was not sent.
+	Rejected int64
+
+	// TimedOut is the number of bodies sent without an interim response,
+	// after Boomer.ExpectContinueTimeout.
+	TimedOut int64
+}
+
+// expectKind is the outcome of an "Expect: 100-continue" request.
+type expectKind int
+
+const (
+	expectNone expectKind = iota
+	expectContinued
+	expectRejected
+	expectTimedOut
+)
+
+// expectOutcome is the outcome of an "Expect: 100-continue" request and
+// the latency of its interim response, or of the final response if
+// rejected.
+type expectOutcome struct {
+	kind    expectKind
+	latency time.Duration
+}
+
+var errExpectRejected = errors.New("expectation rejected")
+
+// expectTransport is a fasthttp transport sending the requests with a
+// body with "Expect: 100-continue": the headers are sent alone and the
+// body held until the server answers. The outcomes are kept per request
+// for the workers to report them.
+type expectTransport struct {
+	timeout time.Duration
+	wait    time.Duration
+
+	outcomes sync.Map
+}
+
+// outcome returns and forgets the outcome of the last sending of req.
+func (t *expectTransport) outcome(req *fasthttp.Request) expectOutcome {
+	if o, ok := t.outcomes.LoadAndDelete(req); ok {
+		return o.(expectOutcome)
+	}
+	return expectOutcome{}
+}
+
+// RoundTrip implements fasthttp.RoundTripper. It follows the default
+// transport, apart from the streaming of the responses.
+func (t *expectTransport) RoundTrip(hc *fasthttp.HostClient, req *fasthttp.Request, resp *fasthttp.Response) (bool, error) {
+	if len(req.Body()) == 0 && req.BodyStream() == nil {
+		return fasthttp.DefaultTransport.RoundTrip(hc, req, resp)
+	}
+	req.Header.Set("Expect", "100-continue")
+	// A consumed body stream cannot be sent again.
+	retry := req.BodyStream() == nil
+
+	var deadline time.Time
+	if t.timeout > 0 {
+		deadline = time.Now().Add(t.timeout)
+	}
+	cc, err := hc.AcquireConn(t.timeout, req.ConnectionClose())
+	if err != nil {
+		return false, err
+	}
+	conn := cc.Conn()
+	resp.ParseNetConn(conn)
+	if err := conn.SetDeadline(deadline); err != nil {
+		hc.CloseConn(cc)
+		return retry, err
+	}
+
+	br := hc.AcquireReader(conn)
+	defer hc.ReleaseReader(br)
+	var outcome expectOutcome
+	gate := &expectGate{w: conn, wait: func(sent time.Time) error {
+		var err error
+		outcome, err = t.await(conn, br, resp, sent, deadline)
+		return err
+	}}
+	bw := bufio.NewWriter(gate)
+	err = req.Write(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if errors.Is(err, errExpectRejected) {
+		// The final response was read up to its headers.
+		err = resp.ReadBody(br, hc.MaxResponseBodySize)
+		t.outcomes.Store(req, outcome)
+		hc.CloseConn(cc)
+		return false, timeoutError(err)
+	}
+	if err != nil {
+		hc.CloseConn(cc)
+		return retry, timeoutError(err)
+	}
+	t.outcomes.Store(req, outcome)
+
+	if err := resp.ReadLimitBody(br, hc.MaxResponseBodySize); err != nil {
+		hc.CloseConn(cc)
+		return retry && err != fasthttp.ErrBodyTooLarge, timeoutError(err)
+	}
+	if req.ConnectionClose() || resp.ConnectionClose() {
+		hc.CloseConn(cc)
+	} else {
+		hc.ReleaseConn(cc)
+	}
+	return false, nil
+}
+
+// await waits for the answer of the server to the headers sent at the
+// given time, for t.wait at most. A final response is read in resp, up to
+// its body, and errExpectRejected returned.
+func (t *expectTransport) await(conn net.Conn, br *bufio.Reader, resp *fasthttp.Response, sent, deadline time.Time) (expectOutcome, error) {
+	wait := t.wait
+	if wait == 0 {
+		wait = time.Second
+	}
+	waitDeadline := sent.Add(wait)
+	if !deadline.IsZero() && deadline.Before(waitDeadline) {
+		waitDeadline = deadline
+	}
+	if err := conn.SetReadDeadline(waitDeadline); err != nil {
+		return expectOutcome{}, err
+	}
+	_, err := br.Peek(1)
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return expectOutcome{}, err
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() && (deadline.IsZero() || waitDeadline.Before(deadline)) {
+		return expectOutcome{kind: expectTimedOut}, nil
+	}
+	if err != nil {
+		return expectOutcome{}, err
+	}
+	for {
+		if err := resp.Header.Read(br); err != nil {
+			return expectOutcome{}, err
+		}
+		latency := time.Since(sent)
+		switch code := resp.Header.StatusCode(); {
+		case code == fasthttp.StatusContinue:
+			return expectOutcome{expectContinued, latency}, nil
+		case code >= 200 || code == fasthttp.StatusSwitchingProtocols:
+			return expectOutcome{expectRejected, latency}, errExpectRejected
+		}
+		// Other informational responses are skipped.
+	}
+}
+
+// timeoutError returns fasthttp.ErrTimeout on any timeout, as the default
+// transport does.
+func timeoutError(err error) error {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return fasthttp.ErrTimeout
+	}
+	return err
+}
+
+// expectGate writes a request to w, waiting before the first byte of its
+// body, which follows the blank line ending the headers.
+type expectGate struct {
+	w    io.Writer
+	wait func(sent time.Time) error
+
+	tail []byte
+	body bool
+}
+
+var headersEnd = []byte("\r\n\r\n")
+
+func (g *expectGate) Write(p []byte) (int, error) {
+	if g.body {
+		return g.w.Write(p)
+	}
+	// The end of the headers may be split across writes.
+	buf := append(g.tail, p...)
+	i := bytes.Index(buf, headersEnd)
+	if i < 0 {
+		if len(buf) > len(headersEnd) {
+			buf = buf[len(buf)-len(headersEnd):]
+		}
+		g.tail = append(g.tail[:0], buf...)
+		return g.w.Write(p)
+	}
+	i += len(headersEnd) - len(g.tail)
+	n, err := g.w.Write(p[:i])
+	if err != nil {
+		return n, err
+	}
+	g.body = true
+	if err := g.wait(time.Now()); err != nil {
+		return n, err
+	}
+	m, err := g.w.Write(p[i:])
+	return n + m, err
+}
+
+// interims accumulates the outcomes of the "Expect: 100-continue"
+// requests.
+type interims struct {
+	out     Continue
+	latency time.Duration
+	lats    reservoir
+}
+
+func newInterims() *interims {
+	return &interims{}
+}
+
+func (in *interims) add(res *result) {
+	switch res.expect.kind {
+	case expectContinued:
+		in.out.Continued++
+		in.latency += res.expect.latency
+		in.lats.add(res.expect.latency)
+	case expectRejected:
+		in.out.Rejected++
+	case expectTimedOut:
+		in.out.TimedOut++
+	}
+}
+
+func (in *interims) build(pctls []int) *Continue {
+	out := in.out
+	if out.Continued > 0 {
+		out.Latency = in.latency / time.Duration(out.Continued)
+		out.Latencies = quantiles(&in.lats, pctls)
+	}
+	return &out
+}
+
+func printContinue(w io.Writer, c *Continue) {
+	fmt.Fprintf(w, "\nExpect 100-continue:\n")
+	fmt.Fprintf(w, "  Continued:\t%s", formatCount(float64(c.Continued)))
+	if c.Continued > 0 {
+		fmt.Fprintf(w, ", interim response in %s average", formatSeconds(c.Latency.Seconds()))
+		for _, l := range c.Latencies {
+			if l.Percentage == 50 || l.Percentage == 99 {
+				fmt.Fprintf(w, ", p%d %s", l.Percentage, formatSeconds(l.Latency.Seconds()))
+			}
+		}
+	}
+	fmt.Fprintln(w)
+	if c.Rejected > 0 {
+		fmt.Fprintf(w, "  Rejected before the body:\t%s\n", formatCount(float64(c.Rejected)))
+	}
+	if c.TimedOut > 0 {
+		fmt.Fprintf(w, "  Sent without interim response:\t%s\n", formatCount(float64(c.TimedOut)))
+	}
+}
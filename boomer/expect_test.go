@@ -0,0 +1,87 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestExpectContinue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Expect") != "100-continue" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/reject":
+			// The body is not read, no interim response is sent.
+			w.WriteHeader(http.StatusForbidden)
+			return
+		case "/slow":
+			time.Sleep(100 * time.Millisecond)
+		}
+		if n, _ := io.Copy(ioutil.Discard, r.Body); n != 10000 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	run := func(path string, body func() (io.Reader, error)) *Report {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(server.URL + path)
+		req.Header.SetMethod("PUT")
+		if body == nil {
+			req.SetBody(bytes.Repeat([]byte("a"), 10000))
+		}
+		boomer := &Boomer{
+			Request:               req,
+			N:                     6,
+			C:                     2,
+			RequestBody:           body,
+			ExpectContinue:        true,
+			ExpectContinueTimeout: 50 * time.Millisecond,
+			Renderer:              RendererFunc(func(io.Writer, *Report) error { return nil }),
+		}
+		return boomer.Run()
+	}
+
+	rep := run("/", nil)
+	if rep.StatusCodeDist[http.StatusOK] != 6 || rep.Continue == nil || rep.Continue.Continued != 6 || rep.Continue.Latency <= 0 {
+		t.Errorf("Expected 6 continued requests, found %v %v %+v", rep.StatusCodeDist, rep.ErrorDist, rep.Continue)
+	}
+	rep = run("/", func() (io.Reader, error) {
+		return strings.NewReader(strings.Repeat("a", 10000)), nil
+	})
+	if rep.StatusCodeDist[http.StatusOK] != 6 || rep.Continue.Continued != 6 {
+		t.Errorf("Expected 6 continued streamed requests, found %v %v %+v", rep.StatusCodeDist, rep.ErrorDist, rep.Continue)
+	}
+	rep = run("/reject", nil)
+	if rep.StatusCodeDist[http.StatusForbidden] != 6 || rep.Continue.Rejected != 6 {
+		t.Errorf("Expected 6 rejected requests, found %v %v %+v", rep.StatusCodeDist, rep.ErrorDist, rep.Continue)
+	}
+	rep = run("/slow", nil)
+	if rep.StatusCodeDist[http.StatusOK] != 6 || rep.Continue.TimedOut != 6 {
+		t.Errorf("Expected 6 bodies sent after the timeout, found %v %v %+v", rep.StatusCodeDist, rep.ErrorDist, rep.Continue)
+	}
+}
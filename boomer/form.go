@@ -0,0 +1,184 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// FormField is a field of a multipart/form-data body. If File is set, the
+// field is a file part with the content of the file at this path, named
+// after its base name.
+type FormField struct {
+	Name  string
+	Value string
+	File  string
+}
+
+// ParseFormField parses a field given as "name=value", or as
+// "name=@path" for a file part, as curl's -F does.
+func ParseFormField(s string) (FormField, error) {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		return FormField{}, fmt.Errorf("form field %q is not name=value or name=@file", s)
+	}
+	f := FormField{Name: s[:i]}
+	if v := s[i+1:]; strings.HasPrefix(v, "@") {
+		f.File = v[1:]
+		if f.File == "" {
+			return FormField{}, fmt.Errorf("form field %q has no file", s)
+		}
+	} else {
+		f.Value = v
+	}
+	return f, nil
+}
+
+// Form is a multipart/form-data body. It is built anew for every request,
+// with a random boundary, and the files are read again every time so that
+// changes to them are picked up.
+type Form struct {
+	Fields []FormField
+
+	// Stream sends the files as they are read from disk, within the
+	// measured time, instead of reading them before the request is sent.
+	// Large files are then not held in memory, but the body cannot be
+	// sent again, on a retry or after a digest challenge.
+	Stream bool
+}
+
+// Validate checks that the files of the form can be read.
+func (f *Form) Validate() error {
+	if len(f.Fields) == 0 {
+		return errors.New("form has no fields")
+	}
+	for _, field := range f.Fields {
+		if field.File == "" {
+			continue
+		}
+		fi, err := os.Stat(field.File)
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return fmt.Errorf("form file %s is not a regular file", field.File)
+		}
+	}
+	return nil
+}
+
+// apply sets the form as the body of req.
+func (f *Form) apply(req *fasthttp.Request) error {
+	// The writer picks a random boundary.
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	req.Header.SetContentType(w.FormDataContentType())
+	if !f.Stream {
+		for _, field := range f.Fields {
+			if field.File == "" {
+				w.WriteField(field.Name, field.Value)
+				continue
+			}
+			data, err := os.ReadFile(field.File)
+			if err != nil {
+				return err
+			}
+			part, _ := w.CreatePart(fileHeader(field))
+			part.Write(data)
+		}
+		w.Close()
+		req.SetBody(buf.Bytes())
+		return nil
+	}
+
+	// The files are read in between the rest of the parts, and their sizes
+	// make up the content length.
+	s := &formStream{}
+	var parts []io.Reader
+	size := 0
+	flush := func() {
+		parts = append(parts, bytes.NewReader(append([]byte(nil), buf.Bytes()...)))
+		size += buf.Len()
+		buf.Reset()
+	}
+	for _, field := range f.Fields {
+		if field.File == "" {
+			w.WriteField(field.Name, field.Value)
+			continue
+		}
+		w.CreatePart(fileHeader(field))
+		flush()
+		file, err := os.Open(field.File)
+		if err != nil {
+			s.Close()
+			return err
+		}
+		s.files = append(s.files, file)
+		fi, err := file.Stat()
+		if err != nil {
+			s.Close()
+			return err
+		}
+		parts = append(parts, io.LimitReader(file, fi.Size()))
+		size += int(fi.Size())
+	}
+	w.Close()
+	flush()
+	s.Reader = io.MultiReader(parts...)
+	req.SetBodyStream(s, size)
+	return nil
+}
+
+// formStream is the body of a streamed form. It is closed by fasthttp
+// once sent, or when the body of the request is replaced.
+type formStream struct {
+	io.Reader
+	files []*os.File
+}
+
+func (s *formStream) Close() error {
+	for _, f := range s.files {
+		f.Close()
+	}
+	return nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// fileHeader returns the header of the part of a file field, typed after
+// the extension of the file.
+func fileHeader(field FormField) textproto.MIMEHeader {
+	name := filepath.Base(field.File)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(field.Name), quoteEscaper.Replace(name)))
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Content-Type", contentType)
+	return h
+}
@@ -0,0 +1,116 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseFormField(t *testing.T) {
+	tests := []struct {
+		in   string
+		want FormField
+		err  bool
+	}{
+		{in: "name=value", want: FormField{Name: "name", Value: "value"}},
+		{in: "name=a=b", want: FormField{Name: "name", Value: "a=b"}},
+		{in: "file=@/tmp/a.png", want: FormField{Name: "file", File: "/tmp/a.png"}},
+		{in: "empty=", want: FormField{Name: "empty"}},
+		{in: "=value", err: true},
+		{in: "name", err: true},
+		{in: "file=@", err: true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFormField(tt.in)
+		if (err != nil) != tt.err || f != tt.want {
+			t.Errorf("Expected %q to parse as %+v (error %v), found %+v (%v)", tt.in, tt.want, tt.err, f, err)
+		}
+	}
+}
+
+func TestForm(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "image.png")
+	data := bytes.Repeat([]byte{0x89}, 100000)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	boundaries := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("upload")
+		if err != nil || r.FormValue("name") != "pla" || header.Filename != "image.png" || header.Header.Get("Content-Type") != "image/png" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		if b, _ := io.ReadAll(file); !bytes.Equal(b, data) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		boundaries[r.Header.Get("Content-Type")] = true
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	for _, stream := range []bool{false, true} {
+		boundaries = make(map[string]bool)
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(server.URL)
+		req.Header.SetMethod("POST")
+		boomer := &Boomer{
+			Request: req,
+			N:       10,
+			C:       2,
+			Form: &Form{
+				Fields: []FormField{{Name: "name", Value: "pla"}, {Name: "upload", File: path}},
+				Stream: stream,
+			},
+			Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+		}
+		rep := boomer.Run()
+		if rep.StatusCodeDist[http.StatusOK] != 10 {
+			t.Errorf("Expected 10 valid forms (stream %v), found %v %v", stream, rep.StatusCodeDist, rep.ErrorDist)
+		}
+		if len(boundaries) != 10 {
+			t.Errorf("Expected a boundary per request (stream %v), found %d", stream, len(boundaries))
+		}
+	}
+
+	boomer := &Boomer{
+		Request: newGet("http://localhost"),
+		N:       1,
+		C:       1,
+		Form:    &Form{Fields: []FormField{{Name: "upload", File: filepath.Join(dir, "missing")}}},
+	}
+	if err := boomer.Validate(); err == nil {
+		t.Errorf("Expected a missing file to be rejected")
+	}
+}
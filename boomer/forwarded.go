@@ -0,0 +1,70 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"encoding/binary"
+	"net"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// addrPool hands out the addresses of a network in a round robin fashion.
+// It is safe for concurrent use.
+type addrPool struct {
+	base net.IP
+	size uint64
+	n    uint64
+}
+
+func newAddrPool(network *net.IPNet) *addrPool {
+	base := network.IP.Mask(network.Mask)
+	if v4 := base.To4(); v4 != nil {
+		base = v4
+	}
+	ones, bits := network.Mask.Size()
+	host := uint(bits - ones)
+	if host > 62 {
+		host = 62
+	}
+	return &addrPool{base: base, size: 1 << host}
+}
+
+// next returns the following address of the pool.
+func (p *addrPool) next() net.IP {
+	i := (atomic.AddUint64(&p.n, 1) - 1) % p.size
+	ip := make(net.IP, len(p.base))
+	copy(ip, p.base)
+	if len(ip) == net.IPv4len {
+		binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(ip)+uint32(i))
+		return ip
+	}
+	// Only the host part changes, which always fits in the last 8 bytes.
+	tail := ip[len(ip)-8:]
+	binary.BigEndian.PutUint64(tail, binary.BigEndian.Uint64(tail)+i)
+	return ip
+}
+
+// setForwarded sets the X-Forwarded-For and Forwarded headers of req as if
+// it was proxied on behalf of ip.
+func setForwarded(req *fasthttp.Request, ip string) {
+	req.Header.Set("X-Forwarded-For", ip)
+	if len(ip) > 0 && ip[0] != '[' && net.ParseIP(ip).To4() == nil {
+		req.Header.Set("Forwarded", `for="[`+ip+`]"`)
+		return
+	}
+	req.Header.Set("Forwarded", "for="+ip)
+}
@@ -0,0 +1,44 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"net"
+	"testing"
+)
+
+func TestAddrPool(t *testing.T) {
+	cases := []struct {
+		cidr string
+		want []string
+	}{
+		{"10.0.0.254/31", []string{"10.0.0.254", "10.0.0.255", "10.0.0.254"}},
+		{"192.168.1.7/32", []string{"192.168.1.7", "192.168.1.7"}},
+		{"10.0.0.0/8", []string{"10.0.0.0", "10.0.0.1", "10.0.0.2"}},
+		{"2001:db8::/126", []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3", "2001:db8::"}},
+	}
+	for _, c := range cases {
+		_, network, err := net.ParseCIDR(c.cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool := newAddrPool(network)
+		for i, want := range c.want {
+			if got := pool.next().String(); got != want {
+				t.Errorf("%s: address %d is %s, want %s", c.cidr, i, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+)
+
+// The values of Boomer.Guard.
+const (
+	GuardWarn  = "warn"
+	GuardAbort = "abort"
+)
+
+const (
+	// guardLimit is the share of the CPU or of the file descriptors
+	// above which the load generator is deemed saturated.
+	guardLimit = 0.9
+
+	// guardCPUSeconds is the number of consecutive seconds the CPU must
+	// be saturated, for short spikes, e.g. of garbage collection, to be
+	// ignored.
+	guardCPUSeconds = 3
+)
+
+// Saturation describes the resources used by the load generator itself
+// during a run, see Boomer.Guard.
+type Saturation struct {
+	// CPU is the peak share of GOMAXPROCS used by the process over a
+	// second, and CPUSeconds the number of seconds it was above 90%.
+	CPU        float64
+	CPUSeconds int
+	GOMAXPROCS int
+
+	// FDs is the peak number of open file descriptors, and FDLimit their
+	// limit. Both are zero where unknown.
+	FDs     int
+	FDLimit int
+
+	// Saturated is set if the CPU was above 90% for 3 seconds in a row or
+	// the file descriptors above 90% of their limit: the results then
+	// reflect the load generator rather than the target.
+	Saturated bool
+}
+
+// resourceGuard samples the CPU usage and the open file descriptors of
+// the process every second of a run.
+type resourceGuard struct {
+	abort   bool
+	cancel  context.CancelFunc
+	logf    func(format string, v ...interface{})
+	done    chan struct{}
+	stopped chan struct{}
+
+	cpu    time.Duration
+	last   time.Time
+	streak int
+	sat    Saturation
+	reason string
+}
+
+// newResourceGuard starts guarding the resources of a run, if Guard is
+// set. With GuardAbort, cancel stops the run once saturated.
+func (b *Boomer) newResourceGuard(cancel context.CancelFunc) *resourceGuard {
+	if b.Guard == "" {
+		return nil
+	}
+	g := &resourceGuard{
+		abort:   b.Guard == GuardAbort,
+		cancel:  cancel,
+		logf:    b.logf,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		last:    time.Now(),
+		sat:     Saturation{GOMAXPROCS: runtime.GOMAXPROCS(0)},
+	}
+	g.cpu, _ = processCPU()
+	go g.run()
+	return g
+}
+
+func (g *resourceGuard) run() {
+	defer close(g.stopped)
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-g.done:
+			return
+		case now := <-t.C:
+			g.sample(now)
+		}
+	}
+}
+
+// sample accounts the usage since the previous sample.
+func (g *resourceGuard) sample(now time.Time) {
+	var reason string
+	if cpu, ok := processCPU(); ok {
+		share := float64(cpu-g.cpu) / float64(now.Sub(g.last)) / float64(g.sat.GOMAXPROCS)
+		g.cpu, g.last = cpu, now
+		if share > g.sat.CPU {
+			g.sat.CPU = share
+		}
+		if share > guardLimit {
+			g.sat.CPUSeconds++
+			g.streak++
+		} else {
+			g.streak = 0
+		}
+		if g.streak >= guardCPUSeconds {
+			reason = fmt.Sprintf("CPU above %.0f%% for %d seconds", guardLimit*100, g.streak)
+		}
+	}
+	if open, limit := openFiles(); limit > 0 {
+		if open > g.sat.FDs {
+			g.sat.FDs = open
+		}
+		g.sat.FDLimit = limit
+		if float64(open) > guardLimit*float64(limit) {
+			reason = fmt.Sprintf("%d open files of %d", open, limit)
+		}
+	}
+	if reason == "" || g.sat.Saturated {
+		return
+	}
+	g.sat.Saturated = true
+	g.logf("load generator saturated: %s", reason)
+	if g.abort {
+		g.reason = "load generator saturated, " + reason
+		g.cancel()
+	}
+}
+
+// stop stops the sampling.
+func (g *resourceGuard) stop() {
+	close(g.done)
+	<-g.stopped
+}
+
+func printSaturation(w io.Writer, s *Saturation) {
+	fmt.Fprintf(w, "\nLoad generator:\n")
+	fmt.Fprintf(w, "  Peak CPU:\t%.1f%% of GOMAXPROCS %d, %d seconds above %.0f%%\n",
+		s.CPU*100, s.GOMAXPROCS, s.CPUSeconds, guardLimit*100)
+	if s.FDLimit > 0 {
+		fmt.Fprintf(w, "  Peak open files:\t%d of %d\n", s.FDs, s.FDLimit)
+	}
+	if s.Saturated {
+		fmt.Fprintf(w, "  Saturated:\tthe results reflect the load generator rather than the target\n")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResourceGuard(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the resources are not monitored on windows")
+	}
+	logger := &testLogger{}
+	cancelled := false
+	g := &resourceGuard{
+		abort:  true,
+		cancel: func() { cancelled = true },
+		logf:   (&Boomer{Logger: logger}).logf,
+		sat:    Saturation{GOMAXPROCS: 1},
+	}
+	now := time.Now()
+	for i := 0; i < guardCPUSeconds; i++ {
+		// The CPU used by the test so far, within a nanosecond.
+		g.cpu, g.last = 0, now.Add(-time.Nanosecond)
+		g.sample(now)
+		if g.sat.Saturated != (i == guardCPUSeconds-1) {
+			t.Fatalf("Expected saturation after %d seconds, found %v after %d", guardCPUSeconds, g.sat.Saturated, i+1)
+		}
+	}
+	if g.sat.CPUSeconds != guardCPUSeconds || !cancelled || !strings.Contains(g.reason, "CPU above 90%") {
+		t.Errorf("Expected the run to be aborted, found %+v, %q", g.sat, g.reason)
+	}
+	if len(logger.messages) != 1 {
+		t.Errorf("Expected the saturation to be logged once, found %q", logger.messages)
+	}
+	if runtime.GOOS == "linux" && (g.sat.FDs == 0 || g.sat.FDLimit == 0) {
+		t.Errorf("Expected the open files to be counted, found %+v", g.sat)
+	}
+
+	var w bytes.Buffer
+	printSaturation(&w, &g.sat)
+	if !strings.Contains(w.String(), "seconds above 90%") || !strings.Contains(w.String(), "Saturated:") {
+		t.Errorf("Unexpected saturation summary:\n%s", w.String())
+	}
+}
+
+func TestGuard(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	boomer := &Boomer{
+		Request:  newGet(server.URL),
+		N:        10,
+		C:        2,
+		Guard:    GuardWarn,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	if rep := boomer.Run(); rep.Saturation == nil || rep.Saturation.GOMAXPROCS == 0 {
+		t.Errorf("Expected the saturation in the report, found %+v", rep.Saturation)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	// heatmapBase is the upper bound of the fastest latency bucket of the
+	// heatmap, every following bucket doubles it.
+	heatmapBase    = 100 * time.Microsecond
+	heatmapBuckets = 21
+
+	// heatmapColumns is the maximum number of columns rendered, longer
+	// runs merge several seconds in a column.
+	heatmapColumns = 600
+)
+
+// Heatmap counts the successful requests per second of the run and
+// latency bucket.
+type Heatmap struct {
+	// Bounds are the upper latency bounds of the buckets, in increasing
+	// order. The last bucket also holds the slower requests.
+	Bounds []time.Duration
+
+	// Counts holds the bucket counts of every second of the run, nil for
+	// the seconds without successful requests.
+	Counts [][]uint64
+}
+
+func heatmapBounds() []time.Duration {
+	bounds := make([]time.Duration, heatmapBuckets)
+	for i := range bounds {
+		bounds[i] = heatmapBase << uint(i)
+	}
+	return bounds
+}
+
+// heatmapBucket returns the bucket of a latency.
+func heatmapBucket(d time.Duration) int {
+	i := 0
+	for bound := heatmapBase; d > bound && i < heatmapBuckets-1; bound *= 2 {
+		i++
+	}
+	return i
+}
+
+// columns merges the seconds of h so that at most max columns are left.
+// It returns the merged counts, the number of seconds per column, and
+// the range of buckets holding any request.
+func (h *Heatmap) columns(max int) (cols [][]uint64, step, lo, hi int) {
+	step = (len(h.Counts) + max - 1) / max
+	if step < 1 {
+		step = 1
+	}
+	lo, hi = len(h.Bounds), -1
+	for i, counts := range h.Counts {
+		if i%step == 0 {
+			cols = append(cols, make([]uint64, len(h.Bounds)))
+		}
+		col := cols[len(cols)-1]
+		for b, c := range counts {
+			col[b] += c
+			if c > 0 && b < lo {
+				lo = b
+			}
+			if c > 0 && b > hi {
+				hi = b
+			}
+		}
+	}
+	return cols, step, lo, hi
+}
+
+// heatColor maps a count to a color, from white to dark red on a
+// logarithmic scale so that rare slow requests remain visible.
+func heatColor(c, max uint64) color.RGBA {
+	if c == 0 || max == 0 {
+		return color.RGBA{255, 255, 255, 255}
+	}
+	t := 0.15 + 0.85*math.Log1p(float64(c))/math.Log1p(float64(max))
+	return color.RGBA{uint8(255 - 127*t), uint8(255 * (1 - t)), uint8(255 * (1 - t)), 255}
+}
+
+func maxCount(cols [][]uint64) uint64 {
+	var max uint64
+	for _, col := range cols {
+		for _, c := range col {
+			if c > max {
+				max = c
+			}
+		}
+	}
+	return max
+}
+
+// renderHeatmap writes the latency heatmap of the run as an HTML page
+// holding an SVG image: time on the x axis, latency buckets on the y
+// axis, slowest on top.
+func renderHeatmap(w io.Writer, r *Report) error {
+	const cellH, left, bottom = 16, 90, 30
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>Latency heatmap</title></head>\n<body style=\"font-family: sans-serif\">\n")
+	defer fmt.Fprintf(w, "</body>\n</html>\n")
+	fmt.Fprintf(w, "<h1>Latency heatmap</h1>\n<p>Run %s: %s requests in %s, %s requests/sec.</p>\n", html.EscapeString(r.RunID),
+		formatCount(float64(r.Count)), html.EscapeString(formatSeconds(r.Total.Seconds())), formatCount(r.RPS))
+	if r.Heatmap == nil {
+		fmt.Fprintf(w, "<p>No successful requests.</p>\n")
+		return nil
+	}
+	cols, step, lo, hi := r.Heatmap.columns(heatmapColumns)
+	if hi < lo {
+		fmt.Fprintf(w, "<p>No successful requests.</p>\n")
+		return nil
+	}
+	cellW := 800 / len(cols)
+	if cellW < 1 {
+		cellW = 1
+	} else if cellW > 20 {
+		cellW = 20
+	}
+	rows := hi - lo + 1
+	width, height := left+len(cols)*cellW+10, rows*cellH+bottom+10
+	max := maxCount(cols)
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-size=\"11\">\n", width, height)
+	for x, col := range cols {
+		for b := lo; b <= hi; b++ {
+			if col[b] == 0 {
+				continue
+			}
+			c := heatColor(col[b], max)
+			fmt.Fprintf(w, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"#%02x%02x%02x\"><title>%ds: %d</title></rect>\n",
+				left+x*cellW, (hi-b)*cellH, cellW, cellH, c.R, c.G, c.B, x*step, col[b])
+		}
+	}
+	for b := lo; b <= hi; b++ {
+		fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">&lt; %s</text>\n",
+			left-5, (hi-b)*cellH+cellH-4, html.EscapeString(formatSeconds(r.Heatmap.Bounds[b].Seconds())))
+	}
+	ticks := len(cols)/10 + 1
+	for x := 0; x < len(cols); x += ticks {
+		fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\">%v</text>\n",
+			left+x*cellW, rows*cellH+15, time.Duration(x*step)*time.Second)
+	}
+	fmt.Fprintf(w, "</svg>\n")
+	return nil
+}
+
+// renderHeatmapPNG writes the latency heatmap of the run as a PNG image,
+// without axes, laid out as by renderHeatmap. The run ID is stored as a
+// text chunk of the image.
+func renderHeatmapPNG(w io.Writer, r *Report) error {
+	const cellW, cellH = 4, 16
+	var cols [][]uint64
+	lo, hi := 0, -1
+	if r.Heatmap != nil {
+		cols, _, lo, hi = r.Heatmap.columns(heatmapColumns)
+	}
+	if hi < lo {
+		return fmt.Errorf("no successful requests to draw")
+	}
+	img := image.NewRGBA(image.Rect(0, 0, len(cols)*cellW, (hi-lo+1)*cellH))
+	max := maxCount(cols)
+	for x, col := range cols {
+		for b := lo; b <= hi; b++ {
+			c := heatColor(col[b], max)
+			for px := x * cellW; px < (x+1)*cellW; px++ {
+				for py := (hi - b) * cellH; py < (hi-b+1)*cellH; py++ {
+					img.SetRGBA(px, py, c)
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	_, err := w.Write(pngText(buf.Bytes(), "Run ID", r.RunID))
+	return err
+}
+
+// pngText adds a tEXt chunk to an encoded PNG image, right after the
+// IHDR chunk which must come first.
+func pngText(img []byte, key, value string) []byte {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if len(img) < ihdrEnd || value == "" {
+		return img
+	}
+	data := append([]byte("tEXt"+key+"\x00"), value...)
+	chunk := make([]byte, 4, len(data)+8)
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)-4))
+	chunk = append(chunk, data...)
+	chunk = chunk[:len(chunk)+4]
+	binary.BigEndian.PutUint32(chunk[len(chunk)-4:], crc32.ChecksumIEEE(data))
+	out := make([]byte, 0, len(img)+len(chunk))
+	out = append(out, img[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, img[ihdrEnd:]...)
+}
@@ -0,0 +1,100 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeatmapBucket(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{0, 0},
+		{100 * time.Microsecond, 0},
+		{101 * time.Microsecond, 1},
+		{time.Millisecond, 4},
+		{time.Hour, heatmapBuckets - 1},
+	}
+	bounds := heatmapBounds()
+	for _, tt := range tests {
+		got := heatmapBucket(tt.d)
+		if got != tt.want {
+			t.Errorf("Expected %v in bucket %d, found %d", tt.d, tt.want, got)
+		}
+		if got < heatmapBuckets-1 && tt.d > bounds[got] {
+			t.Errorf("Expected %v to be under the bound %v of its bucket", tt.d, bounds[got])
+		}
+	}
+}
+
+func TestHeatmapColumns(t *testing.T) {
+	h := &Heatmap{Bounds: heatmapBounds(), Counts: make([][]uint64, 25)}
+	for i := range h.Counts {
+		if i == 3 {
+			continue
+		}
+		h.Counts[i] = make([]uint64, heatmapBuckets)
+		h.Counts[i][2+i%3]++
+	}
+	cols, step, lo, hi := h.columns(10)
+	if len(cols) != 9 || step != 3 || lo != 2 || hi != 4 {
+		t.Fatalf("Expected 9 columns of 3 seconds over buckets 2 to 4, found %d of %d over %d to %d", len(cols), step, lo, hi)
+	}
+	if cols[0][2] != 1 || cols[0][3] != 1 || cols[1][2] != 0 || cols[1][3] != 1 {
+		t.Errorf("Unexpected merged columns %v and %v", cols[0], cols[1])
+	}
+}
+
+func TestRenderHeatmap(t *testing.T) {
+	r := &Report{RunID: newUUID(), Count: 3, Heatmap: &Heatmap{Bounds: heatmapBounds(), Counts: [][]uint64{
+		make([]uint64, heatmapBuckets),
+		nil,
+		make([]uint64, heatmapBuckets),
+	}}}
+	r.Heatmap.Counts[0][5] = 2
+	r.Heatmap.Counts[2][7] = 1
+
+	var w bytes.Buffer
+	if err := renderHeatmap(&w, r); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(w.String(), "<rect"); n != 2 {
+		t.Errorf("Expected 2 cells, found %d", n)
+	}
+	if !strings.Contains(w.String(), "</svg>") {
+		t.Errorf("Expected an SVG image, found %s", w.String())
+	}
+
+	w.Reset()
+	if err := renderHeatmapPNG(&w, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(w.Bytes(), []byte("tEXtRun ID\x00"+r.RunID)) {
+		t.Errorf("Expected the run ID in the image")
+	}
+	img, err := png.Decode(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 3*4 || b.Dy() != 3*16 {
+		t.Errorf("Expected a 12x48 image, found %v", b)
+	}
+}
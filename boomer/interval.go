@@ -0,0 +1,111 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// intervalReporter prints a summary of the requests completed during
+// every interval of a run, see Boomer.IntervalReport, for a degradation
+// of the target to show during long runs rather than only in the final
+// report.
+type intervalReporter struct {
+	w        io.Writer
+	interval time.Duration
+	start    time.Time
+	done     chan struct{}
+	stopped  chan struct{}
+
+	mu        sync.Mutex
+	total     int64
+	failures  int64
+	latencies reservoir
+}
+
+// newIntervalReporter starts the interval reports of a run, if
+// IntervalReport is set.
+func (b *Boomer) newIntervalReporter() *intervalReporter {
+	if b.IntervalReport <= 0 {
+		return nil
+	}
+	w := b.IntervalWriter
+	if w == nil {
+		w = os.Stderr
+	}
+	i := &intervalReporter{w: w, interval: b.IntervalReport, start: time.Now(),
+		done: make(chan struct{}), stopped: make(chan struct{})}
+	go i.run()
+	return i
+}
+
+// add accounts a result. Failures are errors and 5xx responses.
+func (i *intervalReporter) add(res *result) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.total++
+	if res.err != nil || res.statusCode >= 500 {
+		i.failures++
+	}
+	if res.err == nil {
+		i.latencies.add(res.duration)
+	}
+}
+
+func (i *intervalReporter) run() {
+	defer close(i.stopped)
+	t := time.NewTicker(i.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-i.done:
+			return
+		case now := <-t.C:
+			i.print(now)
+		}
+	}
+}
+
+// stop stops the reporting. The last, partial interval is left to the
+// final report.
+func (i *intervalReporter) stop() {
+	close(i.done)
+	<-i.stopped
+}
+
+func (i *intervalReporter) print(now time.Time) {
+	i.mu.Lock()
+	total, failures := i.total, i.failures
+	lats, _ := i.latencies.take()
+	i.total, i.failures = 0, 0
+	i.mu.Unlock()
+
+	fmt.Fprintf(i.w, "%s\t+%v\t%s requests/sec", now.Format(time.RFC3339), now.Sub(i.start).Round(time.Second),
+		formatCount(float64(total)/i.interval.Seconds()))
+	if total > 0 {
+		fmt.Fprintf(i.w, "\t%.2f%% errors", float64(failures)/float64(total)*100)
+	}
+	if len(lats) > 0 {
+		sort.Slice(lats, func(a, b int) bool { return lats[a] < lats[b] })
+		fmt.Fprintf(i.w, "\tp50 %s\tp99 %s", formatSeconds(lats[len(lats)/2].Seconds()),
+			formatSeconds(lats[len(lats)*99/100].Seconds()))
+	}
+	fmt.Fprintln(i.w)
+}
@@ -0,0 +1,57 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntervalReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	boomer := &Boomer{
+		Request:        newGet(server.URL + "/?fail=1"),
+		N:              25,
+		C:              1,
+		Qps:            100,
+		IntervalReport: 100 * time.Millisecond,
+		IntervalWriter: &buf,
+		Renderer:       RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	boomer.Run()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("Expected an interval report every 100ms, found %q", buf.String())
+	}
+	for _, l := range lines {
+		for _, want := range []string{"requests/sec", "100.00% errors", "p50", "p99"} {
+			if !strings.Contains(l, want) {
+				t.Errorf("Expected %q in the interval report %q", want, l)
+			}
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Signer signs the JSON reports so that they can be verified as
+// untampered once submitted elsewhere.
+type Signer interface {
+	// Algorithm names the signature algorithm, e.g. "hmac-sha256".
+	Algorithm() string
+	Sign(payload []byte) ([]byte, error)
+	Verify(payload, sig []byte) error
+}
+
+// HMACSigner returns a Signer computing an HMAC-SHA256 of the reports
+// with key.
+func HMACSigner(key []byte) Signer {
+	return hmacSigner(key)
+}
+
+type hmacSigner []byte
+
+func (k hmacSigner) Algorithm() string { return "hmac-sha256" }
+
+func (k hmacSigner) Sign(payload []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, k)
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+func (k hmacSigner) Verify(payload, sig []byte) error {
+	want, _ := k.Sign(payload)
+	if !hmac.Equal(want, sig) {
+		return errors.New("invalid report signature")
+	}
+	return nil
+}
+
+// Ed25519Signer returns a Signer signing the reports with an ed25519
+// private key. Only the public key is needed to verify them, see
+// Ed25519Verifier.
+func Ed25519Signer(key ed25519.PrivateKey) Signer {
+	return ed25519Signer{private: key, public: key.Public().(ed25519.PublicKey)}
+}
+
+// Ed25519Verifier returns a Signer that can only verify reports signed
+// with the private key of key.
+func Ed25519Verifier(key ed25519.PublicKey) Signer {
+	return ed25519Signer{public: key}
+}
+
+type ed25519Signer struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+func (s ed25519Signer) Algorithm() string { return "ed25519" }
+
+func (s ed25519Signer) Sign(payload []byte) ([]byte, error) {
+	if s.private == nil {
+		return nil, errors.New("no ed25519 private key to sign with")
+	}
+	return ed25519.Sign(s.private, payload), nil
+}
+
+func (s ed25519Signer) Verify(payload, sig []byte) error {
+	if !ed25519.Verify(s.public, payload, sig) {
+		return errors.New("invalid report signature")
+	}
+	return nil
+}
+
+// signedReport is the document written by JSONRenderer. Report is kept
+// raw to verify the signature against the encoding that was signed.
+type signedReport struct {
+	Report    json.RawMessage `json:"report"`
+	Signature *signature      `json:"signature,omitempty"`
+}
+
+type signature struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"value"`
+}
+
+// JSONRenderer writes the report as a JSON document, under a "report"
+// key. Durations are given in nanoseconds. If Signer is set, the
+// document also holds the signature of the report, which VerifyReport
+// checks.
+type JSONRenderer struct {
+	Signer Signer
+}
+
+// Render implements Renderer.
+func (j JSONRenderer) Render(w io.Writer, r *Report) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	doc := signedReport{Report: data}
+	if j.Signer != nil {
+		sig, err := j.Signer.Sign(data)
+		if err != nil {
+			return err
+		}
+		doc.Signature = &signature{
+			Algorithm: j.Signer.Algorithm(),
+			Value:     base64.StdEncoding.EncodeToString(sig),
+		}
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
+
+// VerifyReport checks the signature of a document written by a
+// JSONRenderer and returns its report.
+func VerifyReport(data []byte, s Signer) (*Report, error) {
+	var doc signedReport
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Signature == nil {
+		return nil, errors.New("the report is not signed")
+	}
+	if doc.Signature.Algorithm != s.Algorithm() {
+		return nil, fmt.Errorf("the report is signed with %s, not %s", doc.Signature.Algorithm, s.Algorithm())
+	}
+	sig, err := base64.StdEncoding.DecodeString(doc.Signature.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Verify(compactJSON(doc.Report), sig); err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	if err := json.Unmarshal(doc.Report, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReadReport returns the report written by JSONRenderer in data, without
+// verifying its signature, if any.
+func ReadReport(data []byte) (*Report, error) {
+	var doc signedReport
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Report == nil {
+		return nil, errors.New("no report found")
+	}
+	r := &Report{}
+	if err := json.Unmarshal(doc.Report, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// compactJSON removes the indentation of a report: the signature covers
+// its compact encoding.
+func compactJSON(data []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
+
+// WriteSummaryJSON writes the main figures of r as a JSON object on a
+// single line, for scripts: the counts, the rate, and the latencies
+// under keys such as "average" and "p99", in nanoseconds.
+func WriteSummaryJSON(w io.Writer, r *Report) error {
+	errs := errorCount(r)
+	summary := map[string]interface{}{
+		"run_id":   r.RunID,
+		"requests": r.Count + int64(errs),
+		"errors":   errs,
+		"total":    r.Total,
+		"rps":      r.RPS,
+		"average":  r.Average,
+		"fastest":  r.Fastest,
+		"slowest":  r.Slowest,
+		"bytes":    r.SizeTotal,
+	}
+	if r.Aborted != "" {
+		summary["aborted"] = r.Aborted
+	}
+	for _, l := range r.Latencies {
+		summary[fmt.Sprintf("p%d", l.Percentage)] = l.Latency
+	}
+	return json.NewEncoder(w).Encode(summary)
+}
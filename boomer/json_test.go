@@ -0,0 +1,138 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunID(t *testing.T) {
+	a, b := newUUID(), newUUID()
+	if a == b || len(a) != 36 || a[14] != '4' {
+		t.Errorf("Expected distinct version 4 UUIDs, found %s and %s", a, b)
+	}
+}
+
+func TestReportWriter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	boomer := &Boomer{
+		Request:      newGet(server.URL),
+		N:            5,
+		C:            1,
+		Output:       "json",
+		ReportWriter: &buf,
+	}
+	boomer.Run()
+	var doc struct {
+		Report Report `json:"report"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected the JSON report in the writer, found %q: %v", buf.String(), err)
+	}
+	if doc.Report.Count != 5 {
+		t.Errorf("Expected 5 requests, found %d", doc.Report.Count)
+	}
+}
+
+func TestJSONRenderer(t *testing.T) {
+	r := &Report{
+		RunID:          newUUID(),
+		Total:          time.Second,
+		Count:          3,
+		StatusCodeDist: map[int]int{200: 3},
+		Breakdowns:     map[string]map[string]*Breakdown{"target": {"<a&b>": {Count: 3}}},
+	}
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := []Signer{HMACSigner([]byte("secret")), Ed25519Signer(priv)}
+	for _, s := range signers {
+		var w bytes.Buffer
+		if err := (JSONRenderer{Signer: s}).Render(&w, r); err != nil {
+			t.Fatal(err)
+		}
+		verifier := s
+		if s.Algorithm() == "ed25519" {
+			verifier = Ed25519Verifier(priv.Public().(ed25519.PublicKey))
+		}
+		got, err := VerifyReport(w.Bytes(), verifier)
+		if err != nil {
+			t.Fatalf("Expected the %s signature to verify: %v", s.Algorithm(), err)
+		}
+		if got.RunID != r.RunID || got.StatusCodeDist[200] != 3 || got.Breakdowns["target"]["<a&b>"].Count != 3 {
+			t.Errorf("Expected the report back, found %+v", got)
+		}
+
+		tampered := strings.Replace(w.String(), `"Count": 3`, `"Count": 4`, 1)
+		if _, err := VerifyReport([]byte(tampered), verifier); err == nil {
+			t.Errorf("Expected a tampered report to fail the %s verification", s.Algorithm())
+		}
+	}
+
+	var w bytes.Buffer
+	if err := (JSONRenderer{}).Render(&w, r); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(w.Bytes(), &doc); err != nil || doc["signature"] != nil {
+		t.Errorf("Expected an unsigned report, found %s", w.String())
+	}
+	if _, err := VerifyReport(w.Bytes(), signers[0]); err == nil {
+		t.Errorf("Expected an unsigned report to fail verification")
+	}
+	if got, err := ReadReport(w.Bytes()); err != nil || got.RunID != r.RunID {
+		t.Errorf("Expected the unsigned report to be read back, found %v", err)
+	}
+	if _, err := ReadReport([]byte(`{}`)); err == nil {
+		t.Errorf("Expected an error for a document without report")
+	}
+}
+
+func TestWriteSummaryJSON(t *testing.T) {
+	var w bytes.Buffer
+	if err := WriteSummaryJSON(&w, compatReport()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(w.String(), "\n") != 1 {
+		t.Errorf("Expected the summary on a single line, found %q", w.String())
+	}
+	var summary map[string]interface{}
+	if err := json.Unmarshal(w.Bytes(), &summary); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]float64{
+		"requests": 1003,
+		"errors":   3,
+		"rps":      500,
+		"p50":      float64(2 * time.Millisecond),
+		"p99":      float64(8 * time.Millisecond),
+	} {
+		if summary[key] != want {
+			t.Errorf("Expected %s to be %v, found %v", key, want, summary[key])
+		}
+	}
+}
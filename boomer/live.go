@@ -0,0 +1,122 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sschepens/pb"
+)
+
+// liveStats shows the achieved throughput, the number of errors, the
+// 99th percentile latency of the last second and the time left after the
+// progress bar, for a struggling target to show before the end of the
+// run. With fn set, it reports the progress to fn instead of a bar.
+type liveStats struct {
+	bar   *pb.ProgressBar
+	fn    func(done, total, errors int)
+	total int
+	start time.Time
+	done  chan struct{}
+	ended chan struct{}
+
+	mu        sync.Mutex
+	count     int64
+	completed int64
+	errors    int64
+	latencies reservoir
+}
+
+func newLiveStats(bar *pb.ProgressBar, total int, fn func(done, total, errors int)) *liveStats {
+	l := &liveStats{
+		bar:   bar,
+		fn:    fn,
+		total: total,
+		start: time.Now(),
+		done:  make(chan struct{}),
+		ended: make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+func (l *liveStats) add(res *result) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.count++
+	l.completed++
+	if res.err != nil {
+		l.errors++
+		return
+	}
+	l.latencies.add(res.duration)
+}
+
+func (l *liveStats) run() {
+	defer close(l.ended)
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-t.C:
+			l.update()
+		}
+	}
+}
+
+func (l *liveStats) update() {
+	l.mu.Lock()
+	count, completed, errors := l.count, l.completed, l.errors
+	lats, _ := l.latencies.take()
+	l.count = 0
+	l.mu.Unlock()
+
+	if l.fn != nil {
+		l.fn(int(completed), l.total, int(errors))
+		return
+	}
+	s := fmt.Sprintf(" %s req/s, %s errors", formatCount(float64(count)), formatCount(float64(errors)))
+	if len(lats) > 0 {
+		sort.Slice(lats, func(i, j int) bool { return lats[i] < lats[j] })
+		s += ", p99 " + formatSeconds(lats[len(lats)*99/100].Seconds())
+	}
+	if completed > 0 && completed < int64(l.total) {
+		// At the average rate of the run so far.
+		eta := time.Since(l.start) * time.Duration(int64(l.total)-completed) / time.Duration(completed)
+		s += ", ETA " + eta.Round(time.Second).String()
+	}
+	l.bar.Postfix(s)
+}
+
+// stop stops the updates once all the results are in, reporting the
+// final progress to fn.
+func (l *liveStats) stop() {
+	if l == nil {
+		return
+	}
+	close(l.done)
+	<-l.ended
+	if l.fn != nil {
+		l.update()
+	}
+}
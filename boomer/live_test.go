@@ -0,0 +1,94 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sschepens/pb"
+)
+
+func TestLiveStats(t *testing.T) {
+	var buf bytes.Buffer
+	bar := pb.New(10)
+	bar.Output = &buf
+	l := &liveStats{bar: bar}
+	l.add(&result{duration: 10 * time.Millisecond})
+	l.add(&result{duration: 20 * time.Millisecond})
+	l.add(&result{err: errors.New("timeout")})
+	l.update()
+	bar.Update()
+	if want := "3 req/s, 1 errors, p99 20.000 ms"; !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected %q in the progress line, found %q", want, buf.String())
+	}
+
+	buf.Reset()
+	l.update()
+	bar.Update()
+	if want := "0 req/s, 1 errors"; !strings.Contains(buf.String(), want) || strings.Contains(buf.String(), "p99") {
+		t.Errorf("Expected %q in the progress line of an idle second, found %q", want, buf.String())
+	}
+}
+
+func TestLiveStatsETA(t *testing.T) {
+	var buf bytes.Buffer
+	bar := pb.New(30)
+	bar.Output = &buf
+	l := &liveStats{bar: bar, total: 30, start: time.Now().Add(-10 * time.Second)}
+	for i := 0; i < 10; i++ {
+		l.add(&result{duration: time.Millisecond})
+	}
+	l.update()
+	bar.Update()
+	if want := "ETA 20s"; !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected %q in the progress line, found %q", want, buf.String())
+	}
+}
+
+func TestProgressFunc(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var calls [][3]int
+	boomer := &Boomer{
+		Request: newGet(server.URL),
+		N:       20,
+		C:       2,
+		ProgressFunc: func(done, total, errors int) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, [3]int{done, total, errors})
+		},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	boomer.Run()
+	if boomer.bar != nil {
+		t.Error("Expected no progress bar with a ProgressFunc")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) == 0 || calls[len(calls)-1] != [3]int{20, 20, 0} {
+		t.Errorf("Expected the final progress to be reported, found %v", calls)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+// Logger receives the warnings of a run, e.g. the first occurrences of
+// the request errors, or the rate limit not being met. A *log.Logger is
+// a Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// maxLoggedErrors is the number of distinct request errors logged per
+// run; the following ones are only accounted in the report.
+const maxLoggedErrors = 10
+
+// logf logs a warning to b.Logger, if set.
+func (b *Boomer) logf(format string, v ...interface{}) {
+	if b.Logger != nil {
+		b.Logger.Printf(format, v...)
+	}
+}
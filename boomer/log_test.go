@@ -0,0 +1,64 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func TestLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	logger := &testLogger{}
+	boomer := &Boomer{
+		Request:  newGet(url),
+		N:        20,
+		C:        2,
+		Quiet:    true,
+		Logger:   logger,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if boomer.bar != nil {
+		t.Error("Expected no progress bar in quiet mode")
+	}
+	if len(logger.messages) == 0 || len(logger.messages) != len(rep.ErrorDist) {
+		t.Fatalf("Expected every distinct error to be logged once, found %q for %v", logger.messages, rep.ErrorDist)
+	}
+	for _, m := range logger.messages {
+		if !strings.HasPrefix(m, "request error: ") {
+			t.Errorf("Expected a request error, found %q", m)
+		}
+	}
+}
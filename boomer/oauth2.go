@@ -0,0 +1,198 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// oauth2Timeout bounds the token requests.
+const oauth2Timeout = 30 * time.Second
+
+// oauth2MinBackoff and oauth2MaxBackoff bound the wait after a failed
+// token request, doubled on every failure, during which the failure is
+// returned without requesting a token again.
+const (
+	oauth2MinBackoff = time.Second
+	oauth2MaxBackoff = time.Minute
+)
+
+// OAuth2 acquires access tokens with the OAuth2 client credentials grant
+// and refreshes them shortly before they expire. It is safe for concurrent
+// use: a single token request is made at a time, the previous token being
+// used meanwhile until it expires.
+type OAuth2 struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	mu sync.Mutex
+	// client makes the token requests, set by the Boomer for them to go
+	// through its proxy, address overrides and trusted certificates.
+	client    *fasthttp.Client
+	token     string
+	refreshAt time.Time
+	expireAt  time.Time
+	// fetching is closed once the token request in progress, if any, is
+	// done.
+	fetching chan struct{}
+	// err is the error of the last token request, returned until retryAt.
+	err     error
+	retryAt time.Time
+	backoff time.Duration
+}
+
+// noExpiry is the expiry of the tokens issued without a lifetime.
+var noExpiry = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Token returns a valid access token, fetching a new one if needed.
+func (o *OAuth2) Token() (string, error) {
+	o.mu.Lock()
+	now := time.Now()
+	if o.token != "" && now.Before(o.refreshAt) {
+		token := o.token
+		o.mu.Unlock()
+		return token, nil
+	}
+	if o.fetching == nil {
+		if o.err != nil && now.Before(o.retryAt) {
+			err := o.err
+			o.mu.Unlock()
+			return "", err
+		}
+		o.fetching = make(chan struct{})
+		go o.refresh(o.fetching)
+	}
+	fetching := o.fetching
+	if o.token != "" && now.Before(o.expireAt) {
+		token := o.token
+		o.mu.Unlock()
+		return token, nil
+	}
+	o.mu.Unlock()
+
+	<-fetching
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err != nil {
+		return "", o.err
+	}
+	return o.token, nil
+}
+
+// refresh requests a token and closes done. A failure delays the next
+// token request, from oauth2MinBackoff up to oauth2MaxBackoff.
+func (o *OAuth2) refresh(done chan struct{}) {
+	defer close(done)
+	err := o.fetch()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.fetching = nil
+	if err != nil {
+		o.backoff *= 2
+		if o.backoff < oauth2MinBackoff {
+			o.backoff = oauth2MinBackoff
+		}
+		if o.backoff > oauth2MaxBackoff {
+			o.backoff = oauth2MaxBackoff
+		}
+		o.err, o.retryAt = err, time.Now().Add(o.backoff)
+		return
+	}
+	o.err, o.backoff = nil, 0
+}
+
+// setClient sets the client of the token requests.
+func (o *OAuth2) setClient(c *fasthttp.Client) {
+	o.mu.Lock()
+	o.client = c
+	o.mu.Unlock()
+}
+
+// CheckOAuth2 requests a token of OAuth2 as the run would, through the
+// proxy and the address overrides of b, for a misconfiguration to show
+// before the run. The token is used by the run, and a failure does not
+// delay its token requests.
+func (b *Boomer) CheckOAuth2() error {
+	b.OAuth2.setClient(b.oauth2Client())
+	return b.OAuth2.fetch()
+}
+
+// fetch requests a token and stores it. It is made without holding mu,
+// for the requests to go on with the previous token meanwhile.
+func (o *OAuth2) fetch() error {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(o.TokenURL)
+	req.Header.SetMethod("POST")
+	req.Header.SetContentType("application/x-www-form-urlencoded")
+	args := req.PostArgs()
+	args.Set("grant_type", "client_credentials")
+	if len(o.Scopes) > 0 {
+		args.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	req.SetBodyString(args.String())
+	req.Header.Set("Authorization", "Basic "+basicAuth(o.ClientID, o.ClientSecret))
+
+	o.mu.Lock()
+	client := o.client
+	o.mu.Unlock()
+	if client == nil {
+		client = &fasthttp.Client{}
+	}
+	issued := time.Now()
+	if err := client.DoTimeout(req, resp, oauth2Timeout); err != nil {
+		return fmt.Errorf("oauth2: %v", err)
+	}
+	if code := resp.StatusCode(); code != fasthttp.StatusOK {
+		return fmt.Errorf("oauth2: token request failed with status %d: %s", code, resp.Body())
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(resp.Body(), &token); err != nil {
+		return fmt.Errorf("oauth2: %v", err)
+	}
+	if token.AccessToken == "" {
+		return fmt.Errorf("oauth2: no access token in response")
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.token = token.AccessToken
+	o.refreshAt, o.expireAt = noExpiry, noExpiry
+	if token.ExpiresIn > 0 {
+		// Refresh a little ahead of time so that in-flight requests
+		// don't carry an expired token.
+		lifetime := time.Duration(token.ExpiresIn) * time.Second
+		margin := lifetime / 10
+		if margin > 10*time.Second {
+			margin = 10 * time.Second
+		}
+		o.refreshAt = issued.Add(lifetime - margin)
+		o.expireAt = issued.Add(lifetime)
+	}
+	return nil
+}
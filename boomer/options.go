@@ -0,0 +1,228 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// maxQps is the highest rate limit: the requests are paced with a
+// microsecond resolution.
+const maxQps = 1000000
+
+// Option configures a Boomer built by New.
+type Option func(*Boomer)
+
+// WithRequests sets the total number of requests, 200 by default.
+func WithRequests(n int) Option {
+	return func(b *Boomer) { b.N = n }
+}
+
+// WithConcurrency sets the number of workers, 50 by default.
+func WithConcurrency(c int) Option {
+	return func(b *Boomer) { b.C = c }
+}
+
+// WithQps sets the rate limit, in requests per second.
+func WithQps(qps int) Option {
+	return func(b *Boomer) { b.Qps = qps }
+}
+
+// WithTimeout sets the timeout of every request.
+func WithTimeout(d time.Duration) Option {
+	return func(b *Boomer) { b.Timeout = d }
+}
+
+// WithMethod sets the method of the request, GET by default.
+func WithMethod(method string) Option {
+	return func(b *Boomer) { b.Request.Header.SetMethod(method) }
+}
+
+// WithHeader sets a header of the request.
+func WithHeader(key, value string) Option {
+	return func(b *Boomer) { b.Request.Header.Set(key, value) }
+}
+
+// WithBody sets the body of the request.
+func WithBody(body []byte) Option {
+	return func(b *Boomer) { b.Request.SetBody(body) }
+}
+
+// WithRenderer sets the Renderer of the report.
+func WithRenderer(r Renderer) Option {
+	return func(b *Boomer) { b.Renderer = r }
+}
+
+// With applies an arbitrary change to the Boomer, for the settings
+// without a dedicated option.
+func With(f func(*Boomer)) Option {
+	return Option(f)
+}
+
+// New returns a Boomer sending GET requests to url, configured by opts,
+// once its configuration is validated.
+func New(url string, opts ...Option) (*Boomer, error) {
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(url)
+	b := &Boomer{Request: req, N: 200, C: 50}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Validate returns an error if the configuration of b is invalid or
+// conflicting.
+func (b *Boomer) Validate() error {
+	if b.Request == nil && len(b.Targets) == 0 {
+		return errors.New("no request to make")
+	}
+	for _, t := range b.targets() {
+		if t.Request == nil || len(t.Request.URI().Host()) == 0 && b.UnixSocket == "" {
+			return errors.New("requests need an absolute url")
+		}
+		for name := range t.Tags {
+			if ReservedTag(name) {
+				return fmt.Errorf("tag %q is the name of a built-in breakdown", name)
+			}
+		}
+	}
+	switch {
+	case b.N < 1 || b.C < 1:
+		return errors.New("N and C cannot be smaller than 1")
+	case b.Skip < 0:
+		return errors.New("Skip cannot be negative")
+	case len(b.Schedule) > 0 && b.Skip+b.N > len(b.Schedule):
+		return errors.New("Skip and N cannot exceed the length of Schedule")
+	case !validSchedule(b.Schedule, len(b.targets())):
+		return errors.New("Schedule refers to unknown targets")
+	case b.C > b.N:
+		return fmt.Errorf("C (%d) cannot be larger than N (%d)", b.C, b.N)
+	case b.Qps < 0 || b.Qps > maxQps:
+		return fmt.Errorf("Qps must be between 0 and %d", maxQps)
+	case b.Qps > 0 && math.Abs(float64(maxQps/(maxQps/b.Qps)-b.Qps)) > float64(b.Qps)/100:
+		return fmt.Errorf("Qps %d cannot be paced within 1%%, the closest rate is %d", b.Qps, maxQps/(maxQps/b.Qps))
+	case b.Client.MaxConnsPerHost < 0 || b.Client.DialTimeout < 0:
+		return errors.New("MaxConnsPerHost and DialTimeout cannot be negative")
+	case b.Client.IPVersion != 0 && b.Client.IPVersion != 4 && b.Client.IPVersion != 6:
+		return errors.New("IPVersion must be 4 or 6")
+	case b.Client.MaxBandwidth < 0 || b.Client.MaxConnBandwidth < 0:
+		return errors.New("MaxBandwidth and MaxConnBandwidth cannot be negative")
+	case b.Decompress && b.StreamBody:
+		return errors.New("Decompress and StreamBody cannot be used together")
+	case b.MaxBodySize < 0:
+		return errors.New("MaxBodySize cannot be negative")
+	case b.MaxBodySize > 0 && !b.StreamBody:
+		return errors.New("MaxBodySize requires StreamBody")
+	case b.Form != nil && b.Form.Stream && (b.Retries > 0 || b.Digest != nil):
+		return errors.New("a streamed Form cannot be used with Retries or Digest")
+	case b.RequestBody != nil && (b.Retries > 0 || b.Digest != nil):
+		return errors.New("RequestBody cannot be used with Retries or Digest")
+	case b.RequestBody != nil && b.Form != nil:
+		return errors.New("RequestBody and Form cannot be used together")
+	case b.ExpectContinue && (b.Doer != nil || b.StreamBody):
+		return errors.New("ExpectContinue cannot be used with a Doer or StreamBody")
+	case b.ExpectContinueTimeout < 0:
+		return errors.New("ExpectContinueTimeout cannot be negative")
+	case b.Pipeline < 0:
+		return errors.New("Pipeline cannot be negative")
+	case b.Pipeline > 0 && (b.Doer != nil || b.ExpectContinue || b.StreamBody || len(b.Certificates) > 1):
+		return errors.New("Pipeline cannot be used with a Doer, ExpectContinue, StreamBody or several Certificates")
+	case !validRotations(b.RotateHeaders, b.C):
+		return errors.New("RotateHeaders need a name and values, as many as C if partitioned")
+	case b.Burst < 0:
+		return errors.New("Burst cannot be negative")
+	case b.Burst > 0 && (b.BurstInterval <= 0 || b.Burst > b.C):
+		return errors.New("Burst requires a positive BurstInterval and cannot exceed C")
+	case b.Burst > 0 && (b.Qps > 0 || len(b.Schedule) > 0):
+		return errors.New("Burst cannot be used with Qps or Schedule")
+	case b.Spike != nil && (b.Qps > 0 || len(b.Schedule) > 0 || b.Burst > 0):
+		return errors.New("Spike cannot be used with Qps, Schedule or Burst")
+	case b.IntervalReport < 0:
+		return errors.New("IntervalReport cannot be negative")
+	case b.Timeout < 0:
+		return errors.New("Timeout cannot be negative")
+	case b.SlowestRequests < 0:
+		return errors.New("SlowestRequests cannot be negative")
+	case b.Retries < 0:
+		return errors.New("Retries cannot be negative")
+	case b.BadAuthRatio < 0 || b.BadAuthRatio > 1:
+		return errors.New("BadAuthRatio must be between 0 and 1")
+	case b.AbortErrorRate < 0 || b.AbortErrorRate > 1:
+		return errors.New("AbortErrorRate must be between 0 and 1")
+	case b.Guard != "" && b.Guard != GuardWarn && b.Guard != GuardAbort:
+		return fmt.Errorf("unknown Guard %q", b.Guard)
+	case b.AdaptiveErrorRate < 0 || b.AdaptiveErrorRate > 1:
+		return errors.New("AdaptiveErrorRate must be between 0 and 1")
+	case b.AdaptiveQps && b.Qps == 0:
+		return errors.New("AdaptiveQps requires Qps")
+	case !validPercentiles(b.Percentiles):
+		return errors.New("Percentiles must be between 1 and 99")
+	case b.SampleRate < 0 || b.SampleRate > 1:
+		return errors.New("SampleRate must be between 0 and 1")
+	case b.Sketch < 0 || b.Sketch >= 1:
+		return errors.New("Sketch must be between 0 and 1")
+	case b.ThinkTimeJitter > b.ThinkTime:
+		return errors.New("ThinkTimeJitter cannot exceed ThinkTime")
+	case b.Digest != nil && b.OAuth2 != nil:
+		return errors.New("Digest and OAuth2 cannot be used together")
+	case b.Doer != nil && (len(b.Certificates) > 0 || b.RootCAs != nil || b.ProxyAddr != nil || b.UnixSocket != "" || len(b.Resolve) > 0):
+		return errors.New("the TLS and dialing options are not used with a Doer")
+	}
+	if b.Spike != nil {
+		if err := b.Spike.Validate(); err != nil {
+			return err
+		}
+	}
+	if b.Form != nil {
+		return b.Form.Validate()
+	}
+	return nil
+}
+
+func validPercentiles(pctls []int) bool {
+	for _, p := range pctls {
+		if p < 1 || p > 99 {
+			return false
+		}
+	}
+	return true
+}
+
+func validSchedule(schedule []Dispatch, targets int) bool {
+	for _, d := range schedule {
+		if d.Target < 0 || d.Target >= targets {
+			return false
+		}
+	}
+	return true
+}
+
+func validRotations(rotations []HeaderRotation, workers int) bool {
+	for _, r := range rotations {
+		if r.Name == "" || len(r.Values) == 0 || r.Partition && len(r.Values) < workers {
+			return false
+		}
+	}
+	return true
+}
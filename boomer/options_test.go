@@ -0,0 +1,82 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	var method, header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, header = r.Method, r.Header.Get("X-Test")
+	}))
+	defer server.Close()
+
+	b, err := New(server.URL,
+		WithRequests(10),
+		WithConcurrency(2),
+		WithQps(100),
+		WithTimeout(time.Second),
+		WithMethod("PUT"),
+		WithHeader("X-Test", "yes"),
+		WithBody([]byte("body")),
+		WithRenderer(RendererFunc(func(io.Writer, *Report) error { return nil })),
+		With(func(b *Boomer) { b.Retries = 1 }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.N != 10 || b.C != 2 || b.Qps != 100 || b.Timeout != time.Second || b.Retries != 1 {
+		t.Errorf("Expected the options to be applied, found %+v", b)
+	}
+	rep := b.Run()
+	if rep.StatusCodeDist[http.StatusOK] != 10 || method != "PUT" || header != "yes" {
+		t.Errorf("Expected 10 PUT requests with the header, found %v, %s and %q", rep.StatusCodeDist, method, header)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := map[string][]Option{
+		"relative url":      nil,
+		"C larger than N":   {WithRequests(10), WithConcurrency(20)},
+		"no requests":       {WithRequests(0)},
+		"too fast":          {WithQps(2000000)},
+		"imprecise qps":     {WithQps(300000)},
+		"negative timeout":  {WithTimeout(-time.Second)},
+		"negative retries":  {With(func(b *Boomer) { b.Retries = -1 })},
+		"ratio above one":   {With(func(b *Boomer) { b.BadAuthRatio = 2 })},
+		"jitter too large":  {With(func(b *Boomer) { b.ThinkTime, b.ThinkTimeJitter = time.Second, 2*time.Second })},
+		"digest and oauth2": {With(func(b *Boomer) { b.Digest, b.OAuth2 = &DigestAuth{}, &OAuth2{} })},
+		"doer and proxy":    {With(func(b *Boomer) { b.Doer, b.UnixSocket = NetHTTPDoer{}, "/tmp/sock" })},
+		"negative conns":    {With(func(b *Boomer) { b.Client.MaxConnsPerHost = -1 })},
+	}
+	for name, opts := range invalid {
+		url := "http://localhost/"
+		if name == "relative url" {
+			url = "/path"
+		}
+		if _, err := New(url, opts...); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+	if _, err := New("http://localhost/", WithQps(3), WithRequests(1), WithConcurrency(1)); err != nil {
+		t.Errorf("Expected a valid configuration, found %v", err)
+	}
+}
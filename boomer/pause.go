@@ -0,0 +1,89 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pause stops sending new requests until Resume is called, the in-flight
+// ones being completed, e.g. while the target is investigated. The
+// statistics of the run are kept, and the time paused is left out of its
+// rate. It can be called from any goroutine, also before Run.
+func (b *Boomer) Pause() {
+	b.pause.pause()
+}
+
+// Resume resumes sending requests after Pause.
+func (b *Boomer) Resume() {
+	b.pause.resume()
+}
+
+// pauser holds the requests of a run while it is paused.
+type pauser struct {
+	mu      sync.Mutex
+	resumed chan struct{}
+	since   time.Time
+	elapsed time.Duration
+}
+
+func (p *pauser) pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed == nil {
+		p.resumed = make(chan struct{})
+		p.since = time.Now()
+	}
+}
+
+func (p *pauser) resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed != nil {
+		close(p.resumed)
+		p.resumed = nil
+		p.elapsed += time.Since(p.since)
+	}
+}
+
+// total returns the time spent paused so far.
+func (p *pauser) total() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed != nil {
+		return p.elapsed + time.Since(p.since)
+	}
+	return p.elapsed
+}
+
+// wait blocks while paused. It returns the time waited, and false if ctx
+// was done first.
+func (p *pauser) wait(ctx context.Context) (time.Duration, bool) {
+	p.mu.Lock()
+	resumed := p.resumed
+	p.mu.Unlock()
+	if resumed == nil {
+		return 0, true
+	}
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return time.Since(start), false
+	case <-resumed:
+		return time.Since(start), true
+	}
+}
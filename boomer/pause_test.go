@@ -0,0 +1,64 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPause(t *testing.T) {
+	var served int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&served, 1)
+	}))
+	defer server.Close()
+	boomer := &Boomer{
+		Request:  newGet(server.URL),
+		N:        100,
+		C:        2,
+		Qps:      500,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	reports := make(chan *Report)
+	go func() { reports <- boomer.Run() }()
+	for atomic.LoadInt64(&served) < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	boomer.Pause()
+	// The in-flight requests complete.
+	time.Sleep(50 * time.Millisecond)
+	held := atomic.LoadInt64(&served)
+	time.Sleep(200 * time.Millisecond)
+	if n := atomic.LoadInt64(&served); n != held {
+		t.Errorf("Expected no request while paused, found %d", n-held)
+	}
+	boomer.Resume()
+	rep := <-reports
+	if rep.Count != 100 {
+		t.Errorf("Expected the run to complete once resumed, found %d requests", rep.Count)
+	}
+	if rep.Paused < 250*time.Millisecond || rep.Paused > rep.Total {
+		t.Errorf("Expected the pause to be accounted, found %v of %v", rep.Paused, rep.Total)
+	}
+	// 100 requests at 500 qps take 200ms, the pause left out.
+	if rep.RPS < 300 {
+		t.Errorf("Expected the rate of the active time, found %v", rep.RPS)
+	}
+}
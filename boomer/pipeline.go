@@ -0,0 +1,79 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// pipelineDoer sends the requests of all the workers with a
+// fasthttp.PipelineClient per host, spreading them over enough
+// connections for every connection to carry Boomer.Pipeline requests at
+// once.
+type pipelineDoer struct {
+	b *Boomer
+
+	mu      sync.Mutex
+	clients map[string]*fasthttp.PipelineClient
+}
+
+func (b *Boomer) newPipelineDoer() *pipelineDoer {
+	return &pipelineDoer{b: b, clients: make(map[string]*fasthttp.PipelineClient)}
+}
+
+// Do implements Doer.
+func (d *pipelineDoer) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return d.client(req).Do(req, resp)
+}
+
+// DoTimeout implements Doer.
+func (d *pipelineDoer) DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error {
+	return d.client(req).DoTimeout(req, resp, timeout)
+}
+
+// client returns the client of the host of req.
+func (d *pipelineDoer) client(req *fasthttp.Request) *fasthttp.PipelineClient {
+	isTLS := string(req.URI().Scheme()) == "https"
+	addr := RequestAddr(req)
+	key := addr
+	if isTLS {
+		key = "https://" + addr
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c, ok := d.clients[key]
+	if !ok {
+		b := d.b
+		c = &fasthttp.PipelineClient{
+			Addr:      addr,
+			IsTLS:     isTLS,
+			TLSConfig: b.tlsConfig(b.Certificates),
+			Dial:      b.dial,
+			MaxConns:  (b.C + b.Pipeline - 1) / b.Pipeline,
+			// The requests are balanced over the connections, a pending
+			// request per worker keeps them from overflowing meanwhile.
+			MaxPendingRequests:            b.C,
+			ReadBufferSize:                b.Client.ReadBufferSize,
+			WriteBufferSize:               b.Client.WriteBufferSize,
+			MaxIdleConnDuration:           b.Client.MaxIdleConnDuration,
+			DisableHeaderNamesNormalizing: b.Client.DisableHeaderNamesNormalizing,
+		}
+		d.clients[key] = c
+	}
+	return c
+}
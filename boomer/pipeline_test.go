@@ -0,0 +1,45 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPipeline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+	}))
+	defer server.Close()
+
+	boomer := &Boomer{
+		Request:  newGet(server.URL),
+		N:        40,
+		C:        4,
+		Pipeline: 2,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.StatusCodeDist[http.StatusOK] != 40 {
+		t.Fatalf("Expected 40 responses, found %v %v", rep.StatusCodeDist, rep.ErrorDist)
+	}
+	if rep.Pipeline != 2 || rep.Connections.Dialed > 2 {
+		t.Errorf("Expected 2 requests pipelined on 2 connections at most, found %d on %d", rep.Pipeline, rep.Connections.Dialed)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+)
+
+// defaultPreflightTimeout bounds the TLS handshakes of Preflight unless
+// Client.DialTimeout or Timeout is set.
+const defaultPreflightTimeout = 10 * time.Second
+
+// Preflight connects to every target as the run would, through the
+// address overrides, the proxy or the unix socket, and completes the TLS
+// handshake of the https targets, without sending any request. It
+// returns the first error met. It does nothing if Doer is set.
+func (b *Boomer) Preflight() error {
+	if b.Doer != nil {
+		return nil
+	}
+	timeout := b.Client.DialTimeout
+	if timeout <= 0 {
+		timeout = b.Timeout
+	}
+	if timeout <= 0 {
+		timeout = defaultPreflightTimeout
+	}
+	seen := make(map[string]bool)
+	for _, t := range b.targets() {
+		addr := RequestAddr(t.Request)
+		https := string(t.Request.URI().Scheme()) == "https"
+		key := addr
+		if https {
+			key = "https://" + addr
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := b.preflight(addr, https, timeout); err != nil {
+			return fmt.Errorf("%s: %v", addr, err)
+		}
+	}
+	return nil
+}
+
+func (b *Boomer) preflight(addr string, https bool, timeout time.Duration) error {
+	conn, err := b.dialAddr(addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if !https {
+		return nil
+	}
+	cfg := b.tlsConfig(b.Certificates)
+	if cfg.ServerName == "" {
+		cfg.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	tc := tls.Client(conn, cfg)
+	tc.SetDeadline(time.Now().Add(timeout))
+	return tc.Handshake()
+}
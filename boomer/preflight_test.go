@@ -0,0 +1,54 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	var requests int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	b := &Boomer{Targets: []Target{{Request: newGet(server.URL)}, {Request: newGet(tlsServer.URL)}}}
+	err := b.Preflight()
+	if err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("Expected the untrusted certificate to fail, found %v", err)
+	}
+	b.AllowInsecure = true
+	if err := b.Preflight(); err != nil {
+		t.Errorf("Expected the targets to be reachable, found %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("Expected no request to be sent, found %d", n)
+	}
+
+	url := server.URL
+	server.Close()
+	b = &Boomer{Request: newGet(url)}
+	if err := b.Preflight(); err == nil {
+		t.Error("Expected the closed server to fail")
+	}
+}
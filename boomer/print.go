@@ -16,10 +16,14 @@ package boomer
 
 import (
 	"fmt"
-	"github.com/sschepens/gohistogram"
+	"io"
+	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/sschepens/gohistogram"
 )
 
 const (
@@ -30,33 +34,109 @@ type report struct {
 	avgTotal float64
 	fastest  float64
 	slowest  float64
-	average  float64
-	rps      float64
 
+	runID   string
 	results chan *result
 	start   time.Time
 	total   time.Duration
+	// end, if set, is the end of the last request, timing the run
+	// instead of the time of the report.
+	end time.Time
 
 	errorDist      map[string]int
+	errorCats      map[string]int
 	statusCodeDist map[int]int
+	forwardedDist  map[string]int
 	sizeTotal      int64
+	shed           int64
+	retries        Retries
+	breakdowns     breakdowns
+	serverTimings  serverTimings
+	series         []TimeSeriesPoint
+	seriesTotal    []time.Duration
+	heatmap        [][]uint64
+	detailed       bool
+	sampler        *sampler
+	paused         time.Duration
+	samples        []Sample
+	abort          *abortWindow
+	guard          *resourceGuard
+	stream         chan Result
+	tuning         *Tuning
+	throttling     *Throttling
+	percentiles    []int
+	slowRequests   *slowRequests
+	transfers      *transfers
+	compressions   *compressions
+	uploads        *uploads
+	interims       *interims
+	spikes         *spikeWindows
+	intervals      *intervalReporter
+	live           *liveStats
+	logf           func(format string, v ...interface{})
+
+	// interrupted is set if the run was stopped before its end, and
+	// abandoned if it did not wait for all the in-flight requests.
+	interrupted bool
+	abandoned   bool
+
+	drift         bool
+	batchSize     int
+	pipeline      int
+	burst         int
+	burstInterval time.Duration
+	maxIterations int
+	users         []VirtualUser
+	warm          bool
+	sent          int64
+	conns         *Connections
+	identities    map[string]*Identity
 
-	output string
+	renderer Renderer
+	// w receives the report, stdout if nil.
+	w io.Writer
 
-	wg    *sync.WaitGroup
+	wg *sync.WaitGroup
+
+	// histo is the response time histogram, and lats the sample of the
+	// latencies the percentiles are computed from.
 	histo *gohistogram.NumericHistogram
+	lats  latencySample
 }
 
-func newReport(size int, results chan *result, output string) *report {
+func newReport(size int, results chan *result, output string, renderer Renderer) *report {
+	if renderer == nil {
+		switch output {
+		case "csv":
+			renderer = RendererFunc(renderCSV)
+		case "heatmap":
+			renderer = RendererFunc(renderHeatmap)
+		case "heatmap-png":
+			renderer = RendererFunc(renderHeatmapPNG)
+		case "json":
+			renderer = JSONRenderer{}
+		case "wrk":
+			renderer = WrkRenderer{}
+		case "ab":
+			renderer = ABRenderer{}
+		default:
+			renderer = RendererFunc(renderSummary)
+		}
+	}
 	wg := &sync.WaitGroup{}
 	r := &report{
-		output:         output,
+		renderer:       renderer,
+		runID:          newUUID(),
 		results:        results,
 		start:          time.Now(),
 		statusCodeDist: make(map[int]int),
 		errorDist:      make(map[string]int),
+		errorCats:      make(map[string]int),
+		identities:     make(map[string]*Identity),
+		breakdowns:     make(breakdowns),
 		wg:             wg,
 		histo:          gohistogram.NewHistogram(10),
+		lats:           &reservoir{},
 	}
 	wg.Add(1)
 	go r.process()
@@ -65,8 +145,52 @@ func newReport(size int, results chan *result, output string) *report {
 
 func (r *report) process() {
 	for res := range r.results {
+		if res == nil {
+			break
+		}
+		keep := r.sampler.keep()
+		if r.stream != nil && keep {
+			r.stream <- res.export()
+		}
+		r.breakdowns.add(res)
+		if r.spikes != nil {
+			r.spikes.add(res)
+		}
+		if res.shed {
+			r.shed++
+			continue
+		}
+		r.addToSeries(res)
+		if r.intervals != nil {
+			r.intervals.add(res)
+		}
+		r.live.add(res)
+		if r.slowRequests != nil {
+			r.slowRequests.add(res)
+		}
+		if r.detailed && keep {
+			r.addSample(res)
+		}
+		r.users[res.user].Iterations++
+		r.sent += int64(res.attempts)
+		if res.attempts > 1 {
+			r.addRetries(res)
+		}
+		if res.identity != "" {
+			r.addIdentity(res)
+		}
+		if res.forwardedFor != "" {
+			if r.forwardedDist == nil {
+				r.forwardedDist = make(map[string]int)
+			}
+			r.forwardedDist[res.forwardedFor]++
+		}
 		if res.err != nil {
+			if _, seen := r.errorDist[res.err.Error()]; !seen && len(r.errorDist) < maxLoggedErrors && r.logf != nil {
+				r.logf("request error: %v", res.err)
+			}
 			r.errorDist[res.err.Error()]++
+			r.errorCats[errorCategory(res.err)]++
 		} else {
 			sec := res.duration.Seconds()
 			if r.slowest == 0 || sec > r.slowest {
@@ -76,74 +200,416 @@ func (r *report) process() {
 				r.fastest = sec
 			}
 			r.histo.Add(res.duration.Seconds())
+			r.lats.add(res.duration)
 			r.avgTotal += res.duration.Seconds()
+			r.serverTimings.add(res)
+			if r.transfers != nil {
+				r.transfers.add(res)
+			}
+			if r.compressions != nil {
+				r.compressions.add(res)
+			}
+			if r.uploads != nil {
+				r.uploads.add(res)
+			}
+			if r.interims != nil {
+				r.interims.add(res)
+			}
+			r.breakdowns.class(statusDimension, statusClass(res.statusCode)).add(res)
 			r.statusCodeDist[res.statusCode]++
 			if res.contentLength > 0 {
 				r.sizeTotal += int64(res.contentLength)
 			}
 		}
 	}
+	if r.stream != nil {
+		close(r.stream)
+	}
 	r.wg.Done()
 }
 
-func (r *report) finalize() {
+// addToSeries accounts res in the second of the run it was started in.
+// The seconds of the series are added along with the client stats, if
+// needed for the drift.
+func (r *report) addToSeries(res *result) {
+	var client ClientStats
+	i := 0
+	if !res.start.IsZero() && res.start.After(r.start) {
+		i = int(res.start.Sub(r.start) / time.Second)
+	}
+	if r.abort != nil {
+		r.abort.add(i, res)
+	}
+	if len(r.series) <= i && r.drift {
+		client = ReadClientStats()
+	}
+	for len(r.series) <= i {
+		r.series = append(r.series, TimeSeriesPoint{Offset: time.Duration(len(r.series)) * time.Second, Client: client})
+		r.seriesTotal = append(r.seriesTotal, 0)
+		r.heatmap = append(r.heatmap, nil)
+	}
+	if res.err != nil {
+		r.series[i].Errors++
+		return
+	}
+	r.series[i].Count++
+	r.seriesTotal[i] += res.duration
+	if r.heatmap[i] == nil {
+		r.heatmap[i] = make([]uint64, heatmapBuckets)
+	}
+	r.heatmap[i][heatmapBucket(res.duration)]++
+}
+
+func (r *report) addSample(res *result) {
+	s := Sample{
+		Time:       res.start,
+		Offset:     res.start.Sub(r.start),
+		Duration:   res.duration,
+		StatusCode: res.statusCode,
+		Bytes:      res.contentLength,
+		Tags:       res.tags,
+	}
+	if res.err != nil {
+		s.Err = res.err.Error()
+	}
+	r.samples = append(r.samples, s)
+}
+
+func (r *report) addRetries(res *result) {
+	r.retries.Requests++
+	r.retries.Attempts += int64(res.attempts - 1)
+	if res.err == nil && res.statusCode != 502 && res.statusCode != 503 {
+		r.retries.Recovered++
+	}
+}
+
+func (r *report) addIdentity(res *result) {
+	offset := res.start.Sub(r.start)
+	id, ok := r.identities[res.identity]
+	if !ok {
+		id = &Identity{Value: res.identity, FirstSeen: offset}
+		r.identities[res.identity] = id
+	}
+	if offset < id.FirstSeen {
+		id.FirstSeen = offset
+	}
+	if offset > id.LastSeen {
+		id.LastSeen = offset
+	}
+	id.Samples++
+}
+
+// finalize waits for all the results to be processed, renders the report
+// and returns it.
+func (r *report) finalize() *Report {
 	r.wg.Wait()
+	r.live.stop()
+	if r.intervals != nil {
+		r.intervals.stop()
+	}
+	if r.guard != nil {
+		r.guard.stop()
+	}
 	r.total = time.Now().Sub(r.start)
-	count := float64(r.histo.Count())
-	r.rps = count / r.total.Seconds()
-	r.average = r.avgTotal / count
-	r.print()
+	if !r.end.IsZero() {
+		r.total = r.end.Sub(r.start)
+	}
+	rep := r.build()
+	w := r.w
+	if w == nil {
+		w = os.Stdout
+	}
+	if err := r.renderer.Render(w, rep); err != nil {
+		fmt.Fprintf(os.Stderr, "could not render the report: %v\n", err)
+	}
+	return rep
 }
 
-func (r *report) print() {
-	if r.output == "csv" {
-		r.printCSV()
-		return
+// build snapshots the accumulated statistics into a Report.
+func (r *report) build() *Report {
+	count := int64(r.histo.Count())
+	rep := &Report{
+		RunID:           r.runID,
+		Total:           r.total,
+		Fastest:         secondsToDuration(r.fastest),
+		Slowest:         secondsToDuration(r.slowest),
+		Count:           count,
+		SizeTotal:       r.sizeTotal,
+		Shed:            r.shed,
+		Paused:          r.paused,
+		WarmConnections: r.warm,
+		MaxIterations:   r.maxIterations,
+		Detailed:        r.detailed,
+		SampleRate:      1,
+		Samples:         r.samples,
+		StatusCodeDist:  r.statusCodeDist,
+		ErrorDist:       r.errorDist,
+		ErrorCategories: r.errorCats,
+		ForwardedDist:   r.forwardedDist,
+		TimeSeries:      r.series,
+		Breakdowns:      r.breakdowns.build(r.percentiles),
+		ServerTiming:    r.serverTimings.build(),
 	}
-
-	if r.histo.Count() > 0 {
-		fmt.Printf("\nSummary:\n")
-		fmt.Printf("  Total:\t%4.4f secs.\n", r.total.Seconds())
-		fmt.Printf("  Slowest:\t%4.4f secs.\n", r.slowest)
-		fmt.Printf("  Fastest:\t%4.4f secs.\n", r.fastest)
-		fmt.Printf("  Average:\t%4.4f secs.\n", r.average)
-		fmt.Printf("  Requests/sec:\t%4.4f\n", r.rps)
-		if r.sizeTotal > 0 {
-			fmt.Printf("  Total Data Received:\t%d bytes.\n", r.sizeTotal)
-			fmt.Printf("  Response Size per Request:\t%d bytes.\n", r.sizeTotal/int64(r.histo.Count()))
+	for i := range r.users {
+		if n := r.users[i].Iterations; n > 0 {
+			r.users[i].Sessions = 1
+			if r.maxIterations > 0 {
+				r.users[i].Sessions = (n + int64(r.maxIterations) - 1) / int64(r.maxIterations)
+			}
 		}
-		r.printStatusCodes()
-		r.printHistogram()
-		r.printLatencies()
 	}
-
-	if len(r.errorDist) > 0 {
-		r.printErrors()
+	rep.VirtualUsers = r.users
+	if r.sampler != nil {
+		rep.SampleRate = r.sampler.rate
+	}
+	if len(r.heatmap) > 0 {
+		rep.Heatmap = &Heatmap{Bounds: heatmapBounds(), Counts: r.heatmap}
+	}
+	if r.abort != nil {
+		rep.Aborted = r.abort.reason
+	}
+	if r.guard != nil {
+		sat := r.guard.sat
+		rep.Saturation = &sat
+		if rep.Aborted == "" {
+			rep.Aborted = r.guard.reason
+		}
+	}
+	if rep.Aborted == "" && r.interrupted {
+		rep.Aborted = "interrupted"
+		if r.abandoned {
+			rep.Aborted += ", the requests still in flight are left out"
+		}
+	}
+	if r.slowRequests != nil {
+		rep.SlowestRequests = r.slowRequests.build()
+	}
+	if r.transfers != nil {
+		rep.Transfer = r.transfers.build(r.percentiles)
+	}
+	if r.compressions != nil {
+		rep.Compression = r.compressions.build()
+	}
+	if r.uploads != nil {
+		rep.Upload = r.uploads.build()
+	}
+	if r.interims != nil {
+		rep.Continue = r.interims.build(r.percentiles)
+	}
+	if r.spikes != nil {
+		rep.Spike = r.spikes.build(r.total, r.percentiles)
+	}
+	if r.conns != nil {
+		rep.Connections = r.connections()
+	}
+	rep.Tuning = r.tuning
+	rep.Throttling = r.throttling
+	if r.retries.Requests > 0 {
+		retries := r.retries
+		rep.Retries = &retries
+	}
+	for i := range rep.TimeSeries {
+		if n := rep.TimeSeries[i].Count; n > 0 {
+			rep.TimeSeries[i].Average = r.seriesTotal[i] / time.Duration(n)
+		}
+	}
+	if r.drift {
+		rep.Drift = computeDrift(rep.TimeSeries, r.identities)
 	}
+	if count == 0 {
+		return rep
+	}
+	rep.RPS = float64(count) / (r.total - r.paused).Seconds()
+	rep.Average = secondsToDuration(r.avgTotal / float64(count))
+	rep.Pipeline = r.pipeline
+	rep.Burst, rep.BurstInterval = r.burst, r.burstInterval
+	if r.batchSize > 1 {
+		rep.BatchSize = r.batchSize
+		rep.OpsPerSec = rep.RPS * float64(r.batchSize)
+		rep.AveragePerOp = rep.Average / time.Duration(r.batchSize)
+	}
+	for _, b := range r.histo.Bins() {
+		rep.Histogram = append(rep.Histogram, Bucket{
+			Mark:  secondsToDuration(b.Value),
+			Count: b.Count,
+		})
+	}
+	rep.Latencies = quantiles(r.lats, r.percentiles)
+	if s, ok := r.lats.(sketchSample); ok {
+		rep.Sketch = s.Sketch
+	}
+	return rep
 }
 
-func (r *report) printCSV() {
-	//for i, val := range r.lats {
-	//	fmt.Printf("%v,%4.4f\n", i+1, val)
-	//}
+// connections returns the statistics of the connections of the run.
+func (r *report) connections() *Connections {
+	c := *r.conns
+	if sec := (r.total - r.paused).Seconds(); sec > 0 {
+		c.DialsPerSec = float64(c.Dialed) / sec
+	}
+	// Requests are assumed to be sent on the new connections first.
+	if c.Dialed < r.sent {
+		c.ReuseRate = 1 - float64(c.Dialed)/float64(r.sent)
+	}
+	return &c
 }
 
-// Prints percentile latencies.
-func (r *report) printLatencies() {
-	pctls := []int{10, 25, 50, 75, 90, 95, 99}
-	fmt.Printf("\nLatency distribution:\n")
+// DefaultPercentiles are the latency percentiles of the report, unless
+// Boomer.Percentiles is set.
+var DefaultPercentiles = []int{10, 25, 50, 75, 90, 95, 99}
+
+// quantiles returns the latency percentiles pctls of the sample s.
+func quantiles(s latencySample, pctls []int) []LatencyDistribution {
+	var lats []LatencyDistribution
+	if pctls == nil {
+		pctls = DefaultPercentiles
+	}
 	cent := float64(100)
 	for _, p := range pctls {
-		q := r.histo.Quantile(float64(p) / cent)
+		q := s.quantile(float64(p) / cent)
 		if q > 0 {
-			fmt.Printf("  %v%% in %4.4f secs.\n", p, q)
+			lats = append(lats, LatencyDistribution{
+				Percentage: p,
+				Latency:    q,
+			})
+		}
+	}
+	return lats
+}
+
+func secondsToDuration(sec float64) time.Duration {
+	return time.Duration(sec * float64(time.Second))
+}
+
+// renderSummary is the default, human readable, Renderer.
+func renderSummary(w io.Writer, r *Report) error {
+	if r.Aborted != "" {
+		fmt.Fprintf(w, "\nAborted: %s.\n", r.Aborted)
+	}
+	if r.Count > 0 {
+		fmt.Fprintf(w, "\nSummary:\n")
+		fmt.Fprintf(w, "  Run ID:\t%s\n", r.RunID)
+		fmt.Fprintf(w, "  Total:\t%s.\n", formatSeconds(r.Total.Seconds()))
+		fmt.Fprintf(w, "  Slowest:\t%s.\n", formatSeconds(r.Slowest.Seconds()))
+		fmt.Fprintf(w, "  Fastest:\t%s.\n", formatSeconds(r.Fastest.Seconds()))
+		fmt.Fprintf(w, "  Average:\t%s.\n", formatSeconds(r.Average.Seconds()))
+		fmt.Fprintf(w, "  Requests:\t%s\n", formatCount(float64(r.Count)))
+		fmt.Fprintf(w, "  Requests/sec:\t%s\n", formatCount(r.RPS))
+		if r.BatchSize > 1 {
+			fmt.Fprintf(w, "  Operations/request:\t%d\n", r.BatchSize)
+			fmt.Fprintf(w, "  Operations/sec:\t%s\n", formatCount(r.OpsPerSec))
+			fmt.Fprintf(w, "  Average per operation:\t%s.\n", formatSeconds(r.AveragePerOp.Seconds()))
+		}
+		if r.SizeTotal > 0 {
+			fmt.Fprintf(w, "  Total Data Received:\t%s.\n", formatBytes(r.SizeTotal))
+			fmt.Fprintf(w, "  Response Size per Request:\t%s.\n", formatBytes(r.SizeTotal/r.Count))
+		}
+		if r.WarmConnections {
+			fmt.Fprintf(w, "  Connections:\treused from a previous run\n")
 		}
+		if c := r.Connections; c != nil {
+			fmt.Fprintf(w, "  New Connections:\t%s (%s/sec)\n", formatCount(float64(c.Dialed)), formatCount(c.DialsPerSec))
+			fmt.Fprintf(w, "  Connection Reuse:\t%.1f%%\n", c.ReuseRate*100)
+			fmt.Fprintf(w, "  Peak Open Connections:\t%s\n", formatCount(float64(c.Peak)))
+			if c.IPv4 > 0 || c.IPv6 > 0 {
+				fmt.Fprintf(w, "  Address Families:\tIPv4 %s, IPv6 %s\n", formatCount(float64(c.IPv4)), formatCount(float64(c.IPv6)))
+			}
+		}
+		if r.Burst > 0 {
+			fmt.Fprintf(w, "  Bursts:\t%d requests every %s\n", r.Burst, r.BurstInterval)
+		}
+		if r.Pipeline > 0 {
+			fmt.Fprintf(w, "  Pipelined Requests:\t%d per connection\n", r.Pipeline)
+		}
+		if r.Shed > 0 {
+			fmt.Fprintf(w, "  Shed Requests:\t%s\n", formatCount(float64(r.Shed)))
+		}
+		if r.Paused > 0 {
+			fmt.Fprintf(w, "  Paused:\t%s, left out of the rate\n", formatSeconds(r.Paused.Seconds()))
+		}
+		if r.MaxIterations > 0 {
+			printVirtualUsers(w, r.VirtualUsers)
+		}
+		if r.Retries != nil {
+			fmt.Fprintf(w, "  Retried Requests:\t%s (%s retries, %s recovered)\n", formatCount(float64(r.Retries.Requests)),
+				formatCount(float64(r.Retries.Attempts)), formatCount(float64(r.Retries.Recovered)))
+		}
+		if len(r.ForwardedDist) > 0 {
+			fmt.Fprintf(w, "  Simulated Client Addresses:\t%s\n", formatCount(float64(len(r.ForwardedDist))))
+		}
+		printStatusCodes(w, r)
+		printHistogram(w, r)
+		printLatencies(w, r)
+		if r.Detailed {
+			printTimeSeries(w, r)
+		}
+	}
+
+	if r.Transfer != nil && r.Count > 0 {
+		printTransfer(w, r.Transfer)
+	}
+
+	if r.Compression != nil && r.Count > 0 {
+		printCompression(w, r.Compression)
+	}
+
+	if r.Upload != nil && r.Count > 0 {
+		printUpload(w, r.Upload)
+	}
+
+	if r.Continue != nil && r.Count > 0 {
+		printContinue(w, r.Continue)
 	}
+
+	if r.ServerTiming != nil {
+		printServerTiming(w, r.ServerTiming)
+	}
+
+	if r.Tuning != nil {
+		printTuning(w, r.Tuning)
+	}
+
+	if r.Throttling != nil {
+		printThrottling(w, r.Throttling)
+	}
+
+	if r.Saturation != nil {
+		printSaturation(w, r.Saturation)
+	}
+
+	if len(r.Spike) > 0 {
+		printSpike(w, r.Spike)
+	}
+
+	if len(r.Breakdowns) > 0 {
+		printBreakdowns(w, r.Breakdowns)
+	}
+
+	if len(r.ErrorDist) > 0 {
+		printErrors(w, r)
+	}
+
+	if len(r.SlowestRequests) > 0 {
+		printSlowest(w, r.SlowestRequests)
+	}
+
+	if r.Drift != nil {
+		printDrift(w, r.Drift)
+	}
+	return nil
 }
 
-func (r *report) printHistogram() {
-	fmt.Printf("\nResponse time histogram:\n")
-	bins := r.histo.Bins()
+// Prints percentile latencies.
+func printLatencies(w io.Writer, r *Report) {
+	fmt.Fprintf(w, "\nLatency distribution:\n")
+	for _, l := range r.Latencies {
+		fmt.Fprintf(w, "  %v%% in %s.\n", l.Percentage, formatSeconds(l.Latency.Seconds()))
+	}
+}
+
+func printHistogram(w io.Writer, r *Report) {
+	fmt.Fprintf(w, "\nResponse time histogram:\n")
+	bins := r.Histogram
 	max := bins[0].Count
 	for i := 1; i < len(bins); i++ {
 		if bins[i].Count > max {
@@ -156,21 +622,80 @@ func (r *report) printHistogram() {
 		if max > 0 {
 			barLen = bins[i].Count * 40 / max
 		}
-		fmt.Printf("  %4.3f [%v]\t|%v\n", bins[i].Value, bins[i].Count, strings.Repeat(barChar, int(barLen)))
+		fmt.Fprintf(w, "  %s [%s]\t|%v\n", formatSeconds(bins[i].Mark.Seconds()), formatCount(float64(bins[i].Count)), strings.Repeat(barChar, int(barLen)))
 	}
 }
 
 // Prints status code distribution.
-func (r *report) printStatusCodes() {
-	fmt.Printf("\nStatus code distribution:\n")
-	for code, num := range r.statusCodeDist {
-		fmt.Printf("  [%d]\t%d responses\n", code, num)
+func printStatusCodes(w io.Writer, r *Report) {
+	fmt.Fprintf(w, "\nStatus code distribution:\n")
+	for code, num := range r.StatusCodeDist {
+		fmt.Fprintf(w, "  [%d]\t%s responses\n", code, formatCount(float64(num)))
+	}
+}
+
+func printErrors(w io.Writer, r *Report) {
+	if len(r.ErrorCategories) > 0 {
+		fmt.Fprintf(w, "\nError categories:\n")
+		cats := make([]string, 0, len(r.ErrorCategories))
+		for cat := range r.ErrorCategories {
+			cats = append(cats, cat)
+		}
+		sort.Strings(cats)
+		for _, cat := range cats {
+			fmt.Fprintf(w, "  [%s]\t%s", formatCount(float64(r.ErrorCategories[cat])), cat)
+			if generatorErrors[cat] {
+				fmt.Fprintf(w, " (load generator)")
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintf(w, "\nError distribution:\n")
+	for err, num := range r.ErrorDist {
+		fmt.Fprintf(w, "  [%s]\t%s\n", formatCount(float64(num)), err)
+	}
+}
+
+// formatSeconds renders a duration given in seconds using the most
+// readable unit, from microseconds up to seconds.
+func formatSeconds(sec float64) string {
+	switch {
+	case sec < 0:
+		return fmt.Sprintf("%4.4f secs", sec)
+	case sec < 1e-3:
+		return fmt.Sprintf("%4.1f µs", sec*1e6)
+	case sec < 1:
+		return fmt.Sprintf("%4.3f ms", sec*1e3)
 	}
+	return fmt.Sprintf("%4.4f secs", sec)
 }
 
-func (r *report) printErrors() {
-	fmt.Printf("\nError distribution:\n")
-	for err, num := range r.errorDist {
-		fmt.Printf("  [%d]\t%s\n", num, err)
+// formatCount renders large counts with a k/M/G suffix so that huge runs
+// stay readable. Values under ten thousand are printed as is.
+func formatCount(n float64) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.2fG", n/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.2fM", n/1e6)
+	case n >= 1e4:
+		return fmt.Sprintf("%.1fk", n/1e3)
+	case n == float64(int64(n)):
+		return fmt.Sprintf("%d", int64(n))
+	}
+	return fmt.Sprintf("%4.4f", n)
+}
+
+// formatBytes renders a byte count using binary multiples.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d bytes", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
 	}
+	return fmt.Sprintf("%.2f %cB", float64(n)/float64(div), "KMGT"[exp])
 }
@@ -0,0 +1,62 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"testing"
+)
+
+func TestFormatSeconds(t *testing.T) {
+	cases := map[float64]string{
+		0.0000125: "12.5 µs",
+		0.0125:    "12.500 ms",
+		12.5:      "12.5000 secs",
+	}
+	for in, want := range cases {
+		if got := formatSeconds(in); got != want {
+			t.Errorf("formatSeconds(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	cases := map[float64]string{
+		200:        "200",
+		12.5:       "12.5000",
+		25000:      "25.0k",
+		3500000:    "3.50M",
+		7200000000: "7.20G",
+	}
+	for in, want := range cases {
+		if got := formatCount(in); got != want {
+			t.Errorf("formatCount(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		512:            "512 bytes",
+		2048:           "2.00 KB",
+		5 << 20:        "5.00 MB",
+		3 << 30:        "3.00 GB",
+		(1 << 40) * 10: "10.00 TB",
+	}
+	for in, want := range cases {
+		if got := formatBytes(in); got != want {
+			t.Errorf("formatBytes(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
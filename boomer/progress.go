@@ -0,0 +1,37 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sschepens/pb"
+)
+
+// plainProgress makes bar print a line to w every second instead of
+// redrawing itself, for the terminals not handling the carriage returns
+// and the files to keep the progress of the run.
+func plainProgress(bar *pb.ProgressBar, w io.Writer) {
+	bar.Output = nil
+	bar.NotPrint = true
+	bar.ShowBar = false
+	bar.SetRefreshRate(time.Second)
+	bar.Callback = func(s string) {
+		fmt.Fprintln(w, strings.TrimSpace(s))
+	}
+}
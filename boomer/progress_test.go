@@ -0,0 +1,40 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sschepens/pb"
+)
+
+func TestPlainProgress(t *testing.T) {
+	var buf bytes.Buffer
+	bar := pb.New(10)
+	plainProgress(bar, &buf)
+	bar.Set(4)
+	bar.Update()
+	bar.Set(5)
+	bar.Update()
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 3 || lines[2] != "" {
+		t.Fatalf("Expected a line per update, found %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "\r") || !strings.HasPrefix(lines[1], "5 / 10") {
+		t.Errorf("Expected plain progress lines, found %q", buf.String())
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"os"
+	"syscall"
+)
+
+// enableVirtualTerminalProcessing is the console mode of the terminals
+// interpreting the control sequences.
+const enableVirtualTerminalProcessing = 0x4
+
+// plainTerminal reports whether stderr should get the plain progress.
+// The legacy consoles wrap the bar filling their width, printing a new
+// one on every refresh, and stderr is no console at all in the terminals
+// of the Unix-like shells or once redirected.
+func plainTerminal() bool {
+	var mode uint32
+	if err := syscall.GetConsoleMode(syscall.Handle(os.Stderr.Fd()), &mode); err != nil {
+		return true
+	}
+	return mode&enableVirtualTerminalProcessing == 0
+}
@@ -0,0 +1,199 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// dialProxy opens a tunnel to addr through the proxy in b.ProxyAddr.
+// HTTP proxies are asked to CONNECT, SOCKS5 proxies are supported with
+// the socks5 and socks5h schemes. Credentials in the proxy URL are sent
+// as Proxy-Authorization or with the SOCKS5 username/password method.
+func (b *Boomer) dialProxy(addr string) (net.Conn, error) {
+	p := b.ProxyAddr
+	var connect func(net.Conn, string, *url.Userinfo) error
+	port := "80"
+	switch p.Scheme {
+	case "", "http":
+		connect = httpConnect
+	case "socks5", "socks5h":
+		connect = socks5Connect
+		port = "1080"
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", p.Scheme)
+	}
+	host := p.Host
+	if p.Port() == "" {
+		host = net.JoinHostPort(p.Hostname(), port)
+	}
+	conn, err := b.dialTCP(host)
+	if err != nil {
+		return nil, err
+	}
+	if err := connect(conn, addr, p.User); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// httpConnect asks the HTTP proxy on conn to open a tunnel to addr.
+func httpConnect(conn net.Conn, addr string, user *url.Userinfo) error {
+	auth := ""
+	if user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		auth = "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+	_, err := fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n%s\r\n", addr, addr, auth)
+	if err != nil {
+		return err
+	}
+	// The response is read byte by byte so that nothing sent through the
+	// tunnel afterwards is consumed.
+	var head []byte
+	b := make([]byte, 1)
+	for !bytes.HasSuffix(head, []byte("\r\n\r\n")) {
+		if _, err := conn.Read(b); err != nil {
+			return fmt.Errorf("proxy: %v", err)
+		}
+		head = append(head, b[0])
+		if len(head) > 8192 {
+			return fmt.Errorf("proxy: response header too large")
+		}
+	}
+	line := head[:bytes.IndexByte(head, '\n')]
+	fields := bytes.Fields(line)
+	if len(fields) < 2 || !bytes.HasPrefix(fields[0], []byte("HTTP/")) {
+		return fmt.Errorf("proxy: malformed response %q", line)
+	}
+	if !bytes.Equal(fields[1], []byte("200")) {
+		return fmt.Errorf("proxy: CONNECT %s failed: %s", addr, bytes.TrimSpace(line))
+	}
+	return nil
+}
+
+// socks5Connect asks the SOCKS5 proxy on conn to open a tunnel to addr.
+func socks5Connect(conn net.Conn, addr string, user *url.Userinfo) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return err
+	}
+
+	method := byte(0)
+	if user != nil {
+		method = 2
+	}
+	if _, err := conn.Write([]byte{5, 1, method}); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("socks5: %v", err)
+	}
+	if reply[0] != 5 || reply[1] != method {
+		return fmt.Errorf("socks5: no acceptable authentication method")
+	}
+	if user != nil {
+		if err := socks5Authenticate(conn, user); err != nil {
+			return err
+		}
+	}
+
+	req := []byte{5, 1, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("socks5: host name too long")
+		}
+		req = append(req, 3, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, 1)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, 4)
+		req = append(req, ip.To16()...)
+	}
+	req = append(req, 0, 0)
+	binary.BigEndian.PutUint16(req[len(req)-2:], uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return fmt.Errorf("socks5: %v", err)
+	}
+	if head[1] != 0 {
+		return fmt.Errorf("socks5: connect to %s failed with code %d", addr, head[1])
+	}
+	var skip int
+	switch head[3] {
+	case 1:
+		skip = net.IPv4len
+	case 4:
+		skip = net.IPv6len
+	case 3:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return fmt.Errorf("socks5: %v", err)
+		}
+		skip = int(l[0])
+	default:
+		return fmt.Errorf("socks5: unknown address type %d", head[3])
+	}
+	// Skip the bound address and port.
+	if _, err := io.ReadFull(conn, make([]byte, skip+2)); err != nil {
+		return fmt.Errorf("socks5: %v", err)
+	}
+	return nil
+}
+
+// socks5Authenticate performs the RFC 1929 username/password
+// authentication.
+func socks5Authenticate(conn net.Conn, user *url.Userinfo) error {
+	name := user.Username()
+	password, _ := user.Password()
+	if len(name) > 255 || len(password) > 255 {
+		return fmt.Errorf("socks5: credentials too long")
+	}
+	req := []byte{1, byte(len(name))}
+	req = append(req, name...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("socks5: %v", err)
+	}
+	if reply[1] != 0 {
+		return fmt.Errorf("socks5: authentication failed")
+	}
+	return nil
+}
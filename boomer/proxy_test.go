@@ -0,0 +1,209 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// testProxy is a minimal HTTP CONNECT or SOCKS5 proxy counting the
+// tunnels it opens.
+type testProxy struct {
+	l       net.Listener
+	socks   bool
+	tunnels int64
+
+	// user and password are the credentials required, if any.
+	user, password string
+}
+
+func newTestProxy(t *testing.T, socks bool) *testProxy {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProxy{l: l, socks: socks}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	return p
+}
+
+func (p *testProxy) Close() { p.l.Close() }
+
+func (p *testProxy) serve(conn net.Conn) {
+	defer conn.Close()
+	var target string
+	if p.socks {
+		target = p.socksHandshake(conn)
+	} else {
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil || req.Method != "CONNECT" {
+			io.WriteString(conn, "HTTP/1.1 405 Method Not Allowed\r\n\r\n")
+			return
+		}
+		if p.user != "" {
+			want := "Basic " + base64.StdEncoding.EncodeToString([]byte(p.user+":"+p.password))
+			if req.Header.Get("Proxy-Authorization") != want {
+				io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+				return
+			}
+		}
+		target = req.Host
+	}
+	if target == "" {
+		return
+	}
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+	atomic.AddInt64(&p.tunnels, 1)
+	if !p.socks {
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	}
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func (p *testProxy) socksHandshake(conn net.Conn) string {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return ""
+	}
+	io.ReadFull(conn, make([]byte, head[1]))
+	if p.user == "" {
+		conn.Write([]byte{5, 0})
+	} else {
+		conn.Write([]byte{5, 2})
+		l := make([]byte, 2)
+		io.ReadFull(conn, l)
+		user := make([]byte, l[1])
+		io.ReadFull(conn, user)
+		io.ReadFull(conn, l[:1])
+		password := make([]byte, l[0])
+		io.ReadFull(conn, password)
+		if string(user) != p.user || string(password) != p.password {
+			conn.Write([]byte{1, 1})
+			return ""
+		}
+		conn.Write([]byte{1, 0})
+	}
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return ""
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 3:
+		l := make([]byte, 1)
+		io.ReadFull(conn, l)
+		name := make([]byte, l[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		return ""
+	}
+	port := make([]byte, 2)
+	io.ReadFull(conn, port)
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+}
+
+func TestProxies(t *testing.T) {
+	var count int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	for _, scheme := range []string{"http", "socks5"} {
+		proxy := newTestProxy(t, scheme == "socks5")
+		defer proxy.Close()
+
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(server.URL)
+		req.Header.SetMethod("GET")
+		boomer := &Boomer{
+			Request:   req,
+			N:         10,
+			C:         2,
+			ProxyAddr: &url.URL{Scheme: scheme, Host: proxy.l.Addr().String()},
+			Renderer:  RendererFunc(func(io.Writer, *Report) error { return nil }),
+		}
+		atomic.StoreInt64(&count, 0)
+		rep := boomer.Run()
+		if rep.Count != 10 || count != 10 {
+			t.Errorf("%s: expected to boom 10 times, found %v: %v", scheme, count, rep.ErrorDist)
+		}
+		if atomic.LoadInt64(&proxy.tunnels) == 0 {
+			t.Errorf("%s: expected the requests to go through the proxy", scheme)
+		}
+	}
+}
+
+func TestProxyAuthentication(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	for _, scheme := range []string{"http", "socks5"} {
+		proxy := newTestProxy(t, scheme == "socks5")
+		proxy.user, proxy.password = "user", "secret"
+		defer proxy.Close()
+
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(server.URL)
+		req.Header.SetMethod("GET")
+		boomer := &Boomer{
+			Request:   req,
+			N:         5,
+			C:         1,
+			ProxyAddr: &url.URL{Scheme: scheme, Host: proxy.l.Addr().String(), User: url.UserPassword("user", "secret")},
+			Renderer:  RendererFunc(func(io.Writer, *Report) error { return nil }),
+		}
+		if rep := boomer.Run(); rep.Count != 5 {
+			t.Errorf("%s: expected to authenticate to the proxy, found errors %v", scheme, rep.ErrorDist)
+		}
+
+		boomer.ProxyAddr.User = url.UserPassword("user", "wrong")
+		if rep := boomer.Run(); rep.Count != 0 {
+			t.Errorf("%s: expected wrong credentials to be rejected", scheme)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// recordMagic starts the files written by ResultWriter, followed by the
+// version of the format. The results of version 1 have no labels.
+const (
+	recordMagic   = "PLA\x02"
+	recordMagicV1 = "PLA\x01"
+)
+
+const (
+	recordShed = 1 << iota
+	recordErr
+)
+
+// ResultWriter writes results in a compact binary format, read back by
+// ResultReader. Every result is a sequence of varints: its start relative
+// to the previous one in nanoseconds, its duration, status code, content
+// length, attempts and flags, followed by the length and text of its
+// error, if any, and by its labels. The labels are written once per
+// distinct set, as its negated number followed by the count of its labels
+// and their names, values and kinds, and referred to by their number
+// afterwards, zero being no labels.
+type ResultWriter struct {
+	w       *bufio.Writer
+	buf     [binary.MaxVarintLen64]byte
+	last    time.Time
+	started bool
+	v1      bool
+	sets    map[string]int64
+}
+
+// NewResultWriter returns a ResultWriter writing to w. Flush must be
+// called once done.
+func NewResultWriter(w io.Writer) *ResultWriter {
+	return &ResultWriter{w: bufio.NewWriter(w), sets: make(map[string]int64)}
+}
+
+// AppendResultWriter returns a ResultWriter writing to w at the end of
+// the results file read by r, in its version of the format. Flush must be
+// called once done.
+func AppendResultWriter(w io.Writer, r *ResultReader) *ResultWriter {
+	return &ResultWriter{
+		w:       bufio.NewWriter(w),
+		last:    r.last,
+		started: true,
+		v1:      r.v1,
+		sets:    make(map[string]int64),
+	}
+}
+
+// The kinds of the labels of a result, a tag being both.
+const (
+	recordLabel = 1 << iota
+	recordTag
+)
+
+// Write writes res.
+func (rw *ResultWriter) Write(res Result) error {
+	if err := rw.start(); err != nil {
+		return err
+	}
+	var flags int64
+	if res.Shed {
+		flags |= recordShed
+	}
+	if res.Err != nil {
+		flags |= recordErr
+	}
+	delta := res.Start.UnixNano()
+	if !rw.last.IsZero() {
+		delta = res.Start.Sub(rw.last).Nanoseconds()
+	}
+	rw.last = res.Start
+	for _, v := range []int64{delta, int64(res.Duration), int64(res.StatusCode), int64(res.ContentLength), int64(res.Attempts), flags} {
+		if err := rw.varint(v); err != nil {
+			return err
+		}
+	}
+	if res.Err != nil {
+		if err := rw.string(res.Err.Error()); err != nil {
+			return err
+		}
+	}
+	if rw.v1 {
+		return nil
+	}
+	return rw.labels(res)
+}
+
+// labels writes the labels and tags of res.
+func (rw *ResultWriter) labels(res Result) error {
+	kinds := make(map[string]int64, len(res.Labels))
+	for name := range res.Labels {
+		kinds[name] |= recordLabel
+	}
+	for name := range res.Tags {
+		kinds[name] |= recordTag
+	}
+	if len(kinds) == 0 {
+		return rw.varint(0)
+	}
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var key strings.Builder
+	for _, name := range names {
+		value := res.Labels[name]
+		if kinds[name]&recordLabel == 0 {
+			value = res.Tags[name]
+		}
+		fmt.Fprintf(&key, "%q=%q%d;", name, value, kinds[name])
+	}
+	if id, ok := rw.sets[key.String()]; ok {
+		return rw.varint(id)
+	}
+	id := int64(len(rw.sets) + 1)
+	rw.sets[key.String()] = id
+	if err := rw.varint(-id); err != nil {
+		return err
+	}
+	if err := rw.varint(int64(len(names))); err != nil {
+		return err
+	}
+	for _, name := range names {
+		value := res.Labels[name]
+		if kinds[name]&recordLabel == 0 {
+			value = res.Tags[name]
+		}
+		if err := rw.string(name); err != nil {
+			return err
+		}
+		if err := rw.string(value); err != nil {
+			return err
+		}
+		if err := rw.varint(kinds[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// string writes the length and text of s.
+func (rw *ResultWriter) string(s string) error {
+	if err := rw.varint(int64(len(s))); err != nil {
+		return err
+	}
+	_, err := rw.w.WriteString(s)
+	return err
+}
+
+// start writes the magic of the format, once.
+func (rw *ResultWriter) start() error {
+	if rw.started {
+		return nil
+	}
+	rw.started = true
+	_, err := rw.w.WriteString(recordMagic)
+	return err
+}
+
+func (rw *ResultWriter) varint(v int64) error {
+	n := binary.PutVarint(rw.buf[:], v)
+	_, err := rw.w.Write(rw.buf[:n])
+	return err
+}
+
+// Flush writes the buffered results.
+func (rw *ResultWriter) Flush() error {
+	if err := rw.start(); err != nil {
+		return err
+	}
+	return rw.w.Flush()
+}
+
+// ResultReader reads the results written by ResultWriter.
+type ResultReader struct {
+	r       *countingReader
+	last    time.Time
+	started bool
+	v1      bool
+	offset  int64
+	sets    map[int64]labelSet
+}
+
+// labelSet holds the labels and tags shared by results.
+type labelSet struct {
+	labels map[string]string
+	tags   map[string]string
+}
+
+// NewResultReader returns a ResultReader reading from r.
+func NewResultReader(r io.Reader) *ResultReader {
+	return &ResultReader{r: &countingReader{r: bufio.NewReader(r)}, sets: make(map[int64]labelSet)}
+}
+
+// Offset returns the size of the results read so far, for a file whose
+// last result was truncated to be cut after the complete ones.
+func (rr *ResultReader) Offset() int64 {
+	return rr.offset
+}
+
+// Last returns the start of the last result read.
+func (rr *ResultReader) Last() time.Time {
+	return rr.last
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// Read returns the next result, or io.EOF once all were read.
+func (rr *ResultReader) Read() (Result, error) {
+	if !rr.started {
+		magic := make([]byte, len(recordMagic))
+		if _, err := io.ReadFull(rr.r, magic); err != nil || string(magic) != recordMagic && string(magic) != recordMagicV1 {
+			return Result{}, errors.New("not a pla results file")
+		}
+		rr.started, rr.v1 = true, string(magic) == recordMagicV1
+		rr.offset = rr.r.n
+	}
+	var v [6]int64
+	for i := range v {
+		var err error
+		if v[i], err = binary.ReadVarint(rr.r); err != nil {
+			if i == 0 && err == io.EOF {
+				return Result{}, io.EOF
+			}
+			return Result{}, fmt.Errorf("truncated results file: %v", err)
+		}
+	}
+	start := time.Unix(0, v[0])
+	if !rr.last.IsZero() {
+		start = rr.last.Add(time.Duration(v[0]))
+	}
+	res := Result{
+		Start:         start,
+		Duration:      time.Duration(v[1]),
+		StatusCode:    int(v[2]),
+		ContentLength: int(v[3]),
+		Attempts:      int(v[4]),
+		Shed:          v[5]&recordShed != 0,
+	}
+	if v[5]&recordErr != 0 {
+		msg, err := rr.string()
+		if err != nil {
+			return Result{}, err
+		}
+		res.Err = errors.New(msg)
+	}
+	if !rr.v1 {
+		set, err := rr.labels()
+		if err != nil {
+			return Result{}, err
+		}
+		res.Labels, res.Tags = set.labels, set.tags
+	}
+	rr.last, rr.offset = start, rr.r.n
+	return res, nil
+}
+
+// labels reads the labels of a result, defining their set if new.
+func (rr *ResultReader) labels() (labelSet, error) {
+	id, err := binary.ReadVarint(rr.r)
+	if err != nil {
+		return labelSet{}, errors.New("truncated results file")
+	}
+	if id >= 0 {
+		set, ok := rr.sets[id]
+		if !ok && id > 0 {
+			return labelSet{}, fmt.Errorf("unknown labels %d in results file", id)
+		}
+		return set, nil
+	}
+	n, err := binary.ReadVarint(rr.r)
+	if err != nil || n < 0 {
+		return labelSet{}, errors.New("truncated results file")
+	}
+	var set labelSet
+	for i := int64(0); i < n; i++ {
+		name, err := rr.string()
+		if err != nil {
+			return labelSet{}, err
+		}
+		value, err := rr.string()
+		if err != nil {
+			return labelSet{}, err
+		}
+		kind, err := binary.ReadVarint(rr.r)
+		if err != nil {
+			return labelSet{}, errors.New("truncated results file")
+		}
+		if kind&recordLabel != 0 {
+			if set.labels == nil {
+				set.labels = make(map[string]string)
+			}
+			set.labels[name] = value
+		}
+		if kind&recordTag != 0 {
+			if set.tags == nil {
+				set.tags = make(map[string]string)
+			}
+			set.tags[name] = value
+		}
+	}
+	// The sets are numbered again by the writers appending to the file.
+	rr.sets[-id] = set
+	return set, nil
+}
+
+// string reads a length and a text.
+func (rr *ResultReader) string() (string, error) {
+	n, err := binary.ReadVarint(rr.r)
+	if err != nil || n < 0 {
+		return "", errors.New("truncated results file")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rr.r, b); err != nil {
+		return "", errors.New("truncated results file")
+	}
+	return string(b), nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordResults(t *testing.T) {
+	start := time.Unix(1500000000, 123)
+	results := []Result{
+		{Start: start, Duration: 10 * time.Millisecond, StatusCode: 200, ContentLength: 5, Attempts: 1},
+		{Start: start.Add(-time.Millisecond), Duration: time.Second, Err: errors.New("timeout"), Attempts: 3},
+		{Start: start.Add(time.Second), Shed: true},
+		{Start: start.Add(time.Second), StatusCode: 204, ContentLength: -1, Attempts: 1},
+	}
+	var buf bytes.Buffer
+	w := NewResultWriter(&buf)
+	for _, res := range results {
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewResultReader(&buf)
+	for _, want := range results {
+		res, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Start.Equal(want.Start) || (res.Err == nil) != (want.Err == nil) || res.Err != nil && res.Err.Error() != want.Err.Error() {
+			t.Errorf("Expected %+v, found %+v", want, res)
+		}
+		res.Start, res.Err, want.Start, want.Err = time.Time{}, nil, time.Time{}, nil
+		if !reflect.DeepEqual(res, want) {
+			t.Errorf("Expected %+v, found %+v", want, res)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Expected EOF, found %v", err)
+	}
+
+	if _, err := NewResultReader(bytes.NewReader([]byte("not results"))).Read(); err == nil {
+		t.Errorf("Expected an error for an invalid file")
+	}
+	buf.Reset()
+	NewResultWriter(&buf).Flush()
+	if _, err := NewResultReader(&buf).Read(); err != io.EOF {
+		t.Errorf("Expected an empty file to hold no results, found %v", err)
+	}
+}
+
+func TestRecordLabels(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	eu := Result{
+		Start:  start,
+		Labels: map[string]string{"endpoint": "GET /", "region": "eu"},
+		Tags:   map[string]string{"region": "eu"},
+	}
+	results := []Result{eu, {Start: start}, eu, {Start: start, Labels: map[string]string{"priority": "1"}}}
+	var buf bytes.Buffer
+	w := NewResultWriter(&buf)
+	for _, res := range results {
+		w.Write(res)
+	}
+	w.Flush()
+	// The labels of a set are written once.
+	if n := bytes.Count(buf.Bytes(), []byte("GET /")); n != 1 {
+		t.Errorf("Expected the labels of the set once, found %d times", n)
+	}
+	r := NewResultReader(&buf)
+	for _, want := range results {
+		res, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res.Labels, want.Labels) || !reflect.DeepEqual(res.Tags, want.Tags) {
+			t.Errorf("Expected labels %v and tags %v, found %v and %v", want.Labels, want.Tags, res.Labels, res.Tags)
+		}
+	}
+
+	// The results of the first version of the format have no labels.
+	v1 := append([]byte(recordMagicV1), 0, 0, 0, 0, 0, 0)
+	if res, err := NewResultReader(bytes.NewReader(v1)).Read(); err != nil || res.Labels != nil {
+		t.Errorf("Expected a result without labels, found %+v, %v", res, err)
+	}
+}
+
+func TestAppendResultWriter(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	var buf bytes.Buffer
+	w := NewResultWriter(&buf)
+	w.Write(Result{Start: start, StatusCode: 200})
+	w.Flush()
+	size := buf.Len()
+	// A result cut by a crash.
+	w.Write(Result{Start: start.Add(time.Second), Err: errors.New("timeout")})
+	w.Flush()
+	buf.Truncate(buf.Len() - 1)
+
+	r := NewResultReader(bytes.NewReader(buf.Bytes()))
+	if _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(); err == nil || err == io.EOF {
+		t.Fatalf("Expected the cut result to fail, found %v", err)
+	}
+	if r.Offset() != int64(size) || !r.Last().Equal(start) {
+		t.Fatalf("Expected the first result to end at %d, found %d", size, r.Offset())
+	}
+
+	buf.Truncate(int(r.Offset()))
+	w = AppendResultWriter(&buf, r)
+	w.Write(Result{Start: start.Add(2 * time.Second), StatusCode: 204})
+	w.Flush()
+	r = NewResultReader(&buf)
+	for _, want := range []time.Time{start, start.Add(2 * time.Second)} {
+		if res, err := r.Read(); err != nil || !res.Start.Equal(want) {
+			t.Errorf("Expected a result started at %v, found %+v, %v", want, res, err)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Expected EOF, found %v", err)
+	}
+}
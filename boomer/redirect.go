@@ -0,0 +1,71 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// do makes a single request, honoring the configured timeout.
+func (b *Boomer) do(client Doer, req *fasthttp.Request, resp *fasthttp.Response) error {
+	if b.StreamBody {
+		// The body of a previous hop or attempt is left unread.
+		resp.CloseBodyStream()
+		resp.StreamBody = true
+	}
+	if b.Timeout > 0 {
+		return client.DoTimeout(req, resp, b.Timeout)
+	}
+	return client.Do(req, resp)
+}
+
+// doFollow makes req and follows up to b.FollowRedirects redirects.
+// redirect is used as scratch space for the follow up requests so that
+// req is left untouched. resp holds the response of the last hop.
+func (b *Boomer) doFollow(client Doer, req, redirect *fasthttp.Request, resp *fasthttp.Response) error {
+	err := b.do(client, req, resp)
+	for hops := 0; err == nil && hops < b.FollowRedirects; hops++ {
+		code := resp.Header.StatusCode()
+		if !isRedirect(code) {
+			break
+		}
+		location := resp.Header.Peek("Location")
+		if len(location) == 0 {
+			break
+		}
+		if hops == 0 {
+			req.CopyTo(redirect)
+		}
+		redirect.URI().UpdateBytes(location)
+		redirect.Header.SetHostBytes(redirect.URI().Host())
+		if code == fasthttp.StatusSeeOther ||
+			(code != fasthttp.StatusTemporaryRedirect && code != fasthttp.StatusPermanentRedirect &&
+				!redirect.Header.IsGet() && !redirect.Header.IsHead()) {
+			redirect.Header.SetMethod("GET")
+			redirect.ResetBody()
+		}
+		err = b.do(client, redirect, resp)
+	}
+	return err
+}
+
+func isRedirect(code int) bool {
+	switch code {
+	case fasthttp.StatusMovedPermanently, fasthttp.StatusFound, fasthttp.StatusSeeOther,
+		fasthttp.StatusTemporaryRedirect, fasthttp.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Report is the aggregated outcome of a run. It is built once all the
+// requests are done and handed to a Renderer.
+type Report struct {
+	// RunID identifies the run, it is a random UUID.
+	RunID string
+
+	// Aborted is the reason the run was stopped early by
+	// Boomer.AbortErrorRate, if it was.
+	Aborted string
+
+	// Total is the wall time of the run.
+	Total time.Duration
+
+	// Fastest, Slowest and Average describe the latency of the
+	// successful requests.
+	Fastest time.Duration
+	Slowest time.Duration
+	Average time.Duration
+
+	// Count is the number of requests that got a response.
+	Count int64
+
+	// RPS is the number of responses per second.
+	RPS float64
+
+	// BatchSize is the number of operations per request, if batching was
+	// used. OpsPerSec and AveragePerOp are only set in that case.
+	BatchSize    int
+	OpsPerSec    float64
+	AveragePerOp time.Duration
+
+	// SizeTotal is the sum of the response content lengths, in bytes.
+	SizeTotal int64
+
+	// StatusCodeDist counts the responses per status code.
+	StatusCodeDist map[int]int
+
+	// Burst and BurstInterval are the size and interval of the bursts
+	// of requests, see Boomer.Burst.
+	Burst         int
+	BurstInterval time.Duration
+
+	// Pipeline is the number of requests pipelined on every connection,
+	// see Boomer.Pipeline.
+	Pipeline int
+
+	// WarmConnections tells whether the run started with the connection
+	// pools of a previous run, see Boomer.KeepConnections.
+	WarmConnections bool
+
+	// Connections describes the connections of the run, unless
+	// Boomer.Doer is set.
+	Connections *Connections
+
+	// Shed is the number of requests shed to protect the rate of higher
+	// priority targets.
+	Shed int64
+
+	// Paused is the time the run was paused, see Boomer.Pause. It is
+	// part of Total but not of the rates.
+	Paused time.Duration
+
+	// ErrorDist counts the failed requests per error message.
+	ErrorDist map[string]int
+
+	// ErrorCategories counts the failed requests per category, e.g.
+	// ErrorTimeout.
+	ErrorCategories map[string]int
+
+	// ForwardedDist counts the requests per simulated client address,
+	// when Boomer.ForwardedFor is set.
+	ForwardedDist map[string]int
+
+	// Histogram is the response time histogram.
+	Histogram []Bucket
+
+	// Latencies holds the latency percentiles.
+	Latencies []LatencyDistribution
+
+	// Sketch holds the latencies the percentiles were computed from, if
+	// Boomer.Sketch is set, for the percentiles of several runs to be
+	// merged.
+	Sketch *Sketch
+
+	// Breakdowns holds the statistics of classes of requests, per
+	// dimension, e.g. Breakdowns["auth"]["invalid"]. The responses are
+	// always broken down per status class, e.g. Breakdowns["status"]["5xx"].
+	Breakdowns map[string]map[string]*Breakdown
+
+	// TimeSeries holds per-second statistics, in chronological order.
+	TimeSeries []TimeSeriesPoint
+
+	// VirtualUsers describes every worker of the run.
+	VirtualUsers []VirtualUser
+
+	// MaxIterations is Boomer.MaxIterations.
+	MaxIterations int
+
+	// Detailed is set if the report holds Samples, see Verbosity.
+	Detailed bool
+
+	// Samples holds every request, in the order their results were
+	// collected, for detailed reports.
+	Samples []Sample
+
+	// SampleRate is the share of the requests held in Samples, 1 unless
+	// Boomer.SampleRate is set.
+	SampleRate float64
+
+	// Heatmap counts the requests per second and latency bucket.
+	Heatmap *Heatmap
+
+	// SlowestRequests holds the slowest requests, slowest first, if
+	// Boomer.SlowestRequests is set.
+	SlowestRequests []SlowRequest
+
+	// Tuning describes the concurrency tuning, if Boomer.TargetP99 is set.
+	Tuning *Tuning
+
+	// Throttling describes the rate adaptation, if Boomer.AdaptiveQps is
+	// set.
+	Throttling *Throttling
+
+	// Saturation describes the resources used by the load generator, if
+	// Boomer.Guard is set.
+	Saturation *Saturation
+
+	// Spike holds the statistics of the windows before, during and
+	// after the spike, if Boomer.Spike is set.
+	Spike []SpikeWindow
+
+	// Retries describes the retried requests, if any. Their final
+	// outcome is accounted in the distributions above.
+	Retries *Retries
+
+	// Transfer describes the reading of the response bodies, if
+	// Boomer.StreamBody is set.
+	Transfer *Transfer
+
+	// Compression describes the compression of the response bodies, if
+	// Boomer.Decompress is set.
+	Compression *Compression
+
+	// Upload describes the sending of the request bodies, if
+	// Boomer.RequestBody is set.
+	Upload *Upload
+
+	// Continue describes the interim responses to the requests sent
+	// with "Expect: 100-continue", if Boomer.ExpectContinue is set.
+	Continue *Continue
+
+	// ServerTiming is the attribution of the latency to the server
+	// components, if Boomer.ServerTiming is set and the target reports it.
+	ServerTiming *ServerTiming
+
+	// Drift is the trend analysis of the run, if it was enabled.
+	Drift *Drift
+}
+
+// Bucket is a single bar of the response time histogram.
+type Bucket struct {
+	Mark  time.Duration
+	Count uint64
+}
+
+// LatencyDistribution is the latency under which Percentage percent of
+// the requests completed.
+type LatencyDistribution struct {
+	Percentage int
+	Latency    time.Duration
+}
+
+// TimeSeriesPoint summarizes the requests started during one second of
+// the run.
+type TimeSeriesPoint struct {
+	// Offset is the start of the second, relative to the start of the run.
+	Offset  time.Duration
+	Count   int64
+	Errors  int64
+	Average time.Duration
+
+	// Client holds the resources of the load generator when the first
+	// result of the second was reported, sampled when Drift is enabled.
+	Client ClientStats
+}
+
+// Renderer writes a Report in some output format.
+type Renderer interface {
+	Render(w io.Writer, r *Report) error
+}
+
+// RendererFunc is an adapter to allow the use of ordinary functions as
+// renderers.
+type RendererFunc func(w io.Writer, r *Report) error
+
+// Render calls f(w, r).
+func (f RendererFunc) Render(w io.Writer, r *Report) error {
+	return f(w, r)
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,80 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// reservoirSize is the number of latencies kept to compute percentiles.
+// It bounds the memory of the longest runs while keeping the ranks of the
+// percentiles within a fraction of a percent.
+const reservoirSize = 100000
+
+// latencySample computes the percentiles of the latencies added to it,
+// a reservoir or a Sketch.
+type latencySample interface {
+	add(d time.Duration)
+	quantile(q float64) time.Duration
+}
+
+// reservoir is a uniform random sample of up to reservoirSize of the
+// latencies added, Vitter's algorithm R. The zero value is empty.
+type reservoir struct {
+	count int64
+	lats  []time.Duration
+	rand  *rand.Rand
+}
+
+func (r *reservoir) add(d time.Duration) {
+	r.count++
+	if len(r.lats) < reservoirSize {
+		r.lats = append(r.lats, d)
+		return
+	}
+	if r.rand == nil {
+		r.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	if i := r.rand.Int63n(r.count); i < reservoirSize {
+		r.lats[i] = d
+	}
+}
+
+// take returns the sample and the number of latencies added, and empties
+// the reservoir.
+func (r *reservoir) take() ([]time.Duration, int64) {
+	lats, count := r.lats, r.count
+	r.lats, r.count = nil, 0
+	return lats, count
+}
+
+// quantile returns the latency under which the share q of the sample
+// is, by nearest rank, 0 if the sample is empty.
+func (r *reservoir) quantile(q float64) time.Duration {
+	if len(r.lats) == 0 {
+		return 0
+	}
+	if !sort.SliceIsSorted(r.lats, func(i, j int) bool { return r.lats[i] < r.lats[j] }) {
+		sort.Slice(r.lats, func(i, j int) bool { return r.lats[i] < r.lats[j] })
+	}
+	i := int(math.Ceil(q*float64(len(r.lats)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return r.lats[i]
+}
@@ -0,0 +1,50 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReservoir(t *testing.T) {
+	var r reservoir
+	for i := 100; i > 0; i-- {
+		r.add(time.Duration(i))
+	}
+	for q, want := range map[float64]time.Duration{0: 1, 0.1: 10, 0.5: 50, 0.99: 99, 1: 100} {
+		if got := r.quantile(q); got != want {
+			t.Errorf("Expected quantile %v to be %v, found %v", q, want, got)
+		}
+	}
+
+	const n = 10 * reservoirSize
+	r = reservoir{}
+	for i := 1; i <= n; i++ {
+		r.add(time.Duration(i))
+	}
+	if len(r.lats) != reservoirSize || r.count != n {
+		t.Fatalf("Expected a sample of %d of %d latencies, found %d of %d", reservoirSize, n, len(r.lats), r.count)
+	}
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		got, want := float64(r.quantile(q)), q*n
+		if got < want*0.99 || got > want*1.01 {
+			t.Errorf("Expected quantile %v within 1%% of %v, found %v", q, want, got)
+		}
+	}
+	if lats, count := r.take(); len(lats) != reservoirSize || count != n || r.count != 0 || r.lats != nil {
+		t.Errorf("Expected take to empty the reservoir")
+	}
+}
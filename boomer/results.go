@@ -0,0 +1,157 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import "time"
+
+// Result is the outcome of a single request, see Boomer.Results.
+type Result struct {
+	// Start is when the request was sent.
+	Start    time.Time
+	Duration time.Duration
+
+	// StatusCode is the status code of the response, zero if none was
+	// received. It is set along with Err if the response failed an
+	// assertion.
+	StatusCode int
+
+	// ContentLength is the Content-Length of the response, -1 if it was
+	// not given.
+	ContentLength int
+
+	Err error
+
+	// Attempts is the number of times the request was sent, see
+	// Boomer.Retries.
+	Attempts int
+
+	// Shed is set if the request was never sent, see Target.Priority.
+	Shed bool
+
+	// Labels classify the request per dimension of Report.Breakdowns,
+	// e.g. Labels["endpoint"], the status classes aside. They include the
+	// Tags. The results aggregated are broken down per label.
+	Labels map[string]string
+
+	// Tags are the Target.Tags of the request, shared by the results of
+	// the target and not to be modified.
+	Tags map[string]string
+}
+
+// Results returns a channel receiving the result of every request of the
+// next run, or of those sampled by SampleRate, closed once the run is
+// over. It must be called before Run,
+// and the channel must be drained: the run waits for every result to be
+// received.
+func (b *Boomer) Results() <-chan Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stream == nil {
+		b.stream = make(chan Result, b.C)
+	}
+	return b.stream
+}
+
+// takeStream returns the channel of Results for the run about to start,
+// if any, and detaches it from b so that the next run gets a new one.
+func (b *Boomer) takeStream() chan Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stream := b.stream
+	b.stream = nil
+	return stream
+}
+
+func (res *result) export() Result {
+	return Result{
+		Start:         res.start,
+		Duration:      res.duration,
+		StatusCode:    res.statusCode,
+		ContentLength: res.contentLength,
+		Err:           res.err,
+		Attempts:      res.attempts,
+		Shed:          res.shed,
+		Labels:        labelMap(res.labels),
+		Tags:          res.tags,
+	}
+}
+
+// labelMap returns labels per dimension, nil if there are none.
+func labelMap(labels []label) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(labels))
+	for _, l := range labels {
+		m[l.dimension] = l.value
+	}
+	return m
+}
+
+// Aggregate accounts results made elsewhere, e.g. by the agents of a
+// distributed run, as if b made them, until results is closed. The
+// report is rendered with the Output and Renderer of b and returned. The
+// progress bar counts up to N, and is hidden if N is zero.
+func (b *Boomer) Aggregate(results <-chan Result) *Report {
+	b.results = make(chan *result, b.C)
+	b.startProgress()
+
+	r := newReport(b.N, b.results, b.Output, b.Renderer)
+	r.live = b.live
+	r.logf = b.logf
+	r.w = b.ReportWriter
+	r.users = make([]VirtualUser, 1)
+	r.detailed = b.Verbosity.detailed(sampledSize(b.N, b.SampleRate))
+	r.sampler = newSampler(b.SampleRate)
+	r.percentiles = b.Percentiles
+	if b.Sketch > 0 {
+		r.lats = sketchSample{NewSketch(b.Sketch)}
+	}
+	r.stream = b.takeStream()
+	r.intervals = b.newIntervalReporter()
+	// The run is timed by its results, which may have been recorded.
+	first := true
+	for res := range results {
+		if first {
+			r.start, first = res.Start, false
+		}
+		if end := res.Start.Add(res.Duration); end.After(r.end) {
+			r.end = end
+		}
+		b.incProgress()
+		b.results <- &result{
+			start:         res.Start,
+			duration:      res.Duration,
+			statusCode:    res.StatusCode,
+			contentLength: res.ContentLength,
+			err:           res.Err,
+			attempts:      res.Attempts,
+			shed:          res.Shed,
+			labels:        resultLabels(res),
+			tags:          res.Tags,
+		}
+	}
+	close(b.results)
+	b.finalizeProgress()
+	return r.finalize()
+}
+
+// resultLabels returns the labels of res, its tags if it has none.
+func resultLabels(res Result) []label {
+	if res.Labels == nil {
+		return labelsOf(res.Tags)
+	}
+	return labelsOf(res.Labels)
+}
@@ -0,0 +1,97 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:  req,
+		N:        30,
+		C:        3,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	results := boomer.Results()
+	done := make(chan []Result)
+	go func() {
+		var all []Result
+		for res := range results {
+			all = append(all, res)
+		}
+		done <- all
+	}()
+	boomer.Run()
+	all := <-done
+	if len(all) != 30 {
+		t.Fatalf("Expected 30 results, found %d", len(all))
+	}
+	for _, res := range all {
+		if res.Err != nil || res.StatusCode != http.StatusOK || res.ContentLength != 5 || res.Start.IsZero() || res.Duration <= 0 {
+			t.Errorf("Unexpected result %+v", res)
+		}
+	}
+
+	// Without a new call to Results, the next run is not streamed.
+	boomer.Run()
+	if _, ok := <-results; ok {
+		t.Errorf("Expected the stream of the previous run to be closed")
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	boomer := &Boomer{
+		N:        4,
+		C:        2,
+		Output:   "json",
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	results := make(chan Result, 4)
+	start := time.Now()
+	results <- Result{Start: start, Duration: 10 * time.Millisecond, StatusCode: 200, ContentLength: 5, Attempts: 1}
+	results <- Result{Start: start, Duration: 30 * time.Millisecond, StatusCode: 500, ContentLength: 5, Attempts: 1}
+	results <- Result{Start: start, Duration: time.Second, Err: errors.New("timeout"), Attempts: 1}
+	results <- Result{Start: start, Shed: true}
+	close(results)
+
+	report := boomer.Aggregate(results)
+	if report.Count != 2 || report.Shed != 1 {
+		t.Errorf("Expected 2 responses and 1 shed, found %d and %d", report.Count, report.Shed)
+	}
+	if report.StatusCodeDist[200] != 1 || report.StatusCodeDist[500] != 1 || report.ErrorDist["timeout"] != 1 {
+		t.Errorf("Unexpected distributions %v and %v", report.StatusCodeDist, report.ErrorDist)
+	}
+	if report.Fastest != 10*time.Millisecond || report.Slowest != 30*time.Millisecond || report.SizeTotal != 10 {
+		t.Errorf("Expected the latencies and sizes of the successes, found %v, %v and %d", report.Fastest, report.Slowest, report.SizeTotal)
+	}
+	if report.Total != time.Second {
+		t.Errorf("Expected the run to be timed by its results, found %v", report.Total)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"syscall"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Retries describes the requests that were retried.
+type Retries struct {
+	// Requests is the number of requests retried at least once.
+	Requests int64
+
+	// Attempts is the number of retries, on top of the first attempts.
+	Attempts int64
+
+	// Recovered is the number of retried requests that eventually got a
+	// response which was not retriable.
+	Recovered int64
+}
+
+// retriable returns whether a request that ended with err, or with the
+// status code of resp, is worth retrying: the connection was reset or
+// the server, or a proxy in front of it, was temporarily unavailable.
+func retriable(err error, resp *fasthttp.Response) bool {
+	if err != nil {
+		return errors.Is(err, fasthttp.ErrConnectionClosed) ||
+			errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+			errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+	}
+	code := resp.StatusCode()
+	return code == fasthttp.StatusBadGateway || code == fasthttp.StatusServiceUnavailable
+}
+
+// retry repeats req up to b.Retries times while the outcome is
+// retriable, doubling the wait between attempts from b.RetryBackoff.
+// It returns the number of attempts made, including the first one.
+func (b *Boomer) retry(ctx context.Context, client Doer, req, redirect *fasthttp.Request, resp *fasthttp.Response, err error) (int, error) {
+	attempts := 1
+	backoff := b.RetryBackoff
+	for ; attempts <= b.Retries && retriable(err, resp); attempts++ {
+		if backoff > 0 {
+			t := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return attempts, err
+			case <-t.C:
+			}
+			backoff *= 2
+		}
+		resp.Reset()
+		err = b.doFollow(client, req, redirect, resp)
+	}
+	return attempts, err
+}
@@ -0,0 +1,91 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestRetries(t *testing.T) {
+	var count int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt64(&count, 1) % 3 {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	// fasthttp already repeats idempotent requests on closed connections.
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("POST")
+	boomer := &Boomer{
+		Request:      req,
+		N:            5,
+		C:            1,
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+		Renderer:     RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.StatusCodeDist[http.StatusOK] != 5 || len(rep.ErrorDist) != 0 {
+		t.Errorf("Expected every request to recover, found %v and %v", rep.StatusCodeDist, rep.ErrorDist)
+	}
+	if r := rep.Retries; r == nil || r.Requests != 5 || r.Attempts != 10 || r.Recovered != 5 {
+		t.Errorf("Expected 10 retries of 5 recovered requests, found %+v", r)
+	}
+
+	atomic.StoreInt64(&count, 0)
+	boomer.Retries = 1
+	rep = boomer.Run()
+	if rep.Retries == nil || rep.Retries.Recovered != 0 {
+		t.Errorf("Expected no request to recover, found %+v", rep.Retries)
+	}
+	if n := rep.StatusCodeDist[http.StatusOK] + rep.StatusCodeDist[http.StatusServiceUnavailable]; n != 2 {
+		t.Errorf("Expected the exhausted requests to be reported as failed, found %v", rep.StatusCodeDist)
+	}
+}
+
+func TestRetriesDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:  req,
+		N:        3,
+		C:        1,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Retries != nil || rep.StatusCodeDist[http.StatusBadGateway] != 3 {
+		t.Errorf("Expected no retries, found %+v and %v", rep.Retries, rep.StatusCodeDist)
+	}
+}
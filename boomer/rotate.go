@@ -0,0 +1,73 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// HeaderRotation gives a header each of Values in turn, a value per
+// request, e.g. to spread the requests over several User-Agents.
+type HeaderRotation struct {
+	Name   string
+	Values []string
+
+	// Partition splits Values into disjoint shares, a share per worker,
+	// each worker going through its own share only, e.g. for accounts
+	// not to be used by several workers at once. There must be at least
+	// as many values as workers.
+	Partition bool
+}
+
+// headerRotator hands out the values of a rotation across the workers.
+// It is safe for concurrent use.
+type headerRotator struct {
+	HeaderRotation
+	workers int
+	n       uint64
+
+	// shares counts the requests of every worker, if partitioned.
+	shares []uint64
+}
+
+func newHeaderRotators(rotations []HeaderRotation, workers int) []*headerRotator {
+	var rotators []*headerRotator
+	for _, r := range rotations {
+		rotator := &headerRotator{HeaderRotation: r, workers: workers}
+		if r.Partition {
+			rotator.shares = make([]uint64, workers)
+		}
+		rotators = append(rotators, rotator)
+	}
+	return rotators
+}
+
+// apply sets the header of the request of a worker to the following
+// value.
+func (r *headerRotator) apply(req *fasthttp.Request, worker int) {
+	if !r.Partition {
+		i := (atomic.AddUint64(&r.n, 1) - 1) % uint64(len(r.Values))
+		req.Header.Set(r.Name, r.Values[i])
+		return
+	}
+	// The share of a worker is only used by it.
+	lo := worker * len(r.Values) / r.workers
+	hi := (worker + 1) * len(r.Values) / r.workers
+	i := lo + int(r.shares[worker]%uint64(hi-lo))
+	r.shares[worker]++
+	req.Header.Set(r.Name, r.Values[i])
+}
@@ -0,0 +1,110 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRotateHeaders(t *testing.T) {
+	var mu sync.Mutex
+	agents := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents[r.UserAgent()+" "+r.Header.Get("X-Client")]++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	boomer := &Boomer{
+		Request: newGet(server.URL),
+		N:       12,
+		C:       3,
+		RotateHeaders: []HeaderRotation{
+			{Name: "User-Agent", Values: []string{"a", "b", "c"}},
+			{Name: "X-Client", Values: []string{"1"}},
+		},
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	boomer.Run()
+	if len(agents) != 3 || agents["a 1"] != 4 || agents["b 1"] != 4 || agents["c 1"] != 4 {
+		t.Errorf("Expected the 3 User-Agents to be sent 4 times, found %v", agents)
+	}
+}
+
+func TestPartitionRotation(t *testing.T) {
+	values := []string{"a", "b", "c", "d", "e", "f", "g"}
+	r := newHeaderRotators([]HeaderRotation{{Name: "Authorization", Values: values, Partition: true}}, 3)[0]
+	req := newGet("http://localhost")
+	owners := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		worker := i % 3
+		r.apply(req, worker)
+		v := string(req.Header.Peek("Authorization"))
+		if owner, ok := owners[v]; ok && owner != worker {
+			t.Fatalf("Expected %s to be used by worker %d only, found worker %d", v, owner, worker)
+		}
+		owners[v] = worker
+	}
+	if len(owners) != len(values) {
+		t.Errorf("Expected all the values to be used, found %v", owners)
+	}
+
+	boomer := &Boomer{
+		Request:       newGet("http://localhost"),
+		N:             8,
+		C:             8,
+		RotateHeaders: []HeaderRotation{{Name: "Authorization", Values: values, Partition: true}},
+	}
+	if err := boomer.Validate(); err == nil {
+		t.Errorf("Expected fewer values than workers to be rejected")
+	}
+}
+
+func TestRequestIDHeaders(t *testing.T) {
+	var mu sync.Mutex
+	ids := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("Idempotency-Key")
+		if len(id) != 36 || r.Header.Get("X-Request-ID") != id {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if ids[id]++; ids[id] == 1 {
+			// The retry of the request keeps its key.
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	boomer := &Boomer{
+		Request:          newGet(server.URL),
+		N:                10,
+		C:                2,
+		Retries:          1,
+		RequestIDHeaders: []string{"Idempotency-Key", "X-Request-ID"},
+		Renderer:         RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.StatusCodeDist[http.StatusOK] != 10 || len(ids) != 10 {
+		t.Errorf("Expected 10 keys, each retried once, found %d keys and %v", len(ids), rep.StatusCodeDist)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"math/rand"
+	"time"
+)
+
+// sampler keeps a random share of the requests of a run in the samples
+// of the report and in its stream, see Boomer.SampleRate. A nil sampler
+// keeps every request.
+type sampler struct {
+	rate float64
+	rand *rand.Rand
+}
+
+func newSampler(rate float64) *sampler {
+	if rate <= 0 || rate >= 1 {
+		return nil
+	}
+	return &sampler{rate: rate, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// keep tells whether the detail of the next request is kept.
+func (s *sampler) keep() bool {
+	return s == nil || s.rand.Float64() < s.rate
+}
+
+// sampledSize returns the number of requests of a run of n requests
+// kept by a sample rate, for the automatic verbosity.
+func sampledSize(n int, rate float64) int {
+	if rate <= 0 || rate >= 1 {
+		return n
+	}
+	return int(float64(n) * rate)
+}
@@ -0,0 +1,58 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSampleRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	boomer := &Boomer{
+		Request:    newGet(server.URL),
+		N:          1000,
+		C:          4,
+		Verbosity:  VerbosityDetailed,
+		SampleRate: 0.1,
+		Renderer:   RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	results := boomer.Results()
+	streamed := make(chan int)
+	go func() {
+		n := 0
+		for range results {
+			n++
+		}
+		streamed <- n
+	}()
+	rep := boomer.Run()
+	if rep.Count != 1000 {
+		t.Errorf("Expected every request to be counted, found %d", rep.Count)
+	}
+	if n := len(rep.Samples); n < 50 || n > 200 || rep.SampleRate != 0.1 {
+		t.Errorf("Expected about 100 samples at a rate of 0.1, found %d at %v", n, rep.SampleRate)
+	}
+	if n := <-streamed; n != len(rep.Samples) {
+		t.Errorf("Expected the stream to hold the %d samples, found %d", len(rep.Samples), n)
+	}
+
+	if sampledSize(50000, 0.1) != 5000 || !VerbosityAuto.detailed(sampledSize(50000, 0.1)) {
+		t.Error("Expected a sampled run of 50000 requests to be detailed automatically")
+	}
+}
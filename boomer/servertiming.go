@@ -0,0 +1,168 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ServerTiming attributes the latency of the responses carrying a
+// Server-Timing header between the components reported by the server
+// and the rest, mostly network and queueing.
+type ServerTiming struct {
+	// Responses is the number of responses with a Server-Timing header.
+	Responses int64
+
+	// Latency is the average client measured latency of those responses.
+	Latency time.Duration
+
+	// Components are the server reported metrics, slowest first.
+	Components []ServerComponent
+
+	// Network is the part of Latency not reported by the server. The
+	// components are assumed not to overlap; a negative value means
+	// they do, e.g. when the server reports a total besides its parts.
+	Network time.Duration
+}
+
+// ServerComponent is a metric of the Server-Timing header.
+type ServerComponent struct {
+	Name string
+
+	// Count is the number of responses reporting the metric.
+	Count int64
+
+	// Average is the duration of the metric, averaged over the responses
+	// reporting it.
+	Average time.Duration
+
+	// Share is the part of the average latency spent in the metric,
+	// across all the responses with a Server-Timing header.
+	Share float64
+}
+
+type serverMetric struct {
+	name string
+	dur  time.Duration
+}
+
+// parseServerTiming returns the metrics with a duration of the
+// Server-Timing headers of resp, e.g. `db;dur=53.2, app;desc="x";dur=47`.
+// Durations are given in milliseconds.
+func parseServerTiming(resp *fasthttp.Response) []serverMetric {
+	var metrics []serverMetric
+	resp.Header.VisitAll(func(k, v []byte) {
+		if !strings.EqualFold(string(k), "Server-Timing") {
+			return
+		}
+		for _, m := range strings.Split(string(v), ",") {
+			params := strings.Split(m, ";")
+			name := strings.TrimSpace(params[0])
+			if name == "" {
+				continue
+			}
+			for _, p := range params[1:] {
+				kv := strings.SplitN(p, "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "dur") {
+					continue
+				}
+				ms, err := strconv.ParseFloat(strings.Trim(strings.TrimSpace(kv[1]), `"`), 64)
+				if err != nil || ms < 0 {
+					continue
+				}
+				metrics = append(metrics, serverMetric{name: name, dur: time.Duration(ms * float64(time.Millisecond))})
+			}
+		}
+	})
+	return metrics
+}
+
+// serverTimings accumulates the Server-Timing metrics of the results.
+type serverTimings struct {
+	responses    int64
+	latencyTotal time.Duration
+	serverTotal  time.Duration
+	totals       map[string]time.Duration
+	counts       map[string]int64
+}
+
+func (s *serverTimings) add(res *result) {
+	if len(res.serverTiming) == 0 {
+		return
+	}
+	if s.totals == nil {
+		s.totals = make(map[string]time.Duration)
+		s.counts = make(map[string]int64)
+	}
+	s.responses++
+	s.latencyTotal += res.duration
+	seen := make(map[string]bool, len(res.serverTiming))
+	for _, m := range res.serverTiming {
+		s.totals[m.name] += m.dur
+		s.serverTotal += m.dur
+		if !seen[m.name] {
+			s.counts[m.name]++
+			seen[m.name] = true
+		}
+	}
+}
+
+func (s *serverTimings) build() *ServerTiming {
+	if s.responses == 0 {
+		return nil
+	}
+	st := &ServerTiming{
+		Responses: s.responses,
+		Latency:   s.latencyTotal / time.Duration(s.responses),
+		Network:   (s.latencyTotal - s.serverTotal) / time.Duration(s.responses),
+	}
+	for name, total := range s.totals {
+		c := ServerComponent{
+			Name:    name,
+			Count:   s.counts[name],
+			Average: total / time.Duration(s.counts[name]),
+		}
+		if s.latencyTotal > 0 {
+			c.Share = float64(total) / float64(s.latencyTotal)
+		}
+		st.Components = append(st.Components, c)
+	}
+	sort.Slice(st.Components, func(i, j int) bool {
+		if st.Components[i].Share != st.Components[j].Share {
+			return st.Components[i].Share > st.Components[j].Share
+		}
+		return st.Components[i].Name < st.Components[j].Name
+	})
+	return st
+}
+
+func printServerTiming(w io.Writer, st *ServerTiming) {
+	fmt.Fprintf(w, "\nServer timing (%s responses, %s average):\n", formatCount(float64(st.Responses)), formatSeconds(st.Latency.Seconds()))
+	for _, c := range st.Components {
+		fmt.Fprintf(w, "  %s\t%s\t%5.1f%%\t(%s responses)\n", c.Name, formatSeconds(c.Average.Seconds()), c.Share*100, formatCount(float64(c.Count)))
+	}
+	share := 0.0
+	if st.Latency > 0 {
+		share = float64(st.Network) / float64(st.Latency)
+	}
+	fmt.Fprintf(w, "  network and other\t%s\t%5.1f%%\n", formatSeconds(st.Network.Seconds()), share*100)
+}
@@ -0,0 +1,83 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseServerTiming(t *testing.T) {
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	resp.Header.Add("Server-Timing", `db;dur=53.2, cache;desc="Cache Read";dur=0.5`)
+	resp.Header.Add("Server-Timing", `miss, app;dur="12"`)
+	metrics := parseServerTiming(resp)
+	want := []serverMetric{
+		{"db", 53200 * time.Microsecond},
+		{"cache", 500 * time.Microsecond},
+		{"app", 12 * time.Millisecond},
+	}
+	if len(metrics) != len(want) {
+		t.Fatalf("Expected %v metrics, found %v", want, metrics)
+	}
+	for i := range want {
+		if metrics[i] != want[i] {
+			t.Errorf("Expected metric %v, found %v", want[i], metrics[i])
+		}
+	}
+}
+
+func TestServerTiming(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			return
+		}
+		w.Header().Set("Server-Timing", "db;dur=20, app;dur=10")
+		time.Sleep(40 * time.Millisecond)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	timed := fasthttp.AcquireRequest()
+	timed.SetRequestURI(server.URL + "/timed")
+	plain := fasthttp.AcquireRequest()
+	plain.SetRequestURI(server.URL + "/plain")
+	boomer := &Boomer{
+		Targets:      []Target{{Request: timed}, {Request: plain}},
+		N:            10,
+		C:            2,
+		ServerTiming: true,
+		Renderer:     RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	st := boomer.Run().ServerTiming
+	if st == nil || st.Responses != 5 {
+		t.Fatalf("Expected 5 timed responses, found %+v", st)
+	}
+	if len(st.Components) != 2 || st.Components[0].Name != "db" || st.Components[1].Name != "app" {
+		t.Fatalf("Expected db and app components, slowest first, found %+v", st.Components)
+	}
+	if st.Components[0].Average != 20*time.Millisecond || st.Components[0].Count != 5 {
+		t.Errorf("Expected db to average 20ms over 5 responses, found %+v", st.Components[0])
+	}
+	if st.Network < 10*time.Millisecond || st.Network != st.Latency-30*time.Millisecond {
+		t.Errorf("Expected network time to be the rest of the latency, found %v of %v", st.Network, st.Latency)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// SigV4 signs requests with the AWS Signature Version 4 scheme.
+type SigV4 struct {
+	Region       string
+	Service      string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// NewSigV4 returns a signer for region and service using the credentials
+// of the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// environment variables or, if unset, of the shared credentials file for
+// the AWS_PROFILE profile.
+func NewSigV4(region, service string) (*SigV4, error) {
+	s := &SigV4{
+		Region:       region,
+		Service:      service,
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if s.AccessKey != "" && s.SecretKey != "" {
+		return s, nil
+	}
+	if err := s.loadSharedCredentials(); err != nil {
+		return nil, fmt.Errorf("no AWS credentials found: %v", err)
+	}
+	return s, nil
+}
+
+func (s *SigV4) loadSharedCredentials() error {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var section string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if section != profile || len(kv) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(kv[1])
+		switch strings.TrimSpace(kv[0]) {
+		case "aws_access_key_id":
+			s.AccessKey = value
+		case "aws_secret_access_key":
+			s.SecretKey = value
+		case "aws_session_token":
+			s.SessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if s.AccessKey == "" || s.SecretKey == "" {
+		return fmt.Errorf("profile %s not found in %s", profile, path)
+	}
+	return nil
+}
+
+// unsignedPayload stands for the hash of the streamed bodies, which are
+// not read to be signed.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// Sign sets the X-Amz-Date and Authorization headers of req, as of t.
+// Streamed bodies are left unread and signed as UNSIGNED-PAYLOAD.
+func (s *SigV4) Sign(req *fasthttp.Request, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := amzDate[:8]
+	payload := unsignedPayload
+	if !req.IsBodyStream() {
+		payload = sha256Hex(req.Body())
+	}
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	if s.Service == "s3" || payload == unsignedPayload {
+		req.Header.Set("X-Amz-Content-Sha256", payload)
+	}
+
+	headers := map[string]string{"host": string(req.Host())}
+	req.Header.VisitAll(func(k, v []byte) {
+		name := strings.ToLower(string(k))
+		if strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(string(v))
+		}
+	})
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := string(req.URI().Path())
+	if path == "" {
+		path = "/"
+	}
+	path = awsEscape(path, false)
+	if s.Service != "s3" {
+		path = awsEscape(path, false)
+	}
+
+	canonical := strings.Join([]string{
+		string(req.Header.Method()),
+		path,
+		canonicalQuery(req.URI().QueryArgs()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payload,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/" + s.Service + "/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalQuery returns the query parameters of args escaped and sorted
+// by name, then by value.
+func canonicalQuery(args *fasthttp.Args) string {
+	var pairs [][2]string
+	args.VisitAll(func(k, v []byte) {
+		pairs = append(pairs, [2]string{awsEscape(string(k), true), awsEscape(string(v), true)})
+	})
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	query := make([]string, len(pairs))
+	for i, p := range pairs {
+		query[i] = p[0] + "=" + p[1]
+	}
+	return strings.Join(query, "&")
+}
+
+// awsEscape percent-encodes everything but the unreserved characters of
+// RFC 3986 and, unless encodeSlash is set, slashes.
+func awsEscape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
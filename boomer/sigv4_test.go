@@ -0,0 +1,78 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// TestSigV4 checks the get-vanilla and get-vanilla-query-order-key cases
+// of the AWS Signature Version 4 test suite.
+func TestSigV4(t *testing.T) {
+	signer := &SigV4{
+		Region:    "us-east-1",
+		Service:   "service",
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	at := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	cases := map[string]string{
+		"http://example.amazonaws.com/":                             "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		"http://example.amazonaws.com/?Param2=value2&Param1=value1": "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+	}
+	for uri, signature := range cases {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(uri)
+		req.Header.SetMethod("GET")
+		signer.Sign(req, at)
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+			"SignedHeaders=host;x-amz-date, Signature=" + signature
+		if got := string(req.Header.Peek("Authorization")); got != want {
+			t.Errorf("%s: unexpected Authorization header\n got: %s\nwant: %s", uri, got, want)
+		}
+		fasthttp.ReleaseRequest(req)
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	var args fasthttp.Args
+	args.Parse("a-b=2&a=1&b=x y&a=0")
+	if got, want := canonicalQuery(&args), "a=0&a=1&a-b=2&b=x%20y"; got != want {
+		t.Errorf("Expected the parameters sorted by name then value, %s, found %s", want, got)
+	}
+}
+
+func TestSigV4StreamedBody(t *testing.T) {
+	signer := &SigV4{Region: "us-east-1", Service: "service", AccessKey: "id", SecretKey: "secret"}
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI("http://example.amazonaws.com/upload")
+	req.Header.SetMethod("PUT")
+	req.SetBodyStream(strings.NewReader("data"), 4)
+	signer.Sign(req, time.Now())
+	if !req.IsBodyStream() {
+		t.Error("Expected the body to still be streamed")
+	}
+	if got := string(req.Header.Peek("X-Amz-Content-Sha256")); got != "UNSIGNED-PAYLOAD" {
+		t.Errorf("Expected the streamed body to be signed as UNSIGNED-PAYLOAD, found %q", got)
+	}
+	if !strings.Contains(string(req.Header.Peek("Authorization")), "x-amz-content-sha256") {
+		t.Errorf("Expected the payload header to be signed, found %s", req.Header.Peek("Authorization"))
+	}
+}
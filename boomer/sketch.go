@@ -0,0 +1,112 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// Sketch is a DDSketch of latencies: it computes their quantiles within
+// a relative accuracy, in a memory growing with the logarithm of their
+// range only, and sketches of several runs can be merged.
+type Sketch struct {
+	// Accuracy is the relative accuracy of the quantiles, e.g. 0.01.
+	Accuracy float64
+
+	// Zero counts the latencies of zero. Buckets counts the others per
+	// index: bucket i holds the latencies in (γ^(i-1), γ^i] nanoseconds,
+	// γ being (1+Accuracy)/(1-Accuracy).
+	Zero    uint64
+	Buckets map[int]uint64
+}
+
+// NewSketch returns an empty sketch of the given relative accuracy,
+// between 0 and 1 excluded.
+func NewSketch(accuracy float64) *Sketch {
+	return &Sketch{Accuracy: accuracy, Buckets: make(map[int]uint64)}
+}
+
+func (s *Sketch) logGamma() float64 {
+	return math.Log((1 + s.Accuracy) / (1 - s.Accuracy))
+}
+
+// Add accounts a latency.
+func (s *Sketch) Add(d time.Duration) {
+	if d <= 0 {
+		s.Zero++
+		return
+	}
+	s.Buckets[int(math.Ceil(math.Log(float64(d))/s.logGamma()))]++
+}
+
+// Count returns the number of latencies added.
+func (s *Sketch) Count() uint64 {
+	n := s.Zero
+	for _, c := range s.Buckets {
+		n += c
+	}
+	return n
+}
+
+// Quantile returns the latency under which the share q of the latencies
+// are, 0 if the sketch is empty.
+func (s *Sketch) Quantile(q float64) time.Duration {
+	n := s.Count()
+	if n == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(n-1))
+	seen := s.Zero
+	if seen > rank {
+		return 0
+	}
+	indexes := make([]int, 0, len(s.Buckets))
+	for i := range s.Buckets {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	lg := s.logGamma()
+	for _, i := range indexes {
+		if seen += s.Buckets[i]; seen > rank {
+			// The middle of the bucket, within the accuracy of its
+			// latencies.
+			return time.Duration(2 * math.Exp(float64(i)*lg) / (1 + math.Exp(lg)))
+		}
+	}
+	return 0
+}
+
+// Merge adds the latencies of o to s. Both must have the same accuracy.
+func (s *Sketch) Merge(o *Sketch) error {
+	if s.Accuracy != o.Accuracy {
+		return errors.New("cannot merge sketches of different accuracies")
+	}
+	s.Zero += o.Zero
+	for i, c := range o.Buckets {
+		s.Buckets[i] += c
+	}
+	return nil
+}
+
+// sketchSample adapts a Sketch to a latencySample.
+type sketchSample struct {
+	*Sketch
+}
+
+func (s sketchSample) add(d time.Duration)              { s.Add(d) }
+func (s sketchSample) quantile(q float64) time.Duration { return s.Quantile(q) }
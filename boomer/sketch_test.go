@@ -0,0 +1,82 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSketch(t *testing.T) {
+	const n = 100000
+	a, b := NewSketch(0.01), NewSketch(0.01)
+	for i := 1; i <= n; i++ {
+		if i%2 == 0 {
+			a.Add(time.Duration(i) * time.Microsecond)
+		} else {
+			b.Add(time.Duration(i) * time.Microsecond)
+		}
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Count() != n {
+		t.Fatalf("Expected %d latencies, found %d", n, a.Count())
+	}
+	for _, q := range []float64{0.1, 0.5, 0.99} {
+		got, want := float64(a.Quantile(q)), q*n*float64(time.Microsecond)
+		if got < want*0.98 || got > want*1.02 {
+			t.Errorf("Expected quantile %v within 2%% of %v, found %v", q, time.Duration(want), time.Duration(got))
+		}
+	}
+	if err := a.Merge(NewSketch(0.02)); err == nil {
+		t.Error("Expected sketches of different accuracies not to merge")
+	}
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Sketch
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Quantile(0.5) != a.Quantile(0.5) {
+		t.Errorf("Expected the decoded sketch to have the same median, found %v", decoded.Quantile(0.5))
+	}
+}
+
+func TestRunSketch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	boomer := &Boomer{
+		Request:  newGet(server.URL),
+		N:        50,
+		C:        2,
+		Sketch:   0.01,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Sketch == nil || rep.Sketch.Count() != uint64(rep.Count) {
+		t.Fatalf("Expected a sketch of the %d requests, found %+v", rep.Count, rep.Sketch)
+	}
+	if len(rep.Latencies) == 0 || rep.Latencies[len(rep.Latencies)-1].Latency != rep.Sketch.Quantile(0.99) {
+		t.Errorf("Expected the percentiles of the sketch, found %v", rep.Latencies)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// SlowRequest describes one of the slowest requests of a run, see
+// Boomer.SlowestRequests.
+type SlowRequest struct {
+	Start      time.Time
+	Duration   time.Duration
+	Method     string
+	URL        string
+	StatusCode int
+	Err        string
+	Attempts   int
+
+	// ServerTiming is the time spent in every server component, if
+	// Boomer.ServerTiming is set and the target reports it.
+	ServerTiming map[string]time.Duration
+}
+
+// slowRequests keeps the k slowest requests in a min-heap, the fastest
+// of them first.
+type slowRequests struct {
+	k    int
+	reqs slowHeap
+}
+
+type slowHeap []SlowRequest
+
+func (h slowHeap) Len() int            { return len(h) }
+func (h slowHeap) Less(i, j int) bool  { return h[i].Duration < h[j].Duration }
+func (h slowHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *slowHeap) Push(x interface{}) { *h = append(*h, x.(SlowRequest)) }
+func (h *slowHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func (s *slowRequests) add(res *result) {
+	if len(s.reqs) == s.k && res.duration <= s.reqs[0].Duration {
+		return
+	}
+	req := SlowRequest{
+		Start:      res.start,
+		Duration:   res.duration,
+		Method:     res.method,
+		URL:        res.url,
+		StatusCode: res.statusCode,
+		Attempts:   res.attempts,
+	}
+	if res.err != nil {
+		req.Err = res.err.Error()
+	}
+	if len(res.serverTiming) > 0 {
+		req.ServerTiming = make(map[string]time.Duration, len(res.serverTiming))
+		for _, m := range res.serverTiming {
+			req.ServerTiming[m.name] += m.dur
+		}
+	}
+	if len(s.reqs) == s.k {
+		s.reqs[0] = req
+		heap.Fix(&s.reqs, 0)
+		return
+	}
+	heap.Push(&s.reqs, req)
+}
+
+// build returns the requests, slowest first.
+func (s *slowRequests) build() []SlowRequest {
+	reqs := append([]SlowRequest(nil), s.reqs...)
+	sort.Slice(reqs, func(i, j int) bool { return reqs[i].Duration > reqs[j].Duration })
+	return reqs
+}
+
+func printSlowest(w io.Writer, reqs []SlowRequest) {
+	fmt.Fprintf(w, "\nSlowest requests:\n")
+	for _, r := range reqs {
+		outcome := fmt.Sprintf("[%d]", r.StatusCode)
+		if r.Err != "" {
+			outcome = r.Err
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s %s\t%s\n", r.Start.Format("15:04:05.000"), formatSeconds(r.Duration.Seconds()), r.Method, r.URL, outcome)
+		if r.Attempts > 1 {
+			fmt.Fprintf(w, "  \t\t%d attempts\n", r.Attempts)
+		}
+		names := make([]string, 0, len(r.ServerTiming))
+		for name := range r.ServerTiming {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  \t\t%s: %s\n", name, formatSeconds(r.ServerTiming[name].Seconds()))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestSlowest(t *testing.T) {
+	s := &slowRequests{k: 3}
+	for _, ms := range []int{5, 1, 9, 7, 3, 8} {
+		s.add(&result{duration: time.Duration(ms) * time.Millisecond, url: "/", statusCode: 200})
+	}
+	s.add(&result{duration: 10 * time.Millisecond, err: errors.New("timeout"), serverTiming: []serverMetric{{"db", time.Millisecond}}})
+
+	reqs := s.build()
+	var found []time.Duration
+	for _, r := range reqs {
+		found = append(found, r.Duration/time.Millisecond)
+	}
+	if len(found) != 3 || found[0] != 10 || found[1] != 9 || found[2] != 8 {
+		t.Fatalf("Expected the 10, 9 and 8ms requests, found %v", found)
+	}
+	if reqs[0].Err != "timeout" || reqs[0].ServerTiming["db"] != time.Millisecond {
+		t.Errorf("Expected the error and the server timing of the slowest request, found %+v", reqs[0])
+	}
+}
+
+func TestSlowestRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	fast := fasthttp.AcquireRequest()
+	fast.SetRequestURI(server.URL + "/fast")
+	slow := fasthttp.AcquireRequest()
+	slow.SetRequestURI(server.URL + "/slow")
+	b := &Boomer{
+		Targets:         []Target{{Request: fast}, {Request: slow}},
+		N:               10,
+		C:               2,
+		Output:          "json",
+		Renderer:        RendererFunc(func(io.Writer, *Report) error { return nil }),
+		SlowestRequests: 2,
+	}
+	report := b.Run()
+	if len(report.SlowestRequests) != 2 {
+		t.Fatalf("Expected 2 slowest requests, found %v", report.SlowestRequests)
+	}
+	for _, r := range report.SlowestRequests {
+		if r.URL != server.URL+"/slow" || r.Method != "GET" || r.StatusCode != 200 {
+			t.Errorf("Expected a slow GET, found %+v", r)
+		}
+	}
+}
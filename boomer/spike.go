@@ -0,0 +1,225 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spike is a spike test profile: the requests are sent at a baseline
+// rate, then at a much higher rate for a short while, then at the
+// baseline rate again, to see how the target copes with the spike and
+// recovers from it.
+type Spike struct {
+	// Base and Peak are the baseline and spike rates, in requests per
+	// second.
+	Base int
+	Peak int
+
+	// At is the start of the spike, relative to the start of the run,
+	// and For its duration.
+	At  time.Duration
+	For time.Duration
+
+	// Recovery is the duration of the baseline after the spike accounted
+	// by Requests. The requests after it are still sent at the baseline
+	// rate.
+	Recovery time.Duration
+}
+
+// ParseSpike parses a spike profile given as comma separated key=value
+// pairs, e.g. "base=100qps,spike=2000qps,at=60s,for=10s". The rates may
+// omit the "qps" suffix. An optional "recovery" duration defaults to
+// the duration of the baseline before the spike.
+func ParseSpike(s string) (*Spike, error) {
+	sp := &Spike{}
+	recovery := ""
+	for _, kv := range strings.Split(s, ",") {
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("spike profile %q is not a list of key=value", s)
+		}
+		key, value := strings.TrimSpace(kv[:i]), strings.TrimSpace(kv[i+1:])
+		var err error
+		switch key {
+		case "base":
+			sp.Base, err = strconv.Atoi(strings.TrimSuffix(value, "qps"))
+		case "spike":
+			sp.Peak, err = strconv.Atoi(strings.TrimSuffix(value, "qps"))
+		case "at":
+			sp.At, err = time.ParseDuration(value)
+		case "for":
+			sp.For, err = time.ParseDuration(value)
+		case "recovery":
+			recovery = value
+			sp.Recovery, err = time.ParseDuration(value)
+		default:
+			return nil, fmt.Errorf("unknown spike profile key %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid spike profile %s %q", key, value)
+		}
+	}
+	if recovery == "" {
+		sp.Recovery = sp.At
+	}
+	if err := sp.Validate(); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// Validate checks the rates and durations of the profile.
+func (s *Spike) Validate() error {
+	switch {
+	case s.Base <= 0 || s.Peak <= 0:
+		return errors.New("the spike profile requires positive base and spike rates")
+	case s.At <= 0 || s.For <= 0:
+		return errors.New("the spike profile requires positive at and for durations")
+	case s.Recovery < 0:
+		return errors.New("the spike profile recovery cannot be negative")
+	}
+	return nil
+}
+
+// Requests returns the number of requests of the profile, from the
+// start of the run to the end of the recovery.
+func (s *Spike) Requests() int {
+	return s.before() + s.during() + int(float64(s.Base)*s.Recovery.Seconds())
+}
+
+func (s *Spike) before() int {
+	return int(float64(s.Base) * s.At.Seconds())
+}
+
+func (s *Spike) during() int {
+	return int(float64(s.Peak) * s.For.Seconds())
+}
+
+// offset returns the time the i-th request is due at, from the start of
+// the run.
+func (s *Spike) offset(i int) time.Duration {
+	before, during := s.before(), s.during()
+	switch {
+	case i < before:
+		return time.Duration(i) * time.Second / time.Duration(s.Base)
+	case i < before+during:
+		return s.At + time.Duration(i-before)*time.Second/time.Duration(s.Peak)
+	}
+	return s.At + s.For + time.Duration(i-before-during)*time.Second/time.Duration(s.Base)
+}
+
+// window returns the window of a request started at offset: 0 before
+// the spike, 1 during it and 2 after it.
+func (s *Spike) window(offset time.Duration) int {
+	switch {
+	case offset < s.At:
+		return 0
+	case offset < s.At+s.For:
+		return 1
+	}
+	return 2
+}
+
+// SpikeWindow holds the statistics of the requests started in a window of
+// a spike test, see Boomer.Spike.
+type SpikeWindow struct {
+	// Name is "pre-spike", "spike" or "recovery".
+	Name string
+
+	// Start and End bound the window, relative to the start of the run.
+	Start time.Duration
+	End   time.Duration
+
+	// Qps is the rate the requests were sent at, and RPS the number of
+	// responses and errors per second.
+	Qps int
+	RPS float64
+
+	Breakdown
+}
+
+var spikeWindowNames = [...]string{"pre-spike", "spike", "recovery"}
+
+// spikeWindows accumulates the results of a spike test per window.
+type spikeWindows struct {
+	spike   *Spike
+	start   time.Time
+	windows [len(spikeWindowNames)]*breakdown
+}
+
+func newSpikeWindows(s *Spike, start time.Time) *spikeWindows {
+	w := &spikeWindows{spike: s, start: start}
+	for i := range w.windows {
+		w.windows[i] = newBreakdown()
+	}
+	return w
+}
+
+func (w *spikeWindows) add(res *result) {
+	var offset time.Duration
+	if res.start.After(w.start) {
+		offset = res.start.Sub(w.start)
+	}
+	w.windows[w.spike.window(offset)].add(res)
+}
+
+// build returns the windows, the recovery ending with the run.
+func (w *spikeWindows) build(total time.Duration, pctls []int) []SpikeWindow {
+	s := w.spike
+	bounds := [...]time.Duration{0, s.At, s.At + s.For, total}
+	rates := [...]int{s.Base, s.Peak, s.Base}
+	out := make([]SpikeWindow, len(w.windows))
+	for i, b := range w.windows {
+		end := bounds[i+1]
+		if end > total {
+			end = total
+		}
+		out[i] = SpikeWindow{
+			Name:      spikeWindowNames[i],
+			Start:     bounds[i],
+			End:       end,
+			Qps:       rates[i],
+			Breakdown: *b.build(pctls),
+		}
+		if d := end - bounds[i]; d > 0 {
+			out[i].RPS = float64(b.Count+b.Errors) / d.Seconds()
+		}
+	}
+	return out
+}
+
+func printSpike(w io.Writer, windows []SpikeWindow) {
+	fmt.Fprintf(w, "\nSpike:\n")
+	for _, s := range windows {
+		fmt.Fprintf(w, "  [%s]\t%v to %v at %d qps\t%s requests/sec, %s responses, %s errors", s.Name,
+			s.Start.Round(time.Millisecond), s.End.Round(time.Millisecond), s.Qps,
+			formatCount(s.RPS), formatCount(float64(s.Count)), formatCount(float64(s.Errors)))
+		if s.Count > 0 {
+			fmt.Fprintf(w, ", average %s", formatSeconds(s.Average.Seconds()))
+			for _, l := range s.Latencies {
+				if l.Percentage == 50 || l.Percentage == 99 {
+					fmt.Fprintf(w, ", p%d %s", l.Percentage, formatSeconds(l.Latency.Seconds()))
+				}
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseSpike(t *testing.T) {
+	s, err := ParseSpike("base=100qps,spike=2000qps,at=60s,for=10s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Spike{Base: 100, Peak: 2000, At: time.Minute, For: 10 * time.Second, Recovery: time.Minute}
+	if *s != want {
+		t.Errorf("Expected %+v, found %+v", want, *s)
+	}
+	if s.Requests() != 6000+20000+6000 {
+		t.Errorf("Expected 32000 requests, found %d", s.Requests())
+	}
+	if s, err = ParseSpike("base=10,spike=50,at=1s,for=1s,recovery=0s"); err != nil || s.Recovery != 0 {
+		t.Errorf("Expected no recovery, found %+v, %v", s, err)
+	}
+	for _, invalid := range []string{"", "base=100", "base=0,spike=10,at=1s,for=1s", "base=1,spike=1,at=1s,for=1s,x=1", "base=1qps,spike=a,at=1s,for=1s"} {
+		if _, err := ParseSpike(invalid); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestSpikeOffset(t *testing.T) {
+	s := &Spike{Base: 10, Peak: 100, At: time.Second, For: time.Second}
+	for i, want := range map[int]time.Duration{
+		0:   0,
+		9:   900 * time.Millisecond,
+		10:  time.Second,
+		109: 1990 * time.Millisecond,
+		110: 2 * time.Second,
+		111: 2100 * time.Millisecond,
+	} {
+		if got := s.offset(i); got != want {
+			t.Errorf("Expected request %d at %v, found %v", i, want, got)
+		}
+	}
+}
+
+func TestSpike(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	spike := &Spike{Base: 20, Peak: 200, At: 200 * time.Millisecond, For: 100 * time.Millisecond, Recovery: 200 * time.Millisecond}
+	boomer := &Boomer{
+		Request:  newGet(server.URL),
+		N:        spike.Requests(),
+		C:        4,
+		Spike:    spike,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Total < 450*time.Millisecond {
+		t.Errorf("Expected the requests to be paced by the profile, the run took %v", rep.Total)
+	}
+	if len(rep.Spike) != 3 {
+		t.Fatalf("Expected 3 windows, found %d", len(rep.Spike))
+	}
+	for i, want := range []struct {
+		name  string
+		count int64
+	}{{"pre-spike", 4}, {"spike", 20}, {"recovery", 4}} {
+		w := rep.Spike[i]
+		if w.Name != want.name || w.Count+w.Errors != want.count {
+			t.Errorf("Expected %d requests in the %s window, found %d in %s", want.count, want.name, w.Count+w.Errors, w.Name)
+		}
+	}
+	if rep.Spike[1].RPS <= rep.Spike[0].RPS {
+		t.Errorf("Expected the spike to be faster than the baseline, found %.1f and %.1f requests/sec", rep.Spike[1].RPS, rep.Spike[0].RPS)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Target is one of the requests of a mixed workload, see Boomer.Targets.
+type Target struct {
+	// Name identifies the target in the report, the results of several
+	// targets being broken down per endpoint. Defaults to the method and
+	// path template of the request, its numeric and UUID segments read
+	// as {id}: the targets of the same template, e.g. differing by their
+	// query or the id of a resource, are grouped.
+	Name string
+
+	// Request is the request to be made.
+	Request *fasthttp.Request
+
+	// Weight is the relative share of the requests sent to the target.
+	// Defaults to 1.
+	Weight int
+
+	// Priority is the priority class of the target, higher is more
+	// important. When a rate limit is set and the workers can't keep up
+	// with it, requests of the lower classes are shed first so that the
+	// highest class maintains its rate. Shed requests are not sent and
+	// are counted separately.
+	Priority int
+
+	// Tags classify the requests of the target along dimensions of their
+	// own, e.g. {"region": "eu"}. The report breaks down the results per
+	// tag, and they are carried by the Results and the Samples. They can
+	// not be named after the built-in breakdowns, see ReservedTag.
+	Tags map[string]string
+}
+
+// Dispatch is a scheduled request, see Boomer.Schedule.
+type Dispatch struct {
+	// Target is the index of the target in Boomer.Targets, zero for
+	// Boomer.Request.
+	Target int
+
+	// Offset is the time of the request from the start of the run.
+	Offset time.Duration
+}
+
+// targets returns the targets of the run, falling back to Request.
+func (b *Boomer) targets() []Target {
+	if len(b.Targets) > 0 {
+		return b.Targets
+	}
+	return []Target{{Request: b.Request}}
+}
+
+// endpointDimension breaks down the results per target, see Target.Name.
+const endpointDimension = "endpoint"
+
+// endpoint returns the name of the target in the report.
+func (t Target) endpoint() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return string(t.Request.Header.Method()) + " " + PathTemplate(string(t.Request.URI().Path()))
+}
+
+var idSegmentRegexp = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+
+// PathTemplate returns path with its numeric and UUID segments replaced
+// by {id}, e.g. /users/{id}/orders for /users/42/orders.
+func PathTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if idSegmentRegexp.MatchString(s) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// schedule returns the round-robin dispatching the targets in turn,
+// along with the labels of every target.
+func schedule(targets []Target) (*weightedRoundRobin, [][]label) {
+	prioritized := false
+	for _, t := range targets {
+		if t.Priority != targets[0].Priority {
+			prioritized = true
+		}
+	}
+	labels := make([][]label, len(targets))
+	for i, t := range targets {
+		if len(targets) > 1 {
+			labels[i] = append(labels[i], label{endpointDimension, t.endpoint()})
+		}
+		if prioritized {
+			labels[i] = append(labels[i], label{"priority", strconv.Itoa(t.Priority)})
+		}
+		labels[i] = append(labels[i], labelsOf(t.Tags)...)
+	}
+	return newWeightedRoundRobin(targets), labels
+}
+
+// weightedRoundRobin picks the targets in proportion to their weights,
+// interleaved rather than in blocks so that any number of requests gets
+// close to the mix: the smooth weighted round-robin of nginx.
+type weightedRoundRobin struct {
+	weights []int
+	current []int
+	total   int
+}
+
+func newWeightedRoundRobin(targets []Target) *weightedRoundRobin {
+	r := &weightedRoundRobin{weights: make([]int, len(targets)), current: make([]int, len(targets))}
+	for i, t := range targets {
+		r.weights[i] = t.Weight
+		if r.weights[i] < 1 {
+			r.weights[i] = 1
+		}
+		r.total += r.weights[i]
+	}
+	return r
+}
+
+// next returns the index of the next target.
+func (r *weightedRoundRobin) next() int {
+	best := 0
+	for i, w := range r.weights {
+		r.current[i] += w
+		if r.current[i] > r.current[best] {
+			best = i
+		}
+	}
+	r.current[best] -= r.total
+	return best
+}
+
+// skip skips the n next targets, e.g. those of the requests already made
+// by a resumed run. The sequence repeats every total picks.
+func (r *weightedRoundRobin) skip(n int) {
+	for i := 0; i < n%r.total; i++ {
+		r.next()
+	}
+}
+
+// ReservedTag reports whether name is the dimension of a built-in
+// breakdown, which the tags can not be named after.
+func ReservedTag(name string) bool {
+	switch name {
+	case statusDimension, endpointDimension, "priority", "auth":
+		return true
+	}
+	return false
+}
+
+// tagNames returns the names of tags in order.
+func tagNames(tags map[string]string) []string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// labelsOf returns the labels of m, e.g. of tags, in the order of their
+// dimensions.
+func labelsOf(m map[string]string) []label {
+	var labels []label
+	for _, name := range tagNames(m) {
+		labels = append(labels, label{name, m[name]})
+	}
+	return labels
+}
+
+// formatTags formats tags as name=value pairs separated by semicolons.
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for _, name := range tagNames(tags) {
+		pairs = append(pairs, name+"="+tags[name])
+	}
+	return strings.Join(pairs, ";")
+}
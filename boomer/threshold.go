@@ -0,0 +1,158 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Threshold is a condition on a metric of the report, e.g. p99<250ms.
+type Threshold struct {
+	Metric   string
+	Operator string
+	Value    float64
+
+	// expr is the threshold as given.
+	expr string
+}
+
+var thresholdRegexp = regexp.MustCompile(`^\s*([a-z0-9_]+)\s*(<=|>=|<|>)\s*(\S+)\s*$`)
+
+// ParseThresholds parses comma separated thresholds. The metrics are:
+//
+//	p10, p25, p50, p75, p90, p95, p99, min, max and avg, latencies given
+//	as durations, e.g. 250ms;
+//	error_rate, the share of errors and 5xx responses, e.g. 1% or 0.01;
+//	rps, the number of responses per second.
+func ParseThresholds(s string) ([]Threshold, error) {
+	var thresholds []Threshold
+	for _, expr := range strings.Split(s, ",") {
+		if strings.TrimSpace(expr) == "" {
+			continue
+		}
+		m := thresholdRegexp.FindStringSubmatch(expr)
+		if m == nil {
+			return nil, fmt.Errorf("could not parse the threshold %q", expr)
+		}
+		t := Threshold{Metric: m[1], Operator: m[2], expr: strings.TrimSpace(expr)}
+		var err error
+		switch {
+		case t.latency():
+			var d time.Duration
+			d, err = time.ParseDuration(m[3])
+			t.Value = d.Seconds()
+		case t.Metric == "error_rate":
+			v := strings.TrimSuffix(m[3], "%")
+			t.Value, err = strconv.ParseFloat(v, 64)
+			if v != m[3] {
+				t.Value /= 100
+			}
+		case t.Metric == "rps":
+			t.Value, err = strconv.ParseFloat(m[3], 64)
+		default:
+			return nil, fmt.Errorf("unknown metric %q in the threshold %q", t.Metric, expr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the value of the threshold %q", expr)
+		}
+		thresholds = append(thresholds, t)
+	}
+	return thresholds, nil
+}
+
+func (t Threshold) latency() bool {
+	switch t.Metric {
+	case "p10", "p25", "p50", "p75", "p90", "p95", "p99", "min", "max", "avg":
+		return true
+	}
+	return false
+}
+
+// String returns the threshold as given.
+func (t Threshold) String() string {
+	return t.expr
+}
+
+// Check returns whether r meets the threshold, along with the actual
+// value of the metric.
+func (t Threshold) Check(r *Report) (bool, string) {
+	v, actual := t.measure(r)
+	var ok bool
+	switch t.Operator {
+	case "<":
+		ok = v < t.Value
+	case "<=":
+		ok = v <= t.Value
+	case ">":
+		ok = v > t.Value
+	case ">=":
+		ok = v >= t.Value
+	}
+	return ok, actual
+}
+
+func (t Threshold) measure(r *Report) (float64, string) {
+	var d time.Duration
+	switch t.Metric {
+	case "error_rate":
+		var errors int64
+		for _, n := range r.ErrorDist {
+			errors += int64(n)
+		}
+		failed, total := errors, errors+r.Count
+		for code, n := range r.StatusCodeDist {
+			if code >= 500 {
+				failed += int64(n)
+			}
+		}
+		if total == 0 {
+			return 0, "0%"
+		}
+		rate := float64(failed) / float64(total)
+		return rate, fmt.Sprintf("%.2f%%", rate*100)
+	case "rps":
+		return r.RPS, formatCount(r.RPS)
+	case "min":
+		d = r.Fastest
+	case "max":
+		d = r.Slowest
+	case "avg":
+		d = r.Average
+	default:
+		p, _ := strconv.Atoi(strings.TrimPrefix(t.Metric, "p"))
+		for _, l := range r.Latencies {
+			if l.Percentage == p {
+				d = l.Latency
+			}
+		}
+	}
+	return d.Seconds(), formatSeconds(d.Seconds())
+}
+
+// CheckThresholds returns the thresholds r violates, each along with the
+// actual value of its metric.
+func CheckThresholds(r *Report, thresholds []Threshold) []string {
+	var violations []string
+	for _, t := range thresholds {
+		if ok, actual := t.Check(r); !ok {
+			violations = append(violations, fmt.Sprintf("%s (actual %s)", t, actual))
+		}
+	}
+	return violations
+}
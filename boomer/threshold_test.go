@@ -0,0 +1,72 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseThresholds(t *testing.T) {
+	thresholds, err := ParseThresholds("p99<250ms, error_rate<=1%,rps>500,avg >= 0.5s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Threshold{
+		{Metric: "p99", Operator: "<", Value: 0.25},
+		{Metric: "error_rate", Operator: "<=", Value: 0.01},
+		{Metric: "rps", Operator: ">", Value: 500},
+		{Metric: "avg", Operator: ">=", Value: 0.5},
+	}
+	if len(thresholds) != len(want) {
+		t.Fatalf("Expected %d thresholds, found %v", len(want), thresholds)
+	}
+	for i, w := range want {
+		got := thresholds[i]
+		if got.Metric != w.Metric || got.Operator != w.Operator || got.Value != w.Value {
+			t.Errorf("Expected %+v, found %+v", w, got)
+		}
+	}
+	for _, s := range []string{"p98<1s", "p99<fast", "rps=5", "error_rate<x%", "latency"} {
+		if _, err := ParseThresholds(s); err == nil {
+			t.Errorf("An invalid threshold %q passed parsing", s)
+		}
+	}
+}
+
+func TestCheckThresholds(t *testing.T) {
+	r := &Report{
+		Count:          98,
+		RPS:            600,
+		Average:        100 * time.Millisecond,
+		StatusCodeDist: map[int]int{200: 97, 503: 1},
+		ErrorDist:      map[string]int{"timeout": 2},
+		Latencies:      []LatencyDistribution{{Percentage: 99, Latency: 300 * time.Millisecond}},
+	}
+	thresholds, err := ParseThresholds("p99<250ms,error_rate<5%,rps>500,error_rate<2%,avg<=100ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations := CheckThresholds(r, thresholds)
+	want := []string{"p99<250ms (actual 300.000 ms)", "error_rate<2% (actual 3.00%)"}
+	if len(violations) != len(want) {
+		t.Fatalf("Expected violations %v, found %v", want, violations)
+	}
+	for i := range want {
+		if violations[i] != want[i] {
+			t.Errorf("Expected violation %q, found %q", want[i], violations[i])
+		}
+	}
+}
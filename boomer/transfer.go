@@ -0,0 +1,111 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Transfer describes the reading of the response bodies, when
+// Boomer.StreamBody is set.
+type Transfer struct {
+	// TimeToFirstByte is the average time until the headers of the
+	// responses were received, and Latencies its percentiles.
+	TimeToFirstByte time.Duration
+	Latencies       []LatencyDistribution
+
+	// TransferTime is the average time to read the bodies.
+	TransferTime time.Duration
+
+	// Truncated is the number of bodies larger than Boomer.MaxBodySize,
+	// which were not read in full.
+	Truncated int64
+}
+
+// readBody reads the streamed body of resp, discarding it, up to
+// MaxBodySize if set. It returns the number of bytes read and whether
+// the body was truncated.
+func (b *Boomer) readBody(resp *fasthttp.Response) (int64, bool, error) {
+	stream := resp.BodyStream()
+	if stream == nil {
+		return int64(len(resp.Body())), false, nil
+	}
+	if b.MaxBodySize > 0 {
+		// A byte more tells whether the body is larger.
+		stream = io.LimitReader(stream, b.MaxBodySize+1)
+	}
+	n, err := io.Copy(ioutil.Discard, stream)
+	if cerr := resp.CloseBodyStream(); err == nil {
+		err = cerr
+	}
+	if b.MaxBodySize > 0 && n > b.MaxBodySize {
+		return b.MaxBodySize, true, err
+	}
+	return n, false, err
+}
+
+// transfers accumulates the reading of the bodies of the successful
+// requests.
+type transfers struct {
+	count     int64
+	truncated int64
+	ttfb      time.Duration
+	transfer  time.Duration
+	lats      reservoir
+}
+
+func newTransfers() *transfers {
+	return &transfers{}
+}
+
+func (t *transfers) add(res *result) {
+	t.count++
+	t.ttfb += res.ttfb
+	t.transfer += res.duration - res.ttfb
+	t.lats.add(res.ttfb)
+	if res.truncated {
+		t.truncated++
+	}
+}
+
+func (t *transfers) build(pctls []int) *Transfer {
+	out := &Transfer{Truncated: t.truncated}
+	if t.count > 0 {
+		out.TimeToFirstByte = t.ttfb / time.Duration(t.count)
+		out.TransferTime = t.transfer / time.Duration(t.count)
+		out.Latencies = quantiles(&t.lats, pctls)
+	}
+	return out
+}
+
+func printTransfer(w io.Writer, t *Transfer) {
+	fmt.Fprintf(w, "\nTransfer:\n")
+	fmt.Fprintf(w, "  Time to first byte:\t%s average", formatSeconds(t.TimeToFirstByte.Seconds()))
+	for _, l := range t.Latencies {
+		if l.Percentage == 50 || l.Percentage == 99 {
+			fmt.Fprintf(w, ", p%d %s", l.Percentage, formatSeconds(l.Latency.Seconds()))
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  Transfer time:\t%s average\n", formatSeconds(t.TransferTime.Seconds()))
+	if t.Truncated > 0 {
+		fmt.Fprintf(w, "  Truncated bodies:\t%s\n", formatCount(float64(t.Truncated)))
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestStreamBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The body is chunked, and follows the headers after a while.
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+		w.Write(bytes.Repeat([]byte("a"), 100000))
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:    req,
+		N:          4,
+		C:          2,
+		StreamBody: true,
+		Renderer:   RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.Count != 4 || rep.SizeTotal != 400000 {
+		t.Fatalf("Expected 4 bodies of 100kB, found %d bytes (%v)", rep.SizeTotal, rep.ErrorDist)
+	}
+	if tr := rep.Transfer; tr.TimeToFirstByte > 40*time.Millisecond || tr.TransferTime < 40*time.Millisecond || tr.Truncated != 0 {
+		t.Errorf("Expected a fast first byte and a slow transfer, found %+v", tr)
+	}
+
+	boomer.MaxBodySize = 1000
+	rep = boomer.Run()
+	if rep.Count != 4 || rep.SizeTotal != 4000 || rep.Transfer.Truncated != 4 {
+		t.Errorf("Expected 4 bodies truncated to 1kB, found %d bytes and %+v", rep.SizeTotal, rep.Transfer)
+	}
+	if rep.Connections.Dialed != 4 {
+		t.Errorf("Expected the connections of the truncated bodies to be closed, found %+v", rep.Connections)
+	}
+}
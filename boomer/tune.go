@@ -0,0 +1,208 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultTuneInterval is the period of the concurrency controller,
+// unless Boomer.TuneInterval is set.
+const defaultTuneInterval = time.Second
+
+// Tuning describes how the concurrency was tuned to meet a latency
+// target, see Boomer.TargetP99.
+type Tuning struct {
+	Target time.Duration
+
+	// MaxThroughput is the highest number of responses per second of an
+	// interval whose 99th percentile latency met the target, reached
+	// with Concurrency workers. It is zero if the target was never met.
+	MaxThroughput float64
+	Concurrency   int
+
+	// Steps holds the decisions of the controller, one per interval.
+	Steps []TuningStep
+}
+
+// TuningStep is an interval of the concurrency controller.
+type TuningStep struct {
+	Offset      time.Duration
+	Concurrency int
+	P99         time.Duration
+	RPS         float64
+}
+
+// tuner limits the number of busy workers, adjusting the limit every
+// interval: it grows additively while the 99th percentile latency meets
+// the target, and shrinks multiplicatively otherwise.
+type tuner struct {
+	target   time.Duration
+	interval time.Duration
+	max      int
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	limit     int
+	active    int
+	done      bool
+	latencies reservoir
+	tuning    Tuning
+}
+
+func newTuner(target, interval time.Duration, max int) *tuner {
+	if interval <= 0 {
+		interval = defaultTuneInterval
+	}
+	t := &tuner{target: target, interval: interval, max: max, limit: 1}
+	t.cond = sync.NewCond(&t.mu)
+	t.tuning.Target = target
+	return t
+}
+
+// acquire waits for a worker to be allowed to make a request. It returns
+// false once the tuner is stopped. A nil tuner allows every request.
+func (t *tuner) acquire() bool {
+	if t == nil {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for !t.done && t.active >= t.limit {
+		t.cond.Wait()
+	}
+	if t.done {
+		return false
+	}
+	t.active++
+	return true
+}
+
+// release ends a request that took d, d is negative for failures.
+func (t *tuner) release(d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.active--
+	t.latencies.add(d)
+	t.mu.Unlock()
+	t.cond.Signal()
+}
+
+// leave gives back the permission of a worker that made no request.
+func (t *tuner) leave() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.active--
+	t.mu.Unlock()
+	t.cond.Signal()
+}
+
+// run adjusts the limit every interval until ctx is done.
+func (t *tuner) run(ctx context.Context) {
+	start := time.Now()
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			t.stop()
+			return
+		case now := <-ticker.C:
+			t.adjust(now.Sub(start))
+		}
+	}
+}
+
+func (t *tuner) adjust(offset time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lats, n := t.latencies.take()
+	if n == 0 {
+		return
+	}
+	step := TuningStep{
+		Offset:      offset,
+		Concurrency: t.limit,
+		P99:         p99(lats),
+		RPS:         float64(n) / t.interval.Seconds(),
+	}
+	t.tuning.Steps = append(t.tuning.Steps, step)
+	if step.P99 <= t.target {
+		if step.RPS > t.tuning.MaxThroughput {
+			t.tuning.MaxThroughput = step.RPS
+			t.tuning.Concurrency = t.limit
+		}
+		t.limit += 1 + t.limit/10
+		if t.limit > t.max {
+			t.limit = t.max
+		}
+		t.cond.Broadcast()
+	} else if t.limit = t.limit * 3 / 4; t.limit < 1 {
+		t.limit = 1
+	}
+}
+
+// p99 returns the 99th percentile of lats, sorting it. Failures, given
+// as negative latencies, count as the slowest requests.
+func p99(lats []time.Duration) time.Duration {
+	for i, d := range lats {
+		if d < 0 {
+			lats[i] = math.MaxInt64
+		}
+	}
+	sort.Slice(lats, func(i, j int) bool { return lats[i] < lats[j] })
+	return lats[len(lats)*99/100]
+}
+
+// stop releases the waiting workers for good.
+func (t *tuner) stop() {
+	t.mu.Lock()
+	t.done = true
+	t.mu.Unlock()
+	t.cond.Broadcast()
+}
+
+func (t *tuner) result() *Tuning {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tuning := t.tuning
+	return &tuning
+}
+
+func printTuning(w io.Writer, t *Tuning) {
+	fmt.Fprintf(w, "\nConcurrency tuning (p99 target %s):\n", formatSeconds(t.Target.Seconds()))
+	if t.MaxThroughput == 0 {
+		fmt.Fprintf(w, "  The target was never met.\n")
+	} else {
+		fmt.Fprintf(w, "  Max throughput:\t%s requests/sec with %d workers\n", formatCount(t.MaxThroughput), t.Concurrency)
+	}
+	for _, s := range t.Steps {
+		p99 := "failing"
+		if s.P99 < math.MaxInt64 {
+			p99 = formatSeconds(s.P99.Seconds())
+		}
+		fmt.Fprintf(w, "  %v\t%d workers\tp99 %s\t%s requests/sec\n", s.Offset.Truncate(time.Second), s.Concurrency, p99, formatCount(s.RPS))
+	}
+}
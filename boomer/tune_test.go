@@ -0,0 +1,82 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestTuner(t *testing.T) {
+	tu := newTuner(10*time.Millisecond, time.Second, 8)
+	for i := 0; i < 3; i++ {
+		for j := 0; j <= i; j++ {
+			if !tu.acquire() {
+				t.Fatal("Expected the tuner to allow requests")
+			}
+			tu.release(time.Millisecond)
+		}
+		tu.adjust(time.Duration(i) * time.Second)
+	}
+	if tu.limit != 4 || tu.tuning.Concurrency != 3 {
+		t.Errorf("Expected the limit to grow while meeting the target, found %d reaching %d", tu.limit, tu.tuning.Concurrency)
+	}
+	tu.release(-1)
+	tu.adjust(3 * time.Second)
+	if tu.limit != 3 {
+		t.Errorf("Expected the limit to shrink on failures, found %d", tu.limit)
+	}
+	tu.stop()
+	if tu.acquire() {
+		t.Errorf("Expected a stopped tuner to refuse requests")
+	}
+}
+
+func TestTargetP99(t *testing.T) {
+	var active int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&active, 1)
+		defer atomic.AddInt64(&active, -1)
+		time.Sleep(time.Duration(n) * 4 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:      req,
+		N:            1000000,
+		C:            16,
+		TargetP99:    14 * time.Millisecond,
+		TuneInterval: 100 * time.Millisecond,
+		Renderer:     RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	tuning := boomer.RunContext(ctx).Tuning
+	if tuning == nil || len(tuning.Steps) < 5 {
+		t.Fatalf("Expected the concurrency to be tuned, found %+v", tuning)
+	}
+	if tuning.MaxThroughput == 0 || tuning.Concurrency < 1 || tuning.Concurrency > 4 {
+		t.Errorf("Expected the target to be met with a few workers, found %d", tuning.Concurrency)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Upload describes the sending of the request bodies, when
+// Boomer.RequestBody is set.
+type Upload struct {
+	// Bytes is the number of bytes of the bodies sent.
+	Bytes int64
+
+	// UploadTime is the average time to send a body, from the start of
+	// the request until it was read in full.
+	UploadTime time.Duration
+
+	// Throughput is the average rate the bodies were sent at, in bytes
+	// per second.
+	Throughput float64
+}
+
+// FileBody returns a RequestBody streaming the file at path, opened again
+// for every request.
+func FileBody(path string) func() (io.Reader, error) {
+	return func() (io.Reader, error) {
+		return os.Open(path)
+	}
+}
+
+// uploadStream counts the bytes of a request body as fasthttp sends
+// them, and tells when the body was read in full.
+type uploadStream struct {
+	r    io.Reader
+	n    int64
+	done time.Time
+}
+
+func (s *uploadStream) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.n += int64(n)
+	if err == io.EOF && s.done.IsZero() {
+		s.done = time.Now()
+	}
+	return n, err
+}
+
+// Close closes the body once sent, or when the body of the request is
+// replaced.
+func (s *uploadStream) Close() error {
+	if c, ok := s.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// streamBody sets a body returned by RequestBody as the body of req, sent
+// with chunked transfer encoding.
+func (b *Boomer) streamBody(req *fasthttp.Request) (*uploadStream, error) {
+	r, err := b.RequestBody()
+	if err != nil {
+		return nil, err
+	}
+	s := &uploadStream{r: r}
+	req.SetBodyStream(s, -1)
+	return s, nil
+}
+
+// uploads accumulates the bodies sent by the successful requests.
+type uploads struct {
+	count int64
+	bytes int64
+	time  time.Duration
+}
+
+func (u *uploads) add(res *result) {
+	u.count++
+	u.bytes += res.uploaded
+	u.time += res.uploadTime
+}
+
+func (u *uploads) build() *Upload {
+	out := &Upload{Bytes: u.bytes}
+	if u.count > 0 {
+		out.UploadTime = u.time / time.Duration(u.count)
+	}
+	if u.time > 0 {
+		out.Throughput = float64(u.bytes) / u.time.Seconds()
+	}
+	return out
+}
+
+func printUpload(w io.Writer, u *Upload) {
+	fmt.Fprintf(w, "\nUpload:\n")
+	fmt.Fprintf(w, "  Sent:\t%s.\n", formatBytes(u.Bytes))
+	fmt.Fprintf(w, "  Upload time:\t%s average\n", formatSeconds(u.UploadTime.Seconds()))
+	fmt.Fprintf(w, "  Throughput:\t%s/sec\n", formatBytes(int64(u.Throughput)))
+}
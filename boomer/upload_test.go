@@ -0,0 +1,66 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestRequestBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(ioutil.Discard, r.Body)
+		if n != 100000 || len(r.TransferEncoding) == 0 || r.TransferEncoding[0] != "chunked" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "body")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("a"), 100000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("PUT")
+	boomer := &Boomer{
+		Request:     req,
+		N:           6,
+		C:           2,
+		RequestBody: FileBody(path),
+		Renderer:    RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if rep.StatusCodeDist[http.StatusOK] != 6 {
+		t.Fatalf("Expected 6 chunked bodies of 100kB, found %v %v", rep.StatusCodeDist, rep.ErrorDist)
+	}
+	if u := rep.Upload; u == nil || u.Bytes != 600000 || u.UploadTime <= 0 || u.Throughput <= 0 {
+		t.Errorf("Expected 600kB sent, found %+v", u)
+	}
+
+	boomer.RequestBody = FileBody(filepath.Join(t.TempDir(), "missing"))
+	rep = boomer.Run()
+	if rep.Count != 0 || len(rep.ErrorDist) == 0 {
+		t.Errorf("Expected the requests to fail without their body, found %v %v", rep.StatusCodeDist, rep.ErrorDist)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verbosity selects how much detail a report holds.
+type Verbosity int
+
+const (
+	// VerbosityAuto is VerbosityDetailed for runs of up to
+	// detailedRunSize requests and VerbosityAggregate otherwise.
+	VerbosityAuto Verbosity = iota
+
+	// VerbosityAggregate only keeps aggregated statistics, whose size
+	// does not depend on the number of requests.
+	VerbosityAggregate
+
+	// VerbosityDetailed also keeps a sample per request, exported by
+	// the csv output, and prints per-second statistics in the summary.
+	VerbosityDetailed
+)
+
+// detailedRunSize is the largest run that is detailed by default.
+const detailedRunSize = 10000
+
+// ParseVerbosity parses auto, aggregate or detailed.
+func ParseVerbosity(s string) (Verbosity, error) {
+	switch s {
+	case "", "auto":
+		return VerbosityAuto, nil
+	case "aggregate":
+		return VerbosityAggregate, nil
+	case "detailed":
+		return VerbosityDetailed, nil
+	}
+	return 0, fmt.Errorf("unknown verbosity %q", s)
+}
+
+// detailed returns whether a run of n requests is detailed.
+func (v Verbosity) detailed(n int) bool {
+	return v == VerbosityDetailed || v == VerbosityAuto && n <= detailedRunSize
+}
+
+// Sample is the outcome of a single request.
+type Sample struct {
+	// Time is the start of the request, and Offset the same relative to
+	// the start of the run.
+	Time       time.Time
+	Offset     time.Duration
+	Duration   time.Duration
+	StatusCode int
+	Err        string
+
+	// Bytes is the size of the response body, -1 if unknown.
+	Bytes int
+
+	// Tags are the Target.Tags of the request.
+	Tags map[string]string `json:",omitempty"`
+}
+
+// CSVFields are the columns of the csv output, in their default order:
+// the number of the request, its latency in seconds, its status code,
+// its error, its start as an RFC 3339 time and in seconds from the start
+// of the run, and the size of the response body in bytes. The tags of the
+// request, as name=value pairs separated by semicolons, follow in a tags
+// column if any request has tags, or if requested.
+var CSVFields = []string{"request", "latency", "status", "error", "time", "offset", "bytes"}
+
+var csvColumns = map[string]func(i int, s Sample) string{
+	"request": func(i int, s Sample) string { return strconv.Itoa(i + 1) },
+	"latency": func(i int, s Sample) string { return fmt.Sprintf("%4.4f", s.Duration.Seconds()) },
+	"status":  func(i int, s Sample) string { return strconv.Itoa(s.StatusCode) },
+	"error":   func(i int, s Sample) string { return strconv.Quote(s.Err) },
+	"time":    func(i int, s Sample) string { return s.Time.UTC().Format(time.RFC3339Nano) },
+	"offset":  func(i int, s Sample) string { return fmt.Sprintf("%.6f", s.Offset.Seconds()) },
+	"bytes": func(i int, s Sample) string {
+		if s.Bytes < 0 {
+			return ""
+		}
+		return strconv.Itoa(s.Bytes)
+	},
+	"tags": func(i int, s Sample) string { return strconv.Quote(formatTags(s.Tags)) },
+}
+
+// ParseCSVFields parses a comma separated list of CSVFields.
+func ParseCSVFields(s string) ([]string, error) {
+	var fields []string
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if _, ok := csvColumns[f]; !ok {
+			return nil, fmt.Errorf("unknown csv field %q; the fields are %s and tags", f, strings.Join(CSVFields, ", "))
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// CSVRenderer writes a header row and a line per request of a detailed
+// report, with the columns in Fields, all of CSVFields if empty.
+type CSVRenderer struct {
+	Fields []string
+}
+
+// Render implements Renderer.
+func (c CSVRenderer) Render(w io.Writer, r *Report) error {
+	if !r.Detailed {
+		return errors.New("the csv output needs a detailed report")
+	}
+	fields := c.Fields
+	if len(fields) == 0 {
+		fields = CSVFields
+		for _, s := range r.Samples {
+			if len(s.Tags) > 0 {
+				fields = append(fields[:len(fields):len(fields)], "tags")
+				break
+			}
+		}
+	}
+	columns := make([]func(int, Sample) string, len(fields))
+	for i, f := range fields {
+		if columns[i] = csvColumns[f]; columns[i] == nil {
+			return fmt.Errorf("unknown csv field %q", f)
+		}
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString(strings.Join(fields, ",") + "\n")
+	values := make([]string, len(columns))
+	for i, s := range r.Samples {
+		for j, column := range columns {
+			values[j] = column(i, s)
+		}
+		bw.WriteString(strings.Join(values, ",") + "\n")
+	}
+	return bw.Flush()
+}
+
+// renderCSV writes the csv output with the default columns.
+func renderCSV(w io.Writer, r *Report) error {
+	return CSVRenderer{}.Render(w, r)
+}
+
+func printTimeSeries(w io.Writer, r *Report) {
+	fmt.Fprintf(w, "\nPer second:\n")
+	for _, p := range r.TimeSeries {
+		fmt.Fprintf(w, "  %v\t%s responses\t%s errors\t%s average\n", p.Offset,
+			formatCount(float64(p.Count)), formatCount(float64(p.Errors)), formatSeconds(p.Average.Seconds()))
+	}
+}
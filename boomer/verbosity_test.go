@@ -0,0 +1,112 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestVerbosity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:  req,
+		N:        5,
+		C:        1,
+		Renderer: RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if !rep.Detailed || len(rep.Samples) != 5 {
+		t.Fatalf("Expected a small run to be detailed, found %d samples", len(rep.Samples))
+	}
+	var w bytes.Buffer
+	if err := renderCSV(&w, rep); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+	if len(lines) != 6 || !strings.HasPrefix(lines[5], "5,") || !strings.Contains(lines[5], `,200,""`) {
+		t.Errorf("Expected a header and a line per request, found %q", w.String())
+	}
+
+	boomer.Verbosity = VerbosityAggregate
+	rep = boomer.Run()
+	if rep.Detailed || rep.Samples != nil {
+		t.Errorf("Expected an aggregate report, found %d samples", len(rep.Samples))
+	}
+	if err := renderCSV(&w, rep); err == nil {
+		t.Errorf("Expected the csv output to need a detailed report")
+	}
+
+	if VerbosityAuto.detailed(detailedRunSize + 1) {
+		t.Errorf("Expected a large run not to be detailed by default")
+	}
+	if v, err := ParseVerbosity("detailed"); err != nil || !v.detailed(detailedRunSize+1) {
+		t.Errorf("Expected a detailed verbosity to apply to large runs")
+	}
+	if _, err := ParseVerbosity("loud"); err == nil {
+		t.Errorf("An invalid verbosity passed parsing")
+	}
+}
+
+func TestCSVFields(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	rep := &Report{
+		Detailed: true,
+		Samples: []Sample{
+			{Time: start, Duration: 1500 * time.Microsecond, StatusCode: 200, Bytes: 42},
+			{Time: start.Add(time.Second), Offset: time.Second, Err: `dial "x"`, Bytes: -1},
+		},
+	}
+	fields, err := ParseCSVFields("time, status,bytes,error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w bytes.Buffer
+	if err := (CSVRenderer{Fields: fields}).Render(&w, rep); err != nil {
+		t.Fatal(err)
+	}
+	want := "time,status,bytes,error\n" +
+		"2026-01-02T03:04:05Z,200,42,\"\"\n" +
+		"2026-01-02T03:04:06Z,0,,\"dial \\\"x\\\"\"\n"
+	if w.String() != want {
+		t.Errorf("Expected %q, found %q", want, w.String())
+	}
+
+	w.Reset()
+	renderCSV(&w, rep)
+	if header := strings.SplitN(w.String(), "\n", 2)[0]; header != strings.Join(CSVFields, ",") {
+		t.Errorf("Expected all the fields by default, found %q", header)
+	}
+	rep.Samples[1].Tags = map[string]string{"region": "eu"}
+	w.Reset()
+	renderCSV(&w, rep)
+	if header := strings.SplitN(w.String(), "\n", 2)[0]; header != strings.Join(CSVFields, ",")+",tags" {
+		t.Errorf("Expected the tags column with tagged requests, found %q", header)
+	}
+	if _, err := ParseCSVFields("latency,size"); err == nil {
+		t.Error("Expected an unknown field to fail")
+	}
+}
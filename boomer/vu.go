@@ -0,0 +1,66 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"fmt"
+	"io"
+)
+
+// VirtualUser describes what a worker, simulating a user, did during the
+// run.
+type VirtualUser struct {
+	// Iterations is the number of requests made.
+	Iterations int64
+
+	// Sessions is the number of sessions the user went through: one, plus
+	// one every time it was recycled after Boomer.MaxIterations requests.
+	Sessions int64
+}
+
+// session is the state a virtual user keeps between its requests.
+type session struct {
+	jar    *cookieJar
+	digest *digestState
+}
+
+// newSession returns the state of a new virtual user.
+func (b *Boomer) newSession() session {
+	var s session
+	if b.Cookies {
+		s.jar = newCookieJar()
+	}
+	if b.Digest != nil {
+		s.digest = &digestState{auth: b.Digest}
+	}
+	return s
+}
+
+func printVirtualUsers(w io.Writer, users []VirtualUser) {
+	var min, max, total, sessions int64
+	for i, u := range users {
+		if i == 0 || u.Iterations < min {
+			min = u.Iterations
+		}
+		if u.Iterations > max {
+			max = u.Iterations
+		}
+		total += u.Iterations
+		sessions += u.Sessions
+	}
+	fmt.Fprintf(w, "  Virtual Users:\t%s (%s sessions)\n", formatCount(float64(len(users))), formatCount(float64(sessions)))
+	fmt.Fprintf(w, "  Iterations per User:\t%s min, %s average, %s max\n", formatCount(float64(min)),
+		formatCount(float64(total)/float64(len(users))), formatCount(float64(max)))
+}
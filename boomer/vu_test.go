@@ -0,0 +1,63 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestMaxIterations(t *testing.T) {
+	var sessions int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err != nil {
+			atomic.AddInt64(&sessions, 1)
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := &Boomer{
+		Request:       req,
+		N:             12,
+		C:             2,
+		Cookies:       true,
+		MaxIterations: 3,
+		Renderer:      RendererFunc(func(io.Writer, *Report) error { return nil }),
+	}
+	rep := boomer.Run()
+	if len(rep.VirtualUsers) != 2 {
+		t.Fatalf("Expected 2 virtual users, found %+v", rep.VirtualUsers)
+	}
+	var iterations, reported int64
+	for _, u := range rep.VirtualUsers {
+		iterations += u.Iterations
+		reported += u.Sessions
+	}
+	if iterations != 12 {
+		t.Errorf("Expected 12 iterations, found %+v", rep.VirtualUsers)
+	}
+	if sessions != reported || sessions < 4 {
+		t.Errorf("Expected the %d reported sessions to be started, found %d", reported, sessions)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// WrkRenderer writes the summary of the report in the format of wrk, for
+// the scripts parsing its output. URL and Connections, the number of
+// concurrent workers, are printed in the header if set.
+type WrkRenderer struct {
+	URL         string
+	Connections int
+}
+
+// Render implements Renderer.
+func (wr WrkRenderer) Render(w io.Writer, r *Report) error {
+	bw := bufio.NewWriter(w)
+	if wr.URL != "" {
+		fmt.Fprintf(bw, "Running %s test @ %s\n", wrkTime(r.Total), wr.URL)
+	}
+	if wr.Connections > 0 {
+		// Every worker is both a thread and a connection of wrk.
+		fmt.Fprintf(bw, "  %d threads and %d connections\n", wr.Connections, wr.Connections)
+	}
+	fmt.Fprintf(bw, "  Thread Stats%6s%11s%8s%12s\n", "Avg", "Stdev", "Max", "+/- Stdev")
+	stdev, within := latencyStdev(r)
+	fmt.Fprintf(bw, "    %-10s", "Latency")
+	wrkUnits(bw, wrkTime(r.Average), 8)
+	wrkUnits(bw, wrkTime(stdev), 10)
+	wrkUnits(bw, wrkTime(r.Slowest), 9)
+	fmt.Fprintf(bw, "%8.2f%%\n", within*100)
+	if len(r.TimeSeries) > 0 && wr.Connections > 0 {
+		mean, stdev, max, within := rateStats(r.TimeSeries, wr.Connections)
+		fmt.Fprintf(bw, "    %-10s", "Req/Sec")
+		wrkUnits(bw, wrkMetric(mean), 8)
+		wrkUnits(bw, wrkMetric(stdev), 10)
+		wrkUnits(bw, wrkMetric(max), 9)
+		fmt.Fprintf(bw, "%8.2f%%\n", within*100)
+	}
+	if len(r.Latencies) > 0 {
+		fmt.Fprintf(bw, "  Latency Distribution\n")
+		for _, l := range r.Latencies {
+			fmt.Fprintf(bw, "%7d%%", l.Percentage)
+			wrkUnits(bw, wrkTime(l.Latency), 10)
+			fmt.Fprintf(bw, "\n")
+		}
+	}
+	errs := errorCount(r)
+	fmt.Fprintf(bw, "  %d requests in %s, %sB read\n", r.Count+int64(errs), wrkTime(r.Total), wrkBinary(float64(r.SizeTotal)))
+	if errs > 0 {
+		timeouts := r.ErrorCategories[ErrorTimeout]
+		connect := r.ErrorCategories[ErrorDNS] + r.ErrorCategories[ErrorTLS] + r.ErrorCategories[ErrorTooManyConnections]
+		fmt.Fprintf(bw, "  Socket errors: connect %d, read %d, write 0, timeout %d\n", connect, errs-timeouts-connect, timeouts)
+	}
+	non2xx := 0
+	for code, n := range r.StatusCodeDist {
+		if code >= 400 {
+			non2xx += n
+		}
+	}
+	if non2xx > 0 {
+		fmt.Fprintf(bw, "  Non-2xx or 3xx responses: %d\n", non2xx)
+	}
+	var transfer float64
+	if sec := r.Total.Seconds(); sec > 0 {
+		transfer = float64(r.SizeTotal) / sec
+	}
+	fmt.Fprintf(bw, "Requests/sec: %9.2f\n", r.RPS)
+	fmt.Fprintf(bw, "Transfer/sec: %10sB\n", wrkBinary(transfer))
+	return bw.Flush()
+}
+
+// errorCount returns the number of failed requests of r.
+func errorCount(r *Report) int {
+	n := 0
+	for _, c := range r.ErrorDist {
+		n += c
+	}
+	return n
+}
+
+// latencyStdev returns the standard deviation of the latencies of r, as
+// estimated from its histogram, and the fraction of the requests within
+// a standard deviation of the average.
+func latencyStdev(r *Report) (time.Duration, float64) {
+	var n, sum float64
+	avg := r.Average.Seconds()
+	for _, b := range r.Histogram {
+		d := b.Mark.Seconds() - avg
+		n += float64(b.Count)
+		sum += float64(b.Count) * d * d
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	stdev := math.Sqrt(sum / n)
+	var within float64
+	for _, b := range r.Histogram {
+		if math.Abs(b.Mark.Seconds()-avg) <= stdev {
+			within += float64(b.Count)
+		}
+	}
+	return secondsToDuration(stdev), within / n
+}
+
+// rateStats returns the average, standard deviation and maximum of the
+// per second rate of each of the workers, and the fraction of the
+// seconds within a standard deviation of the average.
+func rateStats(series []TimeSeriesPoint, workers int) (mean, stdev, max, within float64) {
+	rates := make([]float64, len(series))
+	for i, p := range series {
+		rates[i] = float64(p.Count) / float64(workers)
+		mean += rates[i]
+		if rates[i] > max {
+			max = rates[i]
+		}
+	}
+	mean /= float64(len(rates))
+	for _, v := range rates {
+		stdev += (v - mean) * (v - mean)
+	}
+	stdev = math.Sqrt(stdev / float64(len(rates)))
+	for _, v := range rates {
+		if math.Abs(v-mean) <= stdev {
+			within++
+		}
+	}
+	return mean, stdev, max, within / float64(len(rates))
+}
+
+// wrkUnits writes s right aligned in width columns, the unit suffix
+// overflowing into the two columns of padding that follow it.
+func wrkUnits(w io.Writer, s string, width int) {
+	pad := 2
+	for i := len(s) - 1; i >= len(s)-2 && i >= 0; i-- {
+		if c := s[i]; c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+			pad--
+		}
+	}
+	fmt.Fprintf(w, "%*s%*s", width-pad, s, pad, "")
+}
+
+// wrkTime formats d like wrk, e.g. 635.91us or 1.20s.
+func wrkTime(d time.Duration) string {
+	n := float64(d) / float64(time.Microsecond)
+	units := []struct {
+		scale float64
+		name  string
+	}{{1000, "ms"}, {1000, "s"}, {60, "m"}, {60, "h"}}
+	unit := "us"
+	for _, u := range units {
+		if n < u.scale {
+			break
+		}
+		n /= u.scale
+		unit = u.name
+	}
+	return fmt.Sprintf("%.2f%s", n, unit)
+}
+
+// wrkMetric formats n with a decimal k, M or G suffix, e.g. 56.20k.
+func wrkMetric(n float64) string {
+	return scaleUnits(n, 1000, []string{"", "k", "M", "G", "T"})
+}
+
+// wrkBinary formats n with a binary K, M or G suffix, e.g. 17.76G.
+func wrkBinary(n float64) string {
+	return scaleUnits(n, 1024, []string{"", "K", "M", "G", "T"})
+}
+
+func scaleUnits(n, scale float64, units []string) string {
+	i := 0
+	for n >= scale && i < len(units)-1 {
+		n /= scale
+		i++
+	}
+	return fmt.Sprintf("%.2f%s", n, units[i])
+}
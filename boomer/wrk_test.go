@@ -0,0 +1,89 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boomer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func compatReport() *Report {
+	return &Report{
+		Total:          2 * time.Second,
+		Fastest:        time.Millisecond,
+		Slowest:        9 * time.Millisecond,
+		Average:        3 * time.Millisecond,
+		Count:          1000,
+		RPS:            500,
+		SizeTotal:      2048000,
+		StatusCodeDist: map[int]int{200: 990, 503: 10},
+		ErrorDist:      map[string]int{"timeout": 3},
+		ErrorCategories: map[string]int{
+			ErrorTimeout: 3,
+		},
+		Histogram: []Bucket{{Mark: 2 * time.Millisecond, Count: 500}, {Mark: 4 * time.Millisecond, Count: 500}},
+		Latencies: []LatencyDistribution{{Percentage: 50, Latency: 2 * time.Millisecond}, {Percentage: 99, Latency: 8 * time.Millisecond}},
+	}
+}
+
+func TestWrkRenderer(t *testing.T) {
+	var w bytes.Buffer
+	if err := (WrkRenderer{URL: "http://localhost/", Connections: 10}).Render(&w, compatReport()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Running 2.00s test @ http://localhost/\n",
+		"  10 threads and 10 connections\n",
+		"    Latency     3.00ms    1.00ms   9.00ms  100.00%\n",
+		"     50%    2.00ms\n",
+		"  1003 requests in 2.00s, 1.95MB read\n",
+		"  Socket errors: connect 0, read 0, write 0, timeout 3\n",
+		"  Non-2xx or 3xx responses: 10\n",
+		"Requests/sec:    500.00\n",
+		"Transfer/sec:   1000.00KB\n",
+	} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("Expected %q in the wrk output, found:\n%s", want, w.String())
+		}
+	}
+}
+
+func TestABRenderer(t *testing.T) {
+	var w bytes.Buffer
+	if err := (ABRenderer{URL: "http://localhost:8080/a?b=c", Concurrency: 10}).Render(&w, compatReport()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Server Port:            8080\n",
+		"Document Path:          /a?b=c\n",
+		"Document Length:        2048 bytes\n",
+		"Concurrency Level:      10\n",
+		"Complete requests:      1003\n",
+		"Failed requests:        3\n",
+		"Non-2xx responses:      10\n",
+		"Requests per second:    500.00 [#/sec] (mean)\n",
+		"Time per request:       20.000 [ms] (mean)\n",
+		"Time per request:       2.000 [ms] (mean, across all concurrent requests)\n",
+		"Total:          1    3   1.0      2       9\n",
+		"  99%      8\n",
+		" 100%      9 (longest request)\n",
+	} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("Expected %q in the ab output, found:\n%s", want, w.String())
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// capture is a proxy writing the requests passing through it to a
+// targets file, see `pla record`. It is a reverse proxy to upstream if
+// set, and a forward proxy for plain http otherwise.
+type capture struct {
+	upstream *url.URL
+	proxy    *httputil.ReverseProxy
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newCapture(upstream *url.URL, w io.Writer) *capture {
+	c := &capture{upstream: upstream, w: w}
+	c.proxy = &httputil.ReverseProxy{Director: func(r *http.Request) {
+		r.URL = c.target(r)
+		if upstream != nil {
+			r.Host = upstream.Host
+		}
+	}}
+	return c
+}
+
+// target returns the url r is proxied to.
+func (c *capture) target(r *http.Request) *url.URL {
+	u := *r.URL
+	if c.upstream != nil {
+		u.Scheme, u.Host = c.upstream.Scheme, c.upstream.Host
+		u.Path = strings.TrimSuffix(c.upstream.Path, "/") + u.Path
+		u.RawPath = ""
+	}
+	return &u
+}
+
+func (c *capture) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		http.Error(w, "https can not be recorded through CONNECT, use -upstream", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	u := c.target(r)
+	if u.Host == "" {
+		http.Error(w, "not a proxy request, use -upstream for a reverse proxy", http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	fmt.Fprintln(c.w, targetLine(r.Method, u.String(), r.Header.Get("Content-Type"), string(body)))
+	c.mu.Unlock()
+	c.proxy.ServeHTTP(w, r)
+}
+
+// runRecord runs `pla record` with its arguments.
+func runRecord(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	fs.Usage = flag.Usage
+	listen := fs.String("listen", ":8080", "")
+	out := fs.String("out", "", "")
+	upstreamURL := fs.String("upstream", "", "")
+	fs.Parse(args)
+	if *out == "" || fs.NArg() > 0 {
+		return fmt.Errorf("record requires -out and no other argument")
+	}
+	var upstream *url.URL
+	if *upstreamURL != "" {
+		var err error
+		if upstream, err = url.Parse(*upstreamURL); err != nil || upstream.Host == "" {
+			return fmt.Errorf("invalid -upstream %q", *upstreamURL)
+		}
+	}
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintf(os.Stderr, "pla recording on %s to %s\n", *listen, *out)
+	return http.ListenAndServe(*listen, newCapture(upstream, f))
+}
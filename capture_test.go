@@ -0,0 +1,90 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestCapture(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer upstream.Close()
+	u := mustParseURL(upstream.URL + "/api")
+
+	var out bytes.Buffer
+	proxy := httptest.NewServer(newCapture(u, &out))
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL + "/users?page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	resp.Body.Close()
+	if body.String() != "/api/users" {
+		t.Errorf("Expected the request to be proxied upstream, found %q", body.String())
+	}
+	if _, err := http.Post(proxy.URL+"/users", "application/json", strings.NewReader(`{"a": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "GET " + upstream.URL + "/api/users?page=2\n" +
+		"POST " + upstream.URL + "/api/users header=Content-Type:application%2Fjson body=%7B%22a%22:%201%7D\n"
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\nfound:\n%s", expected, out.String())
+	}
+	if _, err := parseTargets(&out, &fasthttp.Request{}); err != nil {
+		t.Errorf("Expected a valid targets file: %v", err)
+	}
+
+	// Without upstream, only proxy requests are accepted.
+	direct := httptest.NewServer(newCapture(nil, &out))
+	defer direct.Close()
+	resp, err = http.Get(direct.URL + "/users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a bad request, found %d", resp.StatusCode)
+	}
+	out.Reset()
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(mustParseURL(direct.URL))}}
+	if resp, err = client.Get(upstream.URL + "/x"); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if out.String() != "GET "+upstream.URL+"/x\n" {
+		t.Errorf("Expected the forwarded request to be recorded, found %q", out.String())
+	}
+}
+
+func mustParseURL(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
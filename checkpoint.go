@@ -0,0 +1,138 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sschepens/pla/boomer"
+)
+
+// openCheckpoint opens the checkpoint file at path, creating it if
+// needed, and returns it positioned after its last complete result, with
+// a writer for the next results and the number of results it holds. A
+// result cut by a crash is dropped.
+func openCheckpoint(path string) (*os.File, *boomer.ResultWriter, int, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, 0, err
+	}
+	if info.Size() == 0 {
+		return f, boomer.NewResultWriter(f), 0, nil
+	}
+	r := boomer.NewResultReader(f)
+	n := 0
+	for {
+		if _, err := r.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			if r.Offset() == 0 {
+				f.Close()
+				return nil, nil, 0, fmt.Errorf("%s: %v", path, err)
+			}
+			break
+		}
+		n++
+	}
+	if err := f.Truncate(r.Offset()); err != nil {
+		f.Close()
+		return nil, nil, 0, err
+	}
+	if _, err := f.Seek(r.Offset(), io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, 0, err
+	}
+	return f, boomer.AppendResultWriter(f, r), n, nil
+}
+
+// runCheckpointed runs b, resuming from the results in the checkpoint
+// file at path and recording the new ones there, synced to disk every
+// interval, see -checkpoint. The report of all the results of the file is
+// rendered and returned.
+func runCheckpointed(b *boomer.Boomer, path string, interval time.Duration) (*boomer.Report, error) {
+	f, w, done, err := openCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	if remaining := b.N - done; remaining > 0 {
+		if done > 0 && !b.Quiet {
+			fmt.Fprintf(os.Stderr, "Resuming from %s: %d of %d requests done\n", path, done, b.N)
+		}
+		n, renderer := b.N, b.Renderer
+		b.N, b.Skip = remaining, done
+		b.Renderer = boomer.RendererFunc(func(io.Writer, *boomer.Report) error { return nil })
+		results := b.Results()
+		written := make(chan error, 1)
+		go func() {
+			written <- writeCheckpoint(f, w, results, interval)
+		}()
+		b.Run()
+		b.N, b.Skip, b.Renderer = n, 0, renderer
+		if err := <-written; err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	// The report of the file has no progress bar, the run had one.
+	quiet := b.Quiet
+	b.Quiet = true
+	defer func() { b.Quiet = quiet }()
+	return replay(b, path)
+}
+
+// writeCheckpoint writes results to f with w until results is closed,
+// syncing f every interval.
+func writeCheckpoint(f *os.File, w *boomer.ResultWriter, results <-chan boomer.Result, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	var err error
+	for {
+		select {
+		case res, ok := <-results:
+			if !ok {
+				if err == nil {
+					err = w.Flush()
+				}
+				if err == nil {
+					err = f.Sync()
+				}
+				return err
+			}
+			// Keep draining the results on errors, the run waits for
+			// them to be received.
+			if err == nil {
+				err = w.Write(res)
+			}
+		case <-t.C:
+			if err == nil {
+				err = w.Flush()
+			}
+			if err == nil {
+				err = f.Sync()
+			}
+		}
+	}
+}
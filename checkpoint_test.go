@@ -0,0 +1,114 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCheckpoint(t *testing.T) {
+	var served int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&served, 1)
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "pla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoint.bin")
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	var rendered int
+	renderer := boomer.RendererFunc(func(io.Writer, *boomer.Report) error {
+		rendered++
+		return nil
+	})
+	b := &boomer.Boomer{Request: req, N: 30, C: 2, Quiet: true, Renderer: renderer}
+	if _, err := runCheckpointed(b, path, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash cuts the last result.
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+	b.N = 50
+	report, err := runCheckpointed(b, path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Count != 50 || served != 51 {
+		t.Errorf("Expected 50 requests reported of the 51 served, found %d of %d", report.Count, served)
+	}
+	if rendered != 2 || b.N != 50 || b.Renderer == nil {
+		t.Errorf("Expected only the reports of the whole file to be rendered, found %d", rendered)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("not results"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCheckpointed(b, path, time.Second); err == nil {
+		t.Error("Expected a file of another format to be rejected")
+	}
+}
+
+func TestCheckpointLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "pla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var targets []boomer.Target
+	for _, path := range []string{"/a", "/b"} {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(server.URL + path)
+		targets = append(targets, boomer.Target{Request: req, Tags: map[string]string{"region": "eu"}})
+	}
+	b := &boomer.Boomer{
+		Targets:  targets,
+		N:        10,
+		C:        1,
+		Quiet:    true,
+		Renderer: boomer.RendererFunc(func(io.Writer, *boomer.Report) error { return nil }),
+	}
+	report, err := runCheckpointed(b, filepath.Join(dir, "checkpoint.bin"), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Breakdowns["endpoint"]) != 2 || report.Breakdowns["region"]["eu"] == nil || report.Breakdowns["region"]["eu"].Count != 10 {
+		t.Errorf("Expected the breakdowns to be kept by the checkpoint, found %v", report.Breakdowns)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/sschepens/pla/boomer"
+)
+
+// compare returns the changes from the run at base to the run at head,
+// see `pla compare`.
+func compare(base, head string, tol boomer.Tolerance, pctls []int) ([]boomer.Change, error) {
+	b, err := loadReport(base, pctls)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", base, err)
+	}
+	h, err := loadReport(head, pctls)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", head, err)
+	}
+	return boomer.Compare(b, h, tol), nil
+}
+
+// loadReport returns the report of a run given as a json report, or as
+// its recorded results, reported with pctls.
+func loadReport(path string, pctls []int) (*boomer.Report, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if json.Valid(data) {
+		return boomer.ReadReport(data)
+	}
+	return replay(&boomer.Boomer{
+		Output:      "json",
+		Renderer:    boomer.RendererFunc(func(io.Writer, *boomer.Report) error { return nil }),
+		Verbosity:   boomer.VerbosityAggregate,
+		Percentiles: pctls,
+	}, path)
+}
@@ -0,0 +1,76 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sschepens/pla/boomer"
+)
+
+func TestCompare(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The baseline is a json report, the new run recorded results.
+	base := filepath.Join(dir, "base.json")
+	f, err := os.Create(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boomer.JSONRenderer{}.Render(f, &boomer.Report{
+		RPS:            100,
+		Average:        10 * time.Millisecond,
+		Count:          100,
+		StatusCodeDist: map[int]int{200: 100},
+	})
+	f.Close()
+
+	head := filepath.Join(dir, "head.bin")
+	f, err = os.Create(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := boomer.NewResultWriter(f)
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		w.Write(boomer.Result{Start: start.Add(time.Duration(i) * 100 * time.Millisecond), Duration: 20 * time.Millisecond, StatusCode: 200})
+	}
+	w.Flush()
+	f.Close()
+
+	changes, err := compare(base, head, boomer.Tolerance{Latency: 0.1, Throughput: 0.1, ErrorRate: 0.01}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	regressions := map[string]bool{}
+	for _, c := range changes {
+		regressions[c.Metric] = c.Regression
+	}
+	if !regressions["rps"] || !regressions["avg"] || regressions["error_rate"] {
+		t.Errorf("Expected throughput and latency regressions, found %+v", changes)
+	}
+
+	if _, err := compare(base, filepath.Join(dir, "missing"), boomer.Tolerance{}, nil); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// config holds the options read from a config file, see -config. The
+// keys are the names of the flags, along with url for the url argument.
+// Profiles are named sets of options overriding them.
+type config struct {
+	options  map[string][]string
+	profiles map[string]map[string][]string
+}
+
+// configLine is a significant line of a config file.
+type configLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// parseConfig parses a config file in the subset of YAML made of
+// "key: value" mappings, nested by indentation, and "- item" lists, e.g.
+//
+//	c: 50
+//	H:
+//	  - "Accept: application/json"
+//	profiles:
+//	  staging:
+//	    url: https://staging.example.com/
+//
+// The values may be quoted, and the lines starting with # are comments.
+func parseConfig(r io.Reader) (*config, error) {
+	var lines []configLine
+	s := bufio.NewScanner(r)
+	for num := 1; s.Scan(); num++ {
+		text := strings.TrimRight(s.Text(), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs cannot be used for indentation", num)
+		}
+		lines = append(lines, configLine{num: num, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return &config{options: map[string][]string{}}, nil
+	}
+	root, i, err := parseConfigMap(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if i < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[i].num)
+	}
+	c := &config{profiles: make(map[string]map[string][]string)}
+	if c.options, err = configOptions(root); err != nil {
+		return nil, err
+	}
+	if profiles, ok := root["profiles"]; ok {
+		m, ok := profiles.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("profiles must be a mapping of names to options")
+		}
+		for name, p := range m {
+			pm, ok := p.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("profile %q must be a mapping of options", name)
+			}
+			if c.profiles[name], err = configOptions(pm); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// parseConfigMap parses the "key: value" lines at indent from lines[i].
+// It returns the mapping and the index of the first line past it.
+func parseConfigMap(lines []configLine, i, indent int) (map[string]interface{}, int, error) {
+	m := make(map[string]interface{})
+	for i < len(lines) && lines[i].indent == indent {
+		l := lines[i]
+		if strings.HasPrefix(l.text, "- ") || l.text == "-" {
+			return nil, i, fmt.Errorf("line %d: unexpected list item", l.num)
+		}
+		key, value := l.text, ""
+		if j := strings.Index(l.text, ": "); j > 0 {
+			key, value = l.text[:j], strings.TrimSpace(l.text[j+2:])
+		} else if strings.HasSuffix(l.text, ":") {
+			key = l.text[:len(l.text)-1]
+		} else {
+			return nil, i, fmt.Errorf("line %d: expected key: value", l.num)
+		}
+		key = strings.TrimSpace(key)
+		if _, dup := m[key]; dup {
+			return nil, i, fmt.Errorf("line %d: duplicate key %q", l.num, key)
+		}
+		i++
+		if value != "" {
+			v, err := unquoteConfig(value)
+			if err != nil {
+				return nil, i, fmt.Errorf("line %d: %v", l.num, err)
+			}
+			m[key] = v
+			continue
+		}
+		switch {
+		case i < len(lines) && lines[i].indent >= indent && strings.HasPrefix(lines[i].text, "-"):
+			var items []string
+			itemIndent := lines[i].indent
+			for i < len(lines) && lines[i].indent == itemIndent && strings.HasPrefix(lines[i].text, "-") {
+				v, err := unquoteConfig(strings.TrimSpace(lines[i].text[1:]))
+				if err != nil {
+					return nil, i, fmt.Errorf("line %d: %v", lines[i].num, err)
+				}
+				items = append(items, v)
+				i++
+			}
+			m[key] = items
+		case i < len(lines) && lines[i].indent > indent:
+			var err error
+			if m[key], i, err = parseConfigMap(lines, i, lines[i].indent); err != nil {
+				return nil, i, err
+			}
+		default:
+			m[key] = ""
+		}
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, i, fmt.Errorf("line %d: unexpected indentation", lines[i].num)
+	}
+	return m, i, nil
+}
+
+// unquoteConfig returns the value of a scalar, unquoting it if needed.
+func unquoteConfig(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1), nil
+	}
+	return s, nil
+}
+
+// configOptions returns the options of a mapping, every value as a list.
+func configOptions(m map[string]interface{}) (map[string][]string, error) {
+	options := make(map[string][]string)
+	for key, v := range m {
+		switch v := v.(type) {
+		case string:
+			options[key] = []string{v}
+		case []string:
+			options[key] = v
+		default:
+			if key != "profiles" {
+				return nil, fmt.Errorf("option %q must be a value or a list", key)
+			}
+		}
+	}
+	return options, nil
+}
+
+// apply sets the options of the config, then those of profile if set,
+// on the flags of fs not set on the command line, and returns the url
+// argument of the config, if any. The ${NAME} references of the values
+// are expanded with secrets or the environment.
+func (c *config) apply(fs *flag.FlagSet, profile string, secrets map[string]string) (string, error) {
+	options := make(map[string][]string)
+	for k, v := range c.options {
+		options[k] = v
+	}
+	if profile != "" {
+		p, ok := c.profiles[profile]
+		if !ok {
+			return "", fmt.Errorf("unknown profile %q", profile)
+		}
+		for k, v := range p {
+			options[k] = v
+		}
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	var url string
+	for name, values := range options {
+		expanded := make([]string, len(values))
+		for i, v := range values {
+			var err error
+			if expanded[i], err = expandVars(v, secrets); err != nil {
+				return "", fmt.Errorf("option %s: %v", name, err)
+			}
+		}
+		values = expanded
+		if name == "url" {
+			if len(values) != 1 {
+				return "", fmt.Errorf("url must be a single value")
+			}
+			url = values[0]
+			continue
+		}
+		if name == "config" || name == "profile" || name == "secret-file" {
+			return "", fmt.Errorf("%s cannot be set in a config file", name)
+		}
+		if fs.Lookup(name) == nil {
+			return "", fmt.Errorf("unknown option %q", name)
+		}
+		if set[name] {
+			continue
+		}
+		for _, v := range values {
+			if err := fs.Set(name, v); err != nil {
+				return "", fmt.Errorf("invalid value %q for option %s: %v", v, name, err)
+			}
+		}
+	}
+	return url, nil
+}
+
+// loadConfig applies the config file at path, or ~/.pla.yaml if path is
+// empty and a profile is selected, to the flags of fs. It returns the url
+// argument of the config, if any.
+func loadConfig(fs *flag.FlagSet, path, profile string, secrets map[string]string) (string, error) {
+	if path == "" {
+		if profile == "" {
+			return "", nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, ".pla.yaml")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	c, err := parseConfig(f)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	url, err := c.apply(fs, profile, secrets)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	return url, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testConfig = `# A load test.
+c: 50
+n: "1000"
+H:
+  - "Accept: application/json"
+  - 'X-Quote: it''s'
+url: https://example.com/
+profiles:
+  staging:
+    url: https://staging.example.com/
+    c: 10
+    H:
+    - "X-Env: staging"
+`
+
+func TestParseConfig(t *testing.T) {
+	c, err := parseConfig(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"c":   {"50"},
+		"n":   {"1000"},
+		"H":   {"Accept: application/json", "X-Quote: it's"},
+		"url": {"https://example.com/"},
+	}
+	if !reflect.DeepEqual(c.options, want) {
+		t.Errorf("Expected %v, found %v", want, c.options)
+	}
+	staging := map[string][]string{
+		"url": {"https://staging.example.com/"},
+		"c":   {"10"},
+		"H":   {"X-Env: staging"},
+	}
+	if !reflect.DeepEqual(c.profiles["staging"], staging) {
+		t.Errorf("Expected %v, found %v", staging, c.profiles["staging"])
+	}
+
+	for _, invalid := range []string{"c 50", "c: 50\n  n: 1", "c: 50\nc: 60", "- a", "H: \"a"} {
+		if _, err := parseConfig(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	c, err := parseConfig(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newFlags := func(args ...string) (*flag.FlagSet, *int, *int, *stringSlice) {
+		fs := flag.NewFlagSet("pla", flag.ContinueOnError)
+		conc, num := fs.Int("c", 1, ""), fs.Int("n", 1, "")
+		var headers stringSlice
+		fs.Var(&headers, "H", "")
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return fs, conc, num, &headers
+	}
+
+	fs, conc, num, headers := newFlags("-c", "5")
+	url, err := c.apply(fs, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *conc != 5 || *num != 1000 || len(*headers) != 2 || url != "https://example.com/" {
+		t.Errorf("Expected the command line to take precedence, found c %d, n %d, %v and %s", *conc, *num, *headers, url)
+	}
+
+	fs, conc, _, headers = newFlags()
+	if url, err = c.apply(fs, "staging", nil); err != nil {
+		t.Fatal(err)
+	}
+	if *conc != 10 || strings.Join(*headers, ",") != "X-Env: staging" || url != "https://staging.example.com/" {
+		t.Errorf("Expected the profile to take precedence, found c %d, %v and %s", *conc, *headers, url)
+	}
+
+	fs, _, _, _ = newFlags()
+	if _, err := c.apply(fs, "production", nil); err == nil {
+		t.Error("Expected an unknown profile to fail")
+	}
+	secret, _ := parseConfig(strings.NewReader("H: \"Authorization: ${API_KEY}\""))
+	fs, _, _, headers = newFlags()
+	if _, err := secret.apply(fs, "", map[string]string{"API_KEY": "s3cr3t"}); err != nil || (*headers)[0] != "Authorization: s3cr3t" {
+		t.Errorf("Expected the secret to be expanded, found %v and %v", *headers, err)
+	}
+	fs, _, _, _ = newFlags()
+	if _, err := secret.apply(fs, "", nil); err == nil {
+		t.Error("Expected an undefined variable to fail")
+	}
+	unknown, _ := parseConfig(strings.NewReader("z: 1"))
+	if _, err := unknown.apply(fs, "", nil); err == nil {
+		t.Error("Expected an unknown option to fail")
+	}
+}
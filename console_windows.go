@@ -0,0 +1,28 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "syscall"
+
+var setConsoleCtrlHandler = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleCtrlHandler")
+
+// enableInterrupts lets Ctrl+C through to the process, the programs
+// started by some shells and launchers inheriting a console ignoring it.
+// Ctrl+C and Ctrl+Break are then received as os.Interrupt, and closing
+// the console as SIGTERM.
+func enableInterrupts() {
+	// A nil handler with FALSE restores the processing of Ctrl+C.
+	setConsoleCtrlHandler.Call(0, 0)
+}
@@ -0,0 +1,235 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// curlRequest is the request of a curl command line, see -from-curl.
+type curlRequest struct {
+	URL      string
+	Method   string
+	Headers  [][2]string
+	Body     string
+	Insecure bool
+}
+
+// curlArgs are the curl options taking an argument, by short and long
+// names.
+var curlArgs = map[string]string{
+	"-X": "--request", "-H": "--header", "-d": "--data", "-u": "--user",
+	"-A": "--user-agent", "-b": "--cookie", "-e": "--referer",
+	"--data-raw": "", "--data-binary": "", "--data-ascii": "",
+	"--data-urlencode": "", "--json": "", "--url": "",
+	"--request": "", "--header": "", "--data": "", "--user": "",
+	"--user-agent": "", "--cookie": "", "--referer": "",
+}
+
+// curlFlags are the curl options without argument that are supported, or
+// harmlessly ignored.
+var curlFlags = map[string]string{
+	"-k": "--insecure", "-I": "--head", "-s": "", "-S": "", "-v": "",
+	"-i": "", "-L": "", "--insecure": "", "--head": "", "--silent": "",
+	"--show-error": "", "--verbose": "", "--include": "", "--location": "",
+	"--compressed": "",
+}
+
+// parseCurl parses a curl command line, as pasted from a shell, into the
+// request it sends.
+func parseCurl(cmd string) (*curlRequest, error) {
+	words, err := splitShellWords(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 || words[0] != "curl" {
+		return nil, fmt.Errorf("expected a curl command")
+	}
+	c := &curlRequest{}
+	var data []string
+	var method, contentType string
+	for i := 1; i < len(words); i++ {
+		w := words[i]
+		if !strings.HasPrefix(w, "-") || w == "-" {
+			if c.URL != "" {
+				return nil, fmt.Errorf("curl: unexpected argument %q", w)
+			}
+			c.URL = w
+			continue
+		}
+		name, value, hasValue := w, "", false
+		if strings.HasPrefix(w, "--") {
+			if kv := strings.SplitN(w, "=", 2); len(kv) == 2 {
+				name, value, hasValue = kv[0], kv[1], true
+			}
+		} else if len(w) > 2 {
+			name, value, hasValue = w[:2], w[2:], true
+		}
+		if long, ok := curlFlags[name]; ok {
+			// Short flags can be grouped, e.g. -sSk.
+			if hasValue && !strings.HasPrefix(w, "--") {
+				words = append(words[:i+1], append([]string{"-" + value}, words[i+1:]...)...)
+			}
+			if long != "" {
+				name = long
+			}
+			switch name {
+			case "--insecure":
+				c.Insecure = true
+			case "--head":
+				method = "HEAD"
+			}
+			continue
+		}
+		long, ok := curlArgs[name]
+		if !ok {
+			return nil, fmt.Errorf("curl: unsupported option %s", name)
+		}
+		if long != "" {
+			name = long
+		}
+		if !hasValue {
+			if i++; i == len(words) {
+				return nil, fmt.Errorf("curl: missing argument of %s", name)
+			}
+			value = words[i]
+		}
+		switch name {
+		case "--url":
+			c.URL = value
+		case "--request":
+			method = strings.ToUpper(value)
+		case "--header":
+			kv := strings.SplitN(value, ":", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("curl: invalid header %q", value)
+			}
+			k, v := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+			if strings.EqualFold(k, "Content-Type") {
+				contentType = v
+				continue
+			}
+			c.Headers = append(c.Headers, [2]string{k, v})
+		case "--data", "--data-raw", "--data-binary", "--data-ascii":
+			if strings.HasPrefix(value, "@") && name != "--data-raw" {
+				return nil, fmt.Errorf("curl: reading the data from a file is not supported")
+			}
+			data = append(data, value)
+		case "--data-urlencode":
+			if kv := strings.SplitN(value, "=", 2); len(kv) == 2 {
+				data = append(data, kv[0]+"="+url.QueryEscape(kv[1]))
+			} else {
+				data = append(data, url.QueryEscape(value))
+			}
+		case "--json":
+			data = append(data, value)
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			c.Headers = append(c.Headers, [2]string{"Accept", "application/json"})
+		case "--user":
+			c.Headers = append(c.Headers, [2]string{"Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte(value))})
+		case "--user-agent":
+			c.Headers = append(c.Headers, [2]string{"User-Agent", value})
+		case "--cookie":
+			c.Headers = append(c.Headers, [2]string{"Cookie", value})
+		case "--referer":
+			c.Headers = append(c.Headers, [2]string{"Referer", value})
+		}
+	}
+	if c.URL == "" {
+		return nil, fmt.Errorf("curl: missing url")
+	}
+	if !strings.Contains(c.URL, "://") {
+		c.URL = "http://" + c.URL
+	}
+	c.Method = "GET"
+	if len(data) > 0 {
+		c.Method = "POST"
+		c.Body = strings.Join(data, "&")
+		if contentType == "" {
+			contentType = "application/x-www-form-urlencoded"
+		}
+	}
+	if method != "" {
+		c.Method = method
+	}
+	if contentType != "" {
+		c.Headers = append(c.Headers, [2]string{"Content-Type", contentType})
+	}
+	return c, nil
+}
+
+// splitShellWords splits s into words as a POSIX shell does, handling
+// quotes, backslash escapes and line continuations, but no expansion.
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '\\':
+			if i++; i == len(s) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			if s[i] != '\n' {
+				word.WriteByte(s[i])
+				inWord = true
+			}
+		case ch == '\'':
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+j])
+			i += j + 1
+			inWord = true
+		case ch == '"':
+			for i++; ; i++ {
+				if i == len(s) {
+					return nil, fmt.Errorf("unterminated double quote")
+				}
+				if s[i] == '"' {
+					break
+				}
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+			inWord = true
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(ch)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCurl(t *testing.T) {
+	c, err := parseCurl(`curl -sSk -X PUT 'https://example.com/api?a=1' \
+  -H "Content-Type: application/json" -H'X-Id: 1' \
+  --data-raw '{"name": "it'"'"'s"}' -u user:pass`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &curlRequest{
+		URL:    "https://example.com/api?a=1",
+		Method: "PUT",
+		Headers: [][2]string{
+			{"X-Id", "1"},
+			{"Authorization", "Basic dXNlcjpwYXNz"},
+			{"Content-Type", "application/json"},
+		},
+		Body:     `{"name": "it's"}`,
+		Insecure: true,
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("Expected %+v, found %+v", expected, c)
+	}
+
+	c, err = parseCurl(`curl localhost:8080 -d a=1 --data-urlencode "b=x y"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.URL != "http://localhost:8080" || c.Method != "POST" || c.Body != "a=1&b=x+y" || c.Headers[0][1] != "application/x-www-form-urlencoded" {
+		t.Errorf("Unexpected form request %+v", c)
+	}
+
+	for _, bad := range []string{"", "wget http://example.com", "curl", "curl -F a=@file http://example.com", "curl -d @file http://example.com", "curl 'http://example.com"} {
+		if _, err := parseCurl(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// debugRequest sends a single request with b, printing it to w as it was
+// sent, authenticated and signed, along with its response, so that
+// configuration mistakes are caught before the run, see -debug.
+func debugRequest(b *boomer.Boomer, w io.Writer) {
+	n, c, quiet, renderer, after := b.N, b.C, b.Quiet, b.Renderer, b.AfterResponse
+	b.N, b.C, b.Quiet = 1, 1, true
+	b.Renderer = boomer.RendererFunc(func(io.Writer, *boomer.Report) error { return nil })
+	b.AfterResponse = func(req *fasthttp.Request, resp *fasthttp.Response, err error) {
+		printExchange(w, req, resp, err)
+	}
+	b.Run()
+	b.N, b.C, b.Quiet, b.Renderer, b.AfterResponse = n, c, quiet, renderer, after
+}
+
+// printExchange prints req and resp, or the error that prevented the
+// response.
+func printExchange(w io.Writer, req *fasthttp.Request, resp *fasthttp.Response, err error) {
+	fmt.Fprintf(w, "Request:\n\n")
+	printMessage(w, req.Header.Header(), req.Body())
+	_, assertion := err.(*boomer.AssertionError)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n\n", err)
+	}
+	// A transport error leaves the response incomplete.
+	if err == nil || assertion {
+		fmt.Fprintf(w, "Response:\n\n")
+		printMessage(w, resp.Header.Header(), responseBody(resp))
+	}
+}
+
+// printMessage prints the raw header of a message, which ends with an
+// empty line, and its body if any.
+func printMessage(w io.Writer, header, body []byte) {
+	w.Write(header)
+	if len(body) > 0 {
+		fmt.Fprintf(w, "%s\n\n", body)
+	}
+}
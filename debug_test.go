@@ -0,0 +1,65 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDebugRequest(t *testing.T) {
+	var count int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Header().Set("X-Served-By", "test")
+		w.Write([]byte("hello " + r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL + "/debug")
+	req.Header.SetMethod("POST")
+	req.SetBodyString("ping")
+	quiet := boomer.RendererFunc(func(io.Writer, *boomer.Report) error { return nil })
+	b := &boomer.Boomer{
+		Request:  req,
+		N:        10,
+		C:        2,
+		Renderer: quiet,
+		BeforeRequest: func(req *fasthttp.Request) {
+			req.Header.Set("Authorization", "Bearer token")
+		},
+	}
+	var buf bytes.Buffer
+	debugRequest(b, &buf)
+	if count != 1 {
+		t.Errorf("Expected a single request, found %d", count)
+	}
+	for _, s := range []string{"POST /debug HTTP/1.1", "Authorization: Bearer token", "ping", "200 OK", "X-Served-By: test", "hello Bearer token"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Expected %q in the output, found %q", s, buf.String())
+		}
+	}
+	if b.N != 10 || b.C != 2 || b.AfterResponse != nil {
+		t.Errorf("Expected the options to be restored, found N %d and C %d", b.N, b.C)
+	}
+}
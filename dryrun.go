@@ -0,0 +1,80 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sschepens/pla/boomer"
+)
+
+// maxPlanTargets is the number of targets listed by the plan of -dry-run.
+const maxPlanTargets = 20
+
+// dryRun validates the run of b and connects to its targets, completing
+// the TLS handshakes, then prints its plan to w without sending any
+// request, see -dry-run.
+func dryRun(b *boomer.Boomer, w io.Writer) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	if err := b.Preflight(); err != nil {
+		return err
+	}
+	printPlan(w, b)
+	return nil
+}
+
+func printPlan(w io.Writer, b *boomer.Boomer) {
+	fmt.Fprintf(w, "Plan:\n")
+	fmt.Fprintf(w, "  Requests:\t%d\n", b.N)
+	fmt.Fprintf(w, "  Concurrency:\t%d\n", b.C)
+	switch {
+	case len(b.Schedule) > 0:
+		fmt.Fprintf(w, "  Rate:\treplayed, over %v\n", b.Schedule[b.N-1].Offset)
+	case b.Spike != nil:
+		s := b.Spike
+		fmt.Fprintf(w, "  Rate:\t%d qps, %d qps from %v to %v\n", s.Base, s.Peak, s.At, s.At+s.For)
+	case b.Burst > 0:
+		fmt.Fprintf(w, "  Rate:\tbursts of %d requests every %v\n", b.Burst, b.BurstInterval)
+	case b.Qps > 0 && b.AdaptiveQps:
+		fmt.Fprintf(w, "  Rate:\tup to %d qps, adaptive\n", b.Qps)
+	case b.Qps > 0:
+		fmt.Fprintf(w, "  Rate:\t%d qps\n", b.Qps)
+	default:
+		fmt.Fprintf(w, "  Rate:\tunlimited\n")
+	}
+	targets := b.Targets
+	if len(targets) == 0 {
+		targets = []boomer.Target{{Request: b.Request}}
+	}
+	fmt.Fprintf(w, "  Targets:\t%d\n", len(targets))
+	for i, t := range targets {
+		if i == maxPlanTargets {
+			fmt.Fprintf(w, "    and %d more\n", len(targets)-i)
+			break
+		}
+		weight := t.Weight
+		if weight < 1 {
+			weight = 1
+		}
+		fmt.Fprintf(w, "    [%d] %s %s", weight, t.Request.Header.Method(), t.Request.URI())
+		if t.Name != "" {
+			fmt.Fprintf(w, " (%s)", t.Name)
+		}
+		fmt.Fprintln(w)
+	}
+}
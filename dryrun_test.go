@@ -0,0 +1,60 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDryRun(t *testing.T) {
+	var count int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL + "/a")
+	b := &boomer.Boomer{
+		Targets: []boomer.Target{{Name: "a", Request: req, Weight: 3}},
+		N:       100,
+		C:       10,
+		Qps:     50,
+	}
+	var buf bytes.Buffer
+	if err := dryRun(b, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no request to be sent, found %d", count)
+	}
+	for _, s := range []string{"Requests:\t100", "Concurrency:\t10", "Rate:\t50 qps", "[3] GET " + server.URL + "/a (a)"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Expected %q in the plan, found %q", s, buf.String())
+		}
+	}
+
+	b.C = 200
+	if err := dryRun(b, &buf); err == nil {
+		t.Error("Expected an invalid concurrency to fail")
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// failureDump saves the request and the response of the first failed
+// requests of a run to a directory, see -save-failures. The failures are
+// the errors, failed assertions included, and the 5xx responses.
+type failureDump struct {
+	dir string
+	max int64
+	n   int64
+
+	mu  sync.Mutex
+	err error
+}
+
+func newFailureDump(dir string, max int) (*failureDump, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &failureDump{dir: dir, max: int64(max)}, nil
+}
+
+// save is a Boomer.AfterResponse hook. It saves every failure, up to max
+// if it is positive, to its own file.
+func (d *failureDump) save(req *fasthttp.Request, resp *fasthttp.Response, err error) {
+	_, assertion := err.(*boomer.AssertionError)
+	if err == nil && resp.StatusCode() < 500 {
+		return
+	}
+	n := atomic.AddInt64(&d.n, 1)
+	if d.max > 0 && n > d.max {
+		return
+	}
+	var buf bytes.Buffer
+	if err != nil {
+		fmt.Fprintf(&buf, "Error: %v\n\n", err)
+	}
+	buf.WriteString(req.String())
+	// The response is only known for the failed assertions, a transport
+	// error leaves it incomplete.
+	if err == nil || assertion {
+		buf.WriteString("\n\n")
+		buf.Write(resp.Header.Header())
+		buf.Write(responseBody(resp))
+	}
+	buf.WriteString("\n")
+	path := filepath.Join(d.dir, fmt.Sprintf("failure-%04d.txt", n))
+	if werr := ioutil.WriteFile(path, buf.Bytes(), 0644); werr != nil {
+		d.mu.Lock()
+		if d.err == nil {
+			d.err = werr
+		}
+		d.mu.Unlock()
+	}
+}
+
+// responseBody returns the body of resp, decompressed for readability
+// when possible.
+func responseBody(resp *fasthttp.Response) []byte {
+	body, err := boomer.DecompressedBody(resp)
+	if err != nil {
+		return resp.Body()
+	}
+	return body
+}
+
+// saved returns the number of failures saved so far, and the first error
+// met while saving them.
+func (d *failureDump) saved() (int64, error) {
+	n := atomic.LoadInt64(&d.n)
+	if d.max > 0 && n > d.max {
+		n = d.max
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return n, d.err
+}
@@ -0,0 +1,84 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+func TestFailureDump(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "out of order", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("unexpected"))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "pla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	d, err := newFailureDump(filepath.Join(dir, "failures"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	quiet := boomer.RendererFunc(func(io.Writer, *boomer.Report) error { return nil })
+	for _, path := range []string{"/ok", "/fail"} {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(server.URL + path)
+		b := &boomer.Boomer{
+			Request:       req,
+			N:             2,
+			C:             1,
+			Output:        "json",
+			Renderer:      quiet,
+			Assertions:    []boomer.Assertion{boomer.ExpectBodyRegexp(regexp.MustCompile("^ok"))},
+			AfterResponse: d.save,
+		}
+		b.Run()
+	}
+
+	if n, err := d.saved(); n != 3 || err != nil {
+		t.Fatalf("Expected 3 failures saved, found %d and %v", n, err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "failures", "*"))
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files, found %v", files)
+	}
+	first, _ := ioutil.ReadFile(filepath.Join(dir, "failures", "failure-0001.txt"))
+	for _, s := range []string{"Error: assertion failed", "GET /ok HTTP/1.1", "unexpected"} {
+		if !strings.Contains(string(first), s) {
+			t.Errorf("Expected the failure to contain %q, found %q", s, first)
+		}
+	}
+	last, _ := ioutil.ReadFile(filepath.Join(dir, "failures", "failure-0003.txt"))
+	if !strings.Contains(string(last), "GET /fail HTTP/1.1") {
+		t.Errorf("Expected the request of the 5xx response, found %q", last)
+	}
+}
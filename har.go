@@ -0,0 +1,99 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// har is the subset of an HTTP Archive used by `pla import`.
+type har struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method   string `json:"method"`
+				URL      string `json:"url"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// runImport runs `pla import` with its arguments.
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Usage = flag.Usage
+	harFile := fs.String("har", "", "")
+	fs.Parse(args)
+	if *harFile == "" || fs.NArg() > 0 {
+		return fmt.Errorf("import requires -har and no other argument")
+	}
+	f, err := os.Open(*harFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return importHAR(f, os.Stdout)
+}
+
+// importHAR converts the requests of the HAR read from r into a targets
+// file written to w. Identical requests are merged into a single target
+// weighted by their number, in order of first appearance. Only the body
+// and its content type are kept of the recorded headers, the others,
+// cookies first, being specific to the recorded session.
+func importHAR(r io.Reader, w io.Writer) error {
+	var doc har
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+	var lines []string
+	weights := make(map[string]int)
+	for _, e := range doc.Log.Entries {
+		req := e.Request
+		if !strings.HasPrefix(req.URL, "http://") && !strings.HasPrefix(req.URL, "https://") {
+			continue
+		}
+		var contentType, body string
+		if req.PostData != nil {
+			contentType, body = req.PostData.MimeType, req.PostData.Text
+		}
+		line := targetLine(req.Method, req.URL, contentType, body)
+		if weights[line] == 0 {
+			lines = append(lines, line)
+		}
+		weights[line]++
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("no http requests found")
+	}
+	fmt.Fprintf(w, "# Imported from a HAR of %d requests.\n", len(doc.Log.Entries))
+	for _, line := range lines {
+		if n := weights[line]; n > 1 {
+			line += fmt.Sprintf(" weight=%d", n)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestImportHAR(t *testing.T) {
+	input := `{"log": {"entries": [
+		{"request": {"method": "GET", "url": "https://example.com/"}},
+		{"request": {"method": "GET", "url": "data:image/png;base64,AAAA"}},
+		{"request": {"method": "POST", "url": "https://example.com/api", "postData": {"mimeType": "application/json", "text": "{\"q\": \"a b\"}"}}},
+		{"request": {"method": "GET", "url": "https://example.com/"}}
+	]}}`
+	var out bytes.Buffer
+	if err := importHAR(strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# Imported from a HAR of 4 requests.
+GET https://example.com/ weight=2
+POST https://example.com/api header=Content-Type:application%2Fjson body=%7B%22q%22:%20%22a%20b%22%7D
+`
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\nfound:\n%s", expected, out.String())
+	}
+
+	// The output is a valid targets file.
+	targets, err := parseTargets(&out, &fasthttp.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0].Weight != 2 || string(targets[1].Request.Body()) != `{"q": "a b"}` {
+		t.Errorf("Unexpected targets %+v", targets)
+	}
+
+	if err := importHAR(strings.NewReader(`{"log": {"entries": []}}`), &out); err == nil {
+		t.Errorf("Expected an error for a HAR without requests")
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// agentManifest is a Deployment of pla agents and the headless Service
+// resolving to their pods, see `pla manifest`. The agents listen on every
+// interface of their pod, with the token of the pla-agent Secret, which
+// is part of the manifest if the token is known.
+var agentManifest = template.Must(template.New("manifest").Parse(`{{if .Token}}apiVersion: v1
+kind: Secret
+metadata:
+  name: pla-agent
+stringData:
+  token: {{.Token}}
+---
+{{end}}apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: pla-agent
+  labels:
+    app: pla-agent
+spec:
+  replicas: {{.Pods}}
+  selector:
+    matchLabels:
+      app: pla-agent
+  template:
+    metadata:
+      labels:
+        app: pla-agent
+    spec:
+      containers:
+      - name: pla-agent
+        image: {{.Image}}
+        args: ["-agent-token", "env:PLA_AGENT_TOKEN", "agent", ":{{.Port}}"]
+        env:
+        - name: PLA_AGENT_TOKEN
+          valueFrom:
+            secretKeyRef:
+              name: pla-agent
+              key: token
+        ports:
+        - containerPort: {{.Port}}
+        readinessProbe:
+          tcpSocket:
+            port: {{.Port}}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: pla-agent
+spec:
+  clusterIP: None
+  selector:
+    app: pla-agent
+  ports:
+  - port: {{.Port}}
+`))
+
+// writeManifest writes the Kubernetes manifest of pods agents running
+// image to w, along with the Secret holding token if not empty.
+func writeManifest(w io.Writer, pods int, image, token string) error {
+	if pods < 1 {
+		return fmt.Errorf("the number of pods cannot be smaller than 1")
+	}
+	_, port, _ := net.SplitHostPort(defaultAgentAddr)
+	return agentManifest.Execute(w, struct {
+		Pods  int
+		Image string
+		Port  string
+		Token string
+	}{pods, image, port, token})
+}
+
+// kubeRolloutTimeout is the time allowed to the agent pods to be ready.
+const kubeRolloutTimeout = 5 * time.Minute
+
+// kubeForwardTimeout is the time allowed to kubectl port-forward to
+// reach an agent.
+const kubeForwardTimeout = 30 * time.Second
+
+// launchAgents applies the manifest of pods agents running image, with
+// token, in the cluster and namespace of the current kubectl context, and
+// forwards a local port to each of them once they are ready. It returns
+// the local addresses of the agents and the function tearing them down,
+// which deletes the resources of the manifest. On error, they are torn
+// down already.
+func launchAgents(pods int, image, token string) ([]string, func(), error) {
+	var manifest bytes.Buffer
+	if err := writeManifest(&manifest, pods, image, token); err != nil {
+		return nil, nil, err
+	}
+	var forwards []*exec.Cmd
+	var once sync.Once
+	teardown := func() {
+		once.Do(func() {
+			for _, cmd := range forwards {
+				cmd.Process.Kill()
+				cmd.Wait()
+			}
+			if _, err := kubectl(manifest.Bytes(), "delete", "--ignore-not-found", "-f", "-"); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		})
+	}
+	fail := func(err error) ([]string, func(), error) {
+		teardown()
+		return nil, nil, err
+	}
+
+	if _, err := kubectl(manifest.Bytes(), "apply", "-f", "-"); err != nil {
+		return fail(err)
+	}
+	if _, err := kubectl(nil, "rollout", "status", "deployment/pla-agent", "--timeout="+kubeRolloutTimeout.String()); err != nil {
+		return fail(err)
+	}
+	out, err := kubectl(nil, "get", "pods", "-l", "app=pla-agent", "--field-selector=status.phase=Running", "-o", "jsonpath={.items[*].metadata.name}")
+	if err != nil {
+		return fail(err)
+	}
+	names := strings.Fields(string(out))
+	if len(names) < pods {
+		return fail(fmt.Errorf("only %d of the %d agent pods are running", len(names), pods))
+	}
+	_, port, _ := net.SplitHostPort(defaultAgentAddr)
+	var addrs []string
+	for _, name := range names[:pods] {
+		addr, cmd, err := forwardPort(name, port)
+		if cmd != nil {
+			forwards = append(forwards, cmd)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, teardown, nil
+}
+
+// forwardPort forwards a free local port to port of pod with kubectl
+// port-forward, and returns the local address once it accepts
+// connections, along with the kubectl command to be killed.
+func forwardPort(pod, port string) (string, *exec.Cmd, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	addr := l.Addr().String()
+	l.Close()
+	_, local, _ := net.SplitHostPort(addr)
+	cmd := exec.Command("kubectl", "port-forward", "pod/"+pod, local+":"+port)
+	if err := cmd.Start(); err != nil {
+		return "", nil, err
+	}
+	for deadline := time.Now().Add(kubeForwardTimeout); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+			conn.Close()
+			return addr, cmd, nil
+		}
+	}
+	return "", cmd, fmt.Errorf("kubectl port-forward pod/%s: agent not reachable after %v", pod, kubeForwardTimeout)
+}
+
+// kubectl runs kubectl with args, reading stdin if not nil, and returns
+// its output.
+func kubectl(stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("kubectl", args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("kubectl %s: %v: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return out, nil
+}
+
+// newAgentToken returns a random token for the agents launched by pla.
+func newAgentToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// resolveAgents expands the dns:host:port addresses of addrs into an
+// address per IP of host, e.g. the pods behind a headless Service.
+func resolveAgents(addrs []string) ([]string, error) {
+	var resolved []string
+	for _, addr := range addrs {
+		if !strings.HasPrefix(addr, "dns:") {
+			resolved = append(resolved, addr)
+			continue
+		}
+		host, port, err := net.SplitHostPort(strings.TrimPrefix(addr, "dns:"))
+		if err != nil {
+			return nil, err
+		}
+		ips, err := net.LookupHost(host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			resolved = append(resolved, net.JoinHostPort(ip, port))
+		}
+	}
+	return resolved, nil
+}
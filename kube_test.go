@@ -0,0 +1,93 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWriteManifest(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeManifest(&buf, 4, "registry.local/pla:1.0", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"replicas: 4", "image: registry.local/pla:1.0", `args: ["-agent-token", "env:PLA_AGENT_TOKEN", "agent", ":7070"]`, "key: token", "clusterIP: None"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Expected the manifest to contain %q, found:\n%s", s, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "kind: Secret") {
+		t.Errorf("Expected no Secret without a token, found:\n%s", buf.String())
+	}
+	if err := writeManifest(&buf, 0, "pla", ""); err == nil {
+		t.Errorf("Expected an error for 0 pods")
+	}
+
+	buf.Reset()
+	if err := writeManifest(&buf, 1, "pla", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "apiVersion: v1\nkind: Secret") || !strings.Contains(buf.String(), "token: s3cret") {
+		t.Errorf("Expected the manifest to hold the Secret of the token, found:\n%s", buf.String())
+	}
+}
+
+func TestLaunchAgentsTeardown(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("kubectl is faked by a shell script")
+	}
+	dir, err := ioutil.TempDir("", "kubectl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	calls := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho \"$1\" >> " + calls + "\n[ \"$1\" != rollout ]\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if _, _, err := launchAgents(2, "pla", "s3cret"); err == nil {
+		t.Fatal("Expected an error when the pods are not ready")
+	}
+	b, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "apply\nrollout\ndelete\n" {
+		t.Errorf("Expected the pods to be deleted when not ready, found calls %q", b)
+	}
+}
+
+func TestResolveAgents(t *testing.T) {
+	addrs, err := resolveAgents([]string{"host1:7070", "dns:127.0.0.1:7071"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 || addrs[0] != "host1:7070" || addrs[1] != "127.0.0.1:7071" {
+		t.Errorf("Expected the dns address to be expanded, found %v", addrs)
+	}
+	if _, err := resolveAgents([]string{"dns:nohost"}); err == nil {
+		t.Errorf("Expected an error for an address without port")
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// openAPIMethods are the operations of a path item, in the order they
+// become targets.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// parseOpenAPI generates a request, copy of tmpl, for every operation of
+// the OpenAPI 3 JSON spec read from r, or only those whose operationId is
+// in ops if not empty. The operations are relative to base if set, or to
+// the first server of the spec. Parameters and bodies are filled from
+// their examples, defaults or schemas; only the required parameters are
+// sent.
+func parseOpenAPI(r io.Reader, base string, ops []string, tmpl *fasthttp.Request) ([]boomer.Target, error) {
+	var spec map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&spec); err != nil {
+		return nil, fmt.Errorf("could not parse the OpenAPI spec: %v", err)
+	}
+	if v, _ := spec["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		return nil, fmt.Errorf("only OpenAPI 3 specs are supported")
+	}
+	g := &openAPIGenerator{spec: spec}
+	if base == "" {
+		servers, _ := spec["servers"].([]interface{})
+		if len(servers) > 0 {
+			server, _ := g.resolve(servers[0]).(map[string]interface{})
+			base, _ = server["url"].(string)
+		}
+		if !strings.Contains(base, "://") {
+			return nil, fmt.Errorf("no absolute server url in the OpenAPI spec, give one as the url")
+		}
+	}
+	base = strings.TrimSuffix(base, "/")
+	selected := make(map[string]bool)
+	for _, op := range ops {
+		selected[op] = true
+	}
+
+	paths, _ := spec["paths"].(map[string]interface{})
+	names := make([]string, 0, len(paths))
+	for p := range paths {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	var targets []boomer.Target
+	for _, p := range names {
+		item, _ := g.resolve(paths[p]).(map[string]interface{})
+		common, _ := item["parameters"].([]interface{})
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ := op["operationId"].(string)
+			if len(selected) > 0 && !selected[id] {
+				continue
+			}
+			delete(selected, id)
+			params, _ := op["parameters"].([]interface{})
+			t, err := g.target(tmpl, base, strings.ToUpper(method), p, append(append([]interface{}(nil), common...), params...), op["requestBody"])
+			if err != nil {
+				return nil, fmt.Errorf("operation %s %s: %v", strings.ToUpper(method), p, err)
+			}
+			t.Name = id
+			if id == "" {
+				t.Name = strings.ToUpper(method) + " " + p
+			}
+			targets = append(targets, t)
+		}
+	}
+	for op := range selected {
+		return nil, fmt.Errorf("operation %q not found in the OpenAPI spec", op)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no operations found in the OpenAPI spec")
+	}
+	return targets, nil
+}
+
+type openAPIGenerator struct {
+	spec map[string]interface{}
+}
+
+// resolve follows the local $ref of node, if any.
+func (g *openAPIGenerator) resolve(node interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return node
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return node
+		}
+		var cur interface{} = g.spec
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.Replace(strings.Replace(part, "~1", "/", -1), "~0", "~", -1)
+			parent, _ := cur.(map[string]interface{})
+			cur = parent[part]
+		}
+		node = cur
+	}
+	return node
+}
+
+func (g *openAPIGenerator) target(tmpl *fasthttp.Request, base, method, path string, params []interface{}, body interface{}) (boomer.Target, error) {
+	t := boomer.Target{Request: &fasthttp.Request{}}
+	tmpl.CopyTo(t.Request)
+	t.Request.Header.SetMethod(method)
+	query := url.Values{}
+	for _, p := range params {
+		param, _ := g.resolve(p).(map[string]interface{})
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if required, _ := param["required"].(bool); !required && in != "path" {
+			continue
+		}
+		v := fmt.Sprint(g.example(param))
+		switch in {
+		case "path":
+			path = strings.Replace(path, "{"+name+"}", url.PathEscape(v), -1)
+		case "query":
+			query.Set(name, v)
+		case "header":
+			t.Request.Header.Set(name, v)
+		case "cookie":
+			t.Request.Header.SetCookie(name, v)
+		}
+	}
+	if strings.Contains(path, "{") {
+		return t, fmt.Errorf("undefined path parameter in %s", path)
+	}
+	uri := base + path
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	t.Request.SetRequestURI(uri)
+
+	if rb, ok := g.resolve(body).(map[string]interface{}); ok {
+		content, _ := rb["content"].(map[string]interface{})
+		types := make([]string, 0, len(content))
+		for ct := range content {
+			types = append(types, ct)
+		}
+		sort.Strings(types)
+		for _, ct := range types {
+			if !strings.Contains(ct, "json") {
+				continue
+			}
+			b, err := json.Marshal(g.example(content[ct]))
+			if err != nil {
+				return t, err
+			}
+			t.Request.SetBody(b)
+			t.Request.Header.SetContentType(ct)
+			break
+		}
+		if len(t.Request.Body()) == 0 && len(types) > 0 {
+			return t, fmt.Errorf("only JSON bodies are supported")
+		}
+	}
+	return t, nil
+}
+
+// example returns the example of a parameter or media type, from its
+// example, examples or schema.
+func (g *openAPIGenerator) example(node interface{}) interface{} {
+	m, _ := g.resolve(node).(map[string]interface{})
+	if v, ok := m["example"]; ok {
+		return v
+	}
+	if examples, ok := m["examples"].(map[string]interface{}); ok {
+		names := make([]string, 0, len(examples))
+		for name := range examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if ex, ok := g.resolve(examples[name]).(map[string]interface{}); ok {
+				if v, ok := ex["value"]; ok {
+					return v
+				}
+			}
+		}
+	}
+	return g.schemaExample(m["schema"], 0)
+}
+
+// schemaExample generates a value valid against schema.
+func (g *openAPIGenerator) schemaExample(node interface{}, depth int) interface{} {
+	s, _ := g.resolve(node).(map[string]interface{})
+	if s == nil || depth > 8 {
+		return nil
+	}
+	for _, k := range []string{"example", "default"} {
+		if v, ok := s[k]; ok {
+			return v
+		}
+	}
+	if enum, ok := s["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	for _, k := range []string{"allOf", "oneOf", "anyOf"} {
+		if all, ok := s[k].([]interface{}); ok && len(all) > 0 {
+			if k != "allOf" {
+				return g.schemaExample(all[0], depth+1)
+			}
+			merged := make(map[string]interface{})
+			for _, sub := range all {
+				if obj, ok := g.schemaExample(sub, depth+1).(map[string]interface{}); ok {
+					for name, v := range obj {
+						merged[name] = v
+					}
+				}
+			}
+			return merged
+		}
+	}
+	typ, _ := s["type"].(string)
+	switch {
+	case typ == "array":
+		return []interface{}{g.schemaExample(s["items"], depth+1)}
+	case typ == "object" || s["properties"] != nil:
+		obj := make(map[string]interface{})
+		props, _ := s["properties"].(map[string]interface{})
+		for name, prop := range props {
+			obj[name] = g.schemaExample(prop, depth+1)
+		}
+		return obj
+	case typ == "integer":
+		if v, ok := s["minimum"].(float64); ok {
+			return int64(v)
+		}
+		return 1
+	case typ == "number":
+		if v, ok := s["minimum"].(float64); ok {
+			return v
+		}
+		return 1.5
+	case typ == "boolean":
+		return true
+	case typ == "string":
+		switch s["format"] {
+		case "date":
+			return "2020-01-01"
+		case "date-time":
+			return "2020-01-01T00:00:00Z"
+		case "uuid":
+			return "00000000-0000-4000-8000-000000000000"
+		case "email":
+			return "user@example.com"
+		}
+		return "string"
+	}
+	return nil
+}
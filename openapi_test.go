@@ -0,0 +1,101 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+const petstore = `{
+	"openapi": "3.0.0",
+	"servers": [{"url": "http://api.example.com/v1"}],
+	"paths": {
+		"/pets/{petId}": {
+			"parameters": [{"$ref": "#/components/parameters/PetId"}],
+			"get": {"operationId": "getPet", "parameters": [
+				{"name": "fields", "in": "query", "schema": {"type": "string"}},
+				{"name": "X-Tenant", "in": "header", "required": true, "example": "acme"}
+			]}
+		},
+		"/pets": {
+			"post": {"operationId": "createPet", "parameters": [
+				{"name": "dry", "in": "query", "required": true, "schema": {"type": "boolean"}}
+			], "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}}}
+		}
+	},
+	"components": {
+		"parameters": {"PetId": {"name": "petId", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 7}}},
+		"schemas": {"Pet": {"type": "object", "properties": {
+			"name": {"type": "string", "example": "rex"},
+			"kind": {"type": "string", "enum": ["dog", "cat"]},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"born": {"type": "string", "format": "date"}
+		}}}
+	}
+}`
+
+func TestParseOpenAPI(t *testing.T) {
+	targets, err := parseOpenAPI(strings.NewReader(petstore), "", nil, &fasthttp.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, found %d", len(targets))
+	}
+	create, get := targets[0], targets[1]
+	if create.Name != "createPet" || string(create.Request.Header.Method()) != "POST" || create.Request.URI().String() != "http://api.example.com/v1/pets?dry=true" {
+		t.Errorf("Unexpected target %s %s %s", create.Name, create.Request.Header.Method(), create.Request.URI())
+	}
+	var pet map[string]interface{}
+	if err := json.Unmarshal(create.Request.Body(), &pet); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"name": "rex", "kind": "dog", "tags": []interface{}{"string"}, "born": "2020-01-01"}
+	if !reflect.DeepEqual(pet, expected) || string(create.Request.Header.ContentType()) != "application/json" {
+		t.Errorf("Expected %v, found %v", expected, pet)
+	}
+	if get.Request.URI().String() != "http://api.example.com/v1/pets/7" || string(get.Request.Header.Peek("X-Tenant")) != "acme" {
+		t.Errorf("Unexpected target %s %s", get.Request.URI(), get.Request.Header.String())
+	}
+
+	targets, err = parseOpenAPI(strings.NewReader(petstore), "http://localhost:8080/", []string{"getPet"}, &fasthttp.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets[0].Request.URI().String() != "http://localhost:8080/pets/7" {
+		t.Errorf("Expected the selected operation against the given url, found %v", targets)
+	}
+
+	anonymous := `{"openapi": "3.0.0", "paths": {"/pets/{petId}": {"delete": {"parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "integer"}}]}}}}`
+	targets, err = parseOpenAPI(strings.NewReader(anonymous), "http://localhost", nil, &fasthttp.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets[0].Name != "DELETE /pets/{petId}" {
+		t.Errorf("Expected an operation without id to be named after its path template, found %v", targets)
+	}
+
+	if _, err := parseOpenAPI(strings.NewReader(petstore), "", []string{"nope"}, &fasthttp.Request{}); err == nil {
+		t.Errorf("Expected an error for an unknown operation")
+	}
+	if _, err := parseOpenAPI(strings.NewReader(`{"swagger": "2.0"}`), "", nil, &fasthttp.Request{}); err == nil {
+		t.Errorf("Expected an error for a Swagger 2 spec")
+	}
+}
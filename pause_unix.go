@@ -0,0 +1,54 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sschepens/pla/boomer"
+)
+
+// pauseOnSignals pauses the run of b on SIGUSR1 and resumes it on
+// SIGUSR2. The returned function stops handling the signals.
+func pauseOnSignals(b *boomer.Boomer) func() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1, syscall.SIGUSR2)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case s := <-c:
+				if s == syscall.SIGUSR1 {
+					b.Pause()
+					fmt.Fprintf(os.Stderr, "\nPaused, send SIGUSR2 to pid %d to resume.\n", os.Getpid())
+				} else {
+					b.Resume()
+					fmt.Fprintf(os.Stderr, "\nResumed.\n")
+				}
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
@@ -15,7 +15,12 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
+
+	"github.com/sschepens/pla/boomer"
 )
 
 func TestParseValidHeaderFlag(t *testing.T) {
@@ -58,3 +63,154 @@ func TestParseAuthMetaCharacters(t *testing.T) {
 		t.Errorf("Could not parse an auth header with a plus sign in the user name")
 	}
 }
+
+func TestParseResolveFlag(t *testing.T) {
+	match, err := parseInputWithRegexp("example.com:443:10.0.0.1", resolveRegexp)
+	if err != nil {
+		t.Fatalf("A valid resolve flag was not parsed correctly: %v", err.Error())
+	}
+	if match[1] != "example.com" || match[2] != "443" || match[3] != "10.0.0.1" {
+		t.Errorf("A valid resolve flag was not parsed correctly, parsed values: %v %v %v", match[1], match[2], match[3])
+	}
+	match, err = parseInputWithRegexp("example.com:80:[::1]", resolveRegexp)
+	if err != nil || match[3] != "::1" {
+		t.Errorf("An IPv6 resolve flag was not parsed correctly: %v", match)
+	}
+	if _, err := parseInputWithRegexp("example.com:10.0.0.1", resolveRegexp); err == nil {
+		t.Errorf("A resolve flag without port passed parsing")
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	cases := map[string]float64{
+		"5%":   0.05,
+		"0.05": 0.05,
+		"100%": 1,
+		" 1% ": 0.01,
+	}
+	for in, want := range cases {
+		got, err := parsePercent(in)
+		if err != nil || got != want {
+			t.Errorf("parsePercent(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "abc", "150%", "-1", "2"} {
+		if _, err := parsePercent(in); err == nil {
+			t.Errorf("An invalid ratio %q passed parsing", in)
+		}
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	cases := map[string]int64{
+		"10Mbps":  1250000,
+		"8bps":    1,
+		"1.5MB/s": 1500000,
+		" 64KB/s": 64000,
+		"1Gbps":   125000000,
+	}
+	for in, want := range cases {
+		got, err := parseBandwidth(in)
+		if err != nil || got != want {
+			t.Errorf("parseBandwidth(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "10", "abcMbps", "1bps", "-1MB/s"} {
+		if _, err := parseBandwidth(in); err == nil {
+			t.Errorf("An invalid bandwidth %q passed parsing", in)
+		}
+	}
+}
+
+func TestParseThinkTime(t *testing.T) {
+	cases := map[string][2]time.Duration{
+		"200ms":      {200 * time.Millisecond, 0},
+		"200ms±50ms": {200 * time.Millisecond, 50 * time.Millisecond},
+		"1s+-1s":     {time.Second, time.Second},
+	}
+	for in, want := range cases {
+		d, jitter, err := parseThinkTime(in)
+		if err != nil || d != want[0] || jitter != want[1] {
+			t.Errorf("parseThinkTime(%q) = %v, %v, %v; want %v", in, d, jitter, err, want)
+		}
+	}
+	for _, in := range []string{"", "abc", "-1s", "10ms±20ms", "10ms±", "20ms + - 5ms"} {
+		if _, _, err := parseThinkTime(in); err == nil {
+			t.Errorf("An invalid think time %q passed parsing", in)
+		}
+	}
+}
+
+func TestReadSecret(t *testing.T) {
+	os.Setenv("PLA_TEST_TOKEN", "from-env")
+	defer os.Unsetenv("PLA_TEST_TOKEN")
+	f, err := ioutil.TempFile("", "pla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("from-file\n")
+	f.Close()
+
+	cases := map[string]string{
+		"plain":              "plain",
+		"env:PLA_TEST_TOKEN": "from-env",
+		"@" + f.Name():       "from-file",
+	}
+	for in, want := range cases {
+		got, err := readSecret(in)
+		if err != nil || got != want {
+			t.Errorf("readSecret(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := readSecret("env:PLA_TEST_UNSET"); err == nil {
+		t.Errorf("An unset environment variable was accepted")
+	}
+}
+
+func TestParseRotation(t *testing.T) {
+	f, err := ioutil.TempFile("", "pla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("curl/8.0\n\n  Mozilla/5.0 (X11; Linux x86_64)  \n")
+	f.Close()
+
+	r, err := parseRotation("User-Agent: @" + f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Name != "User-Agent" || len(r.Values) != 2 || r.Values[1] != "Mozilla/5.0 (X11; Linux x86_64)" {
+		t.Errorf("Expected 2 User-Agents, found %+v", r)
+	}
+	for _, in := range []string{"User-Agent: curl/8.0", "User-Agent @" + f.Name(), "User-Agent: @" + f.Name() + ".missing"} {
+		if _, err := parseRotation(in); err == nil {
+			t.Errorf("An invalid header rotation %q passed parsing", in)
+		}
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	clean := &boomer.Report{}
+	failed := &boomer.Report{ErrorDist: map[string]int{"timeout": 1}}
+	violations := []string{"p99 100ms is not < 50ms"}
+	for _, c := range []struct {
+		mode       string
+		report     *boomer.Report
+		violations []string
+		want       int
+	}{
+		{"thresholds", clean, nil, 0},
+		{"thresholds", failed, nil, 0},
+		{"thresholds", clean, violations, 2},
+		{"errors", clean, nil, 0},
+		{"errors", failed, nil, 2},
+		{"errors", clean, violations, 2},
+		{"never", failed, violations, 0},
+	} {
+		if got := exitStatus(c.mode, c.report, c.violations); got != c.want {
+			t.Errorf("exitStatus(%q) = %d with %v and %v; want %d", c.mode, got, c.report.ErrorDist, c.violations, c.want)
+		}
+	}
+}
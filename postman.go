@@ -0,0 +1,237 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// postmanCollection is the subset of a Postman collection, format v2,
+// used by -postman.
+type postmanCollection struct {
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanVariable `json:"variable"`
+}
+
+// postmanItem is either a request or a folder of items.
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request *postmanRequest `json:"request"`
+}
+
+type postmanRequest struct {
+	Method string          `json:"method"`
+	URL    json.RawMessage `json:"url"`
+	Header []struct {
+		Key      string `json:"key"`
+		Value    string `json:"value"`
+		Disabled bool   `json:"disabled"`
+	} `json:"header"`
+	Body *struct {
+		Mode       string            `json:"mode"`
+		Raw        string            `json:"raw"`
+		URLEncoded []postmanVariable `json:"urlencoded"`
+	} `json:"body"`
+}
+
+type postmanVariable struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Enabled  *bool  `json:"enabled"`
+	Disabled bool   `json:"disabled"`
+}
+
+func (v postmanVariable) active() bool {
+	return !v.Disabled && (v.Enabled == nil || *v.Enabled)
+}
+
+var postmanVarRegexp = regexp.MustCompile(`{{\s*([^{}]+?)\s*}}`)
+
+// parsePostman reads the requests of a Postman collection as targets,
+// copies of tmpl, named after their folders and names. The {{variables}}
+// are replaced by the values of the environment read from env, if not
+// nil, or else of the collection.
+func parsePostman(collection, env io.Reader, tmpl *fasthttp.Request) ([]boomer.Target, error) {
+	var c postmanCollection
+	if err := json.NewDecoder(collection).Decode(&c); err != nil {
+		return nil, fmt.Errorf("could not parse the postman collection: %v", err)
+	}
+	vars := make(map[string]string)
+	for _, v := range c.Variable {
+		if v.active() {
+			vars[v.Key] = v.Value
+		}
+	}
+	if env != nil {
+		var e struct {
+			Values []postmanVariable `json:"values"`
+		}
+		if err := json.NewDecoder(env).Decode(&e); err != nil {
+			return nil, fmt.Errorf("could not parse the postman environment: %v", err)
+		}
+		for _, v := range e.Values {
+			if v.active() {
+				vars[v.Key] = v.Value
+			}
+		}
+	}
+	subst := func(s string) string {
+		return postmanVarRegexp.ReplaceAllStringFunc(s, func(m string) string {
+			if v, ok := vars[postmanVarRegexp.FindStringSubmatch(m)[1]]; ok {
+				return v
+			}
+			return m
+		})
+	}
+
+	var targets []boomer.Target
+	var walk func(items []postmanItem, prefix string) error
+	walk = func(items []postmanItem, prefix string) error {
+		for _, item := range items {
+			if item.Request == nil {
+				if err := walk(item.Item, prefix+item.Name+"/"); err != nil {
+					return err
+				}
+				continue
+			}
+			t, err := item.Request.target(tmpl, subst)
+			if err != nil {
+				return fmt.Errorf("postman request %q: %v", prefix+item.Name, err)
+			}
+			t.Name = prefix + item.Name
+			if item.Name == "" {
+				t.Name = string(t.Request.Header.Method()) + " " + item.Request.pathTemplate()
+			}
+			targets = append(targets, t)
+		}
+		return nil
+	}
+	if err := walk(c.Item, ""); err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no requests found in the postman collection")
+	}
+	return targets, nil
+}
+
+func (p *postmanRequest) target(tmpl *fasthttp.Request, subst func(string) string) (boomer.Target, error) {
+	t := boomer.Target{Request: &fasthttp.Request{}}
+	tmpl.CopyTo(t.Request)
+
+	raw, ok := p.rawURL()
+	if !ok {
+		return t, fmt.Errorf("invalid url")
+	}
+	raw = subst(raw)
+	if postmanVarRegexp.MatchString(raw) {
+		return t, fmt.Errorf("undefined variable in %s", raw)
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	method := p.Method
+	if method == "" {
+		method = "GET"
+	}
+	t.Request.Header.SetMethod(strings.ToUpper(method))
+	t.Request.SetRequestURI(raw)
+	for _, h := range p.Header {
+		if !h.Disabled {
+			t.Request.Header.Set(h.Key, subst(h.Value))
+		}
+	}
+	if p.Body != nil {
+		switch p.Body.Mode {
+		case "raw":
+			t.Request.SetBodyString(subst(p.Body.Raw))
+		case "urlencoded":
+			form := url.Values{}
+			for _, v := range p.Body.URLEncoded {
+				if v.active() {
+					form.Add(v.Key, subst(v.Value))
+				}
+			}
+			t.Request.SetBodyString(form.Encode())
+			t.Request.Header.SetContentType("application/x-www-form-urlencoded")
+		case "":
+		default:
+			return t, fmt.Errorf("unsupported body mode %s", p.Body.Mode)
+		}
+	}
+	return t, nil
+}
+
+// rawURL returns the url of the request, which is either a string or an
+// object holding it as raw.
+func (p *postmanRequest) rawURL() (string, bool) {
+	var raw string
+	if err := json.Unmarshal(p.URL, &raw); err != nil {
+		var u struct {
+			Raw string `json:"raw"`
+		}
+		if err := json.Unmarshal(p.URL, &u); err != nil {
+			return "", false
+		}
+		raw = u.Raw
+	}
+	return raw, true
+}
+
+// pathTemplate returns the path of the url of the request, its variables
+// left as they are, e.g. /users/{{id}} or /users/:id.
+func (p *postmanRequest) pathTemplate() string {
+	raw, _ := p.rawURL()
+	if i := strings.IndexAny(raw, "?#"); i >= 0 {
+		raw = raw[:i]
+	}
+	if i := strings.Index(raw, "://"); i >= 0 {
+		raw = raw[i+3:]
+	}
+	if i := strings.Index(raw, "/"); i >= 0 {
+		return raw[i:]
+	}
+	return "/"
+}
+
+// readPostman returns the targets of the Postman collection at path, with
+// the environment at envPath, if set.
+func readPostman(path, envPath string, tmpl *fasthttp.Request) ([]boomer.Target, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var env io.Reader
+	if envPath != "" {
+		e, err := os.Open(envPath)
+		if err != nil {
+			return nil, err
+		}
+		defer e.Close()
+		env = e
+	}
+	return parsePostman(f, env, tmpl)
+}
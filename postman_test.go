@@ -0,0 +1,78 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestParsePostman(t *testing.T) {
+	collection := `{
+		"info": {"name": "api"},
+		"variable": [{"key": "host", "value": "localhost"}, {"key": "token", "value": "collection"}],
+		"item": [
+			{"name": "users", "item": [
+				{"name": "create", "request": {
+					"method": "POST",
+					"url": {"raw": "http://{{host}}/users"},
+					"header": [{"key": "Authorization", "value": "Bearer {{token}}"}, {"key": "X-Off", "value": "1", "disabled": true}],
+					"body": {"mode": "raw", "raw": "{\"name\": \"{{name}}\"}"}
+				}}
+			]},
+			{"name": "login", "request": {
+				"method": "POST",
+				"url": "{{host}}/login",
+				"body": {"mode": "urlencoded", "urlencoded": [{"key": "user", "value": "{{name}}"}]}
+			}},
+			{"request": {"url": "{{host}}/users/:id?fields=name"}}
+		]
+	}`
+	env := `{"values": [{"key": "token", "value": "env", "enabled": true}, {"key": "name", "value": "ann"}, {"key": "host", "value": "off", "enabled": false}]}`
+	tmpl := &fasthttp.Request{}
+	tmpl.Header.Set("X-Some", "value")
+	targets, err := parsePostman(strings.NewReader(collection), strings.NewReader(env), tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 3 {
+		t.Fatalf("Expected 3 targets, found %d", len(targets))
+	}
+	if targets[2].Name != "GET /users/:id" {
+		t.Errorf("Expected a request without name to be named after its path template, found %s", targets[2].Name)
+	}
+	create, login := targets[0].Request, targets[1].Request
+	if targets[0].Name != "users/create" || create.URI().String() != "http://localhost/users" || string(create.Header.Method()) != "POST" {
+		t.Errorf("Unexpected target %s %s %s", targets[0].Name, create.Header.Method(), create.URI())
+	}
+	if string(create.Header.Peek("Authorization")) != "Bearer env" || len(create.Header.Peek("X-Off")) != 0 || string(create.Header.Peek("X-Some")) != "value" {
+		t.Errorf("Unexpected headers %s", create.Header.String())
+	}
+	if string(create.Body()) != `{"name": "ann"}` {
+		t.Errorf("Unexpected body %s", create.Body())
+	}
+	if login.URI().String() != "http://localhost/login" || string(login.Body()) != "user=ann" || string(login.Header.ContentType()) != "application/x-www-form-urlencoded" {
+		t.Errorf("Unexpected login %s %s", login.URI(), login.Body())
+	}
+
+	if _, err := parsePostman(strings.NewReader(`{"item": [{"name": "a", "request": {"url": "{{nope}}/a"}}]}`), nil, tmpl); err == nil {
+		t.Errorf("Expected an error for an undefined variable")
+	}
+	if _, err := parsePostman(strings.NewReader(`{"item": []}`), nil, tmpl); err == nil {
+		t.Errorf("Expected an error for an empty collection")
+	}
+}
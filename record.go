@@ -0,0 +1,119 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sschepens/pla/boomer"
+)
+
+// record writes the results of the next run of b to path, see -record.
+// The returned function waits for all of them to be written.
+func record(b *boomer.Boomer, path string) (func() error, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	results := b.Results()
+	done := make(chan error, 1)
+	go func() {
+		w := boomer.NewResultWriter(f)
+		var err error
+		for res := range results {
+			// Keep draining the results on errors, the run waits for
+			// them to be received.
+			if err == nil {
+				err = w.Write(res)
+			}
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		done <- err
+	}()
+	return func() error { return <-done }, nil
+}
+
+// replay reports the results recorded at path with b, see `pla report`.
+func replay(b *boomer.Boomer, path string) (*boomer.Report, error) {
+	// A first pass counts the results, for the progress bar and the
+	// verbosity of the report to depend on them as for a run.
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := boomer.NewResultReader(f)
+	n := 0
+	for {
+		if _, err := r.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		n++
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	b.N = n
+	results := make(chan boomer.Result, 100)
+	go func() {
+		defer close(results)
+		r := boomer.NewResultReader(f)
+		for {
+			res, err := r.Read()
+			if err != nil {
+				return
+			}
+			results <- res
+		}
+	}()
+	return b.Aggregate(results), nil
+}
+
+// parsePercentiles parses comma separated percentiles, e.g. "50,90,99".
+func parsePercentiles(input string) ([]int, error) {
+	if input == "" {
+		return nil, nil
+	}
+	var pctls []int
+	for _, s := range strings.Split(input, ",") {
+		p, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || p < 1 || p > 99 {
+			return nil, fmt.Errorf("could not parse the provided percentiles; input = %v", input)
+		}
+		pctls = append(pctls, p)
+	}
+	return pctls, nil
+}
+
+func containsInt(s []int, v int) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "pla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "results.bin")
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	quiet := boomer.RendererFunc(func(io.Writer, *boomer.Report) error { return nil })
+	b := &boomer.Boomer{Request: req, N: 20, C: 2, Output: "json", Renderer: quiet}
+	wait, err := record(b, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := b.Run()
+	if err := wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := replay(&boomer.Boomer{Output: "json", Renderer: quiet, Percentiles: []int{50}}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Count != run.Count || report.SizeTotal != run.SizeTotal || report.Fastest != run.Fastest || report.Slowest != run.Slowest {
+		t.Errorf("Expected the replay to match the run, found %+v and %+v", report, run)
+	}
+	if len(report.Latencies) != 1 || report.Latencies[0].Percentage != 50 {
+		t.Errorf("Expected the median only, found %v", report.Latencies)
+	}
+}
+
+func TestParsePercentiles(t *testing.T) {
+	if pctls, err := parsePercentiles("50, 99"); err != nil || !reflect.DeepEqual(pctls, []int{50, 99}) {
+		t.Errorf("Expected [50 99], found %v and %v", pctls, err)
+	}
+	for _, s := range []string{"0", "100", "99.9", "p99"} {
+		if _, err := parsePercentiles(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
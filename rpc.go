@@ -0,0 +1,154 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, one per line on the standard
+// input of `pla rpc`.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// rpcRunParams are the parameters of the run method, a subset of the
+// command line options.
+type rpcRunParams struct {
+	URL              string            `json:"url"`
+	Method           string            `json:"method"`
+	Headers          map[string]string `json:"headers"`
+	Body             string            `json:"body"`
+	N                int               `json:"n"`
+	C                int               `json:"c"`
+	Qps              int               `json:"qps"`
+	Timeout          string            `json:"timeout"`
+	AllowInsecure    bool              `json:"allow_insecure"`
+	DisableKeepAlive bool              `json:"disable_keepalive"`
+}
+
+// serveRPC answers the JSON-RPC requests read from r, one per line, until
+// r is exhausted. The only method is run, which makes the requests
+// described by its parameters and returns the report:
+//
+//	{"jsonrpc": "2.0", "id": 1, "method": "run", "params": {"url": "http://localhost:8080", "n": 100, "c": 10}}
+//
+// Runs are made one at a time.
+func serveRPC(r io.Reader, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0"}
+		var req rpcRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = &rpcError{Code: rpcParseError, Message: err.Error()}
+		} else {
+			resp.ID = req.ID
+			resp.Result, resp.Error = rpcCall(req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func rpcCall(req rpcRequest) (interface{}, *rpcError) {
+	if req.Method != "run" {
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+	params := rpcRunParams{Method: "GET", N: 200, C: 50}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+	}
+	b, err := params.boomer()
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return b.Run(), nil
+}
+
+// boomer returns the Boomer of a run. Its report is only returned, not
+// rendered: the standard output is the RPC channel.
+func (p rpcRunParams) boomer() (*boomer.Boomer, error) {
+	if p.URL == "" {
+		return nil, fmt.Errorf("missing url")
+	}
+	if p.N <= 0 || p.C <= 0 {
+		return nil, fmt.Errorf("n and c cannot be smaller than 1")
+	}
+	var timeout time.Duration
+	if p.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(p.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	req := fasthttp.AcquireRequest()
+	req.Header.SetMethod(p.Method)
+	req.SetRequestURI(p.URL)
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+	if p.Body != "" {
+		req.SetBodyString(p.Body)
+	}
+	return &boomer.Boomer{
+		Request:       req,
+		N:             p.N,
+		C:             p.C,
+		Qps:           p.Qps,
+		Timeout:       timeout,
+		AllowInsecure: p.AllowInsecure,
+		Client:        boomer.ClientOptions{DisableKeepAlive: p.DisableKeepAlive},
+		// Any output but the summary keeps the progress bar quiet.
+		Output:   "json",
+		Renderer: boomer.RendererFunc(func(io.Writer, *boomer.Report) error { return nil }),
+	}, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeRPC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "yes" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	in := strings.Join([]string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "run", "params": {"url": "` + server.URL + `", "n": 10, "c": 2, "headers": {"X-Test": "yes"}}}`,
+		`{"jsonrpc": "2.0", "id": "two", "method": "stop"}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "run", "params": {"n": 10}}`,
+		`not json`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := serveRPC(strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+	type response struct {
+		ID     json.RawMessage
+		Result *struct {
+			Count          int64
+			StatusCodeDist map[int]int
+		}
+		Error *rpcError
+	}
+	var responses []response
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r response
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, r)
+	}
+	if len(responses) != 4 {
+		t.Fatalf("Expected 4 responses, found %d: %s", len(responses), out.String())
+	}
+	if r := responses[0]; string(r.ID) != "1" || r.Result == nil || r.Result.StatusCodeDist[200] != 10 {
+		t.Errorf("Expected a report of 10 successful requests, found %+v", r.Result)
+	}
+	if r := responses[1]; string(r.ID) != `"two"` || r.Error == nil || r.Error.Code != rpcMethodNotFound {
+		t.Errorf("Expected an unknown method error, found %+v", r.Error)
+	}
+	if r := responses[2]; r.Error == nil || r.Error.Code != rpcInvalidParams {
+		t.Errorf("Expected an invalid params error, found %+v", r.Error)
+	}
+	if r := responses[3]; r.Error == nil || r.Error.Code != rpcParseError {
+		t.Errorf("Expected a parse error, found %+v", r.Error)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	_ "net/http/pprof"
+	"runtime"
+	"runtime/metrics"
+	"time"
+
+	"github.com/sschepens/pla/boomer"
+)
+
+// servePprof serves the net/http/pprof handlers on addr in the
+// background, for profiling pla itself during a run, see -pprof.
+func servePprof(addr string) {
+	go func() {
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			usageAndExit("-pprof: " + err.Error())
+		}
+	}()
+}
+
+// selfStats measures the resources used by pla during a run, to tell
+// whether the load generator rather than the target was the bottleneck,
+// see -self-stats.
+type selfStats struct {
+	start     time.Time
+	busy      float64
+	mem       runtime.MemStats
+	stop      chan struct{}
+	stopped   chan struct{}
+	heap      uint64
+	goroutine uint64
+}
+
+const (
+	cpuTotalMetric = "/cpu/classes/total:cpu-seconds"
+	cpuIdleMetric  = "/cpu/classes/idle:cpu-seconds"
+)
+
+// startSelfStats starts measuring. The heap and the goroutines are
+// sampled every 100ms for their peaks.
+func startSelfStats() *selfStats {
+	s := &selfStats{
+		start:   time.Now(),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	s.busy = cpuSeconds()
+	runtime.ReadMemStats(&s.mem)
+	go func() {
+		defer close(s.stopped)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			s.sample()
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *selfStats) sample() {
+	stats := boomer.ReadClientStats()
+	if stats.HeapBytes > s.heap {
+		s.heap = stats.HeapBytes
+	}
+	if stats.Goroutines > s.goroutine {
+		s.goroutine = stats.Goroutines
+	}
+}
+
+// cpuSeconds returns the CPU time used by the process so far. The
+// runtime only updates it on garbage collections, so one is forced
+// first.
+func cpuSeconds() float64 {
+	runtime.GC()
+	samples := []metrics.Sample{{Name: cpuTotalMetric}, {Name: cpuIdleMetric}}
+	metrics.Read(samples)
+	return samples[0].Value.Float64() - samples[1].Value.Float64()
+}
+
+// print stops measuring and writes the statistics to w.
+func (s *selfStats) print(w io.Writer) {
+	close(s.stop)
+	<-s.stopped
+	wall := time.Since(s.start)
+	// The memory statistics are read first, to leave out the collection
+	// forced by cpuSeconds.
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	busy := cpuSeconds()
+
+	fmt.Fprintf(w, "\nLoad generator:\n")
+	procs := runtime.GOMAXPROCS(0)
+	if sec := wall.Seconds(); sec > 0 {
+		fmt.Fprintf(w, "  CPU:\t%.1f%% of GOMAXPROCS %d\n", (busy-s.busy)/sec/float64(procs)*100, procs)
+	}
+	fmt.Fprintf(w, "  Peak heap:\t%.2f MB\n", float64(s.heap)/(1<<20))
+	fmt.Fprintf(w, "  Memory from the OS:\t%.2f MB\n", float64(mem.Sys)/(1<<20))
+	cycles := mem.NumGC - s.mem.NumGC
+	var longest uint64
+	for i := uint32(0); i < cycles && i < uint32(len(mem.PauseNs)); i++ {
+		if p := mem.PauseNs[(mem.NumGC-1-i)%uint32(len(mem.PauseNs))]; p > longest {
+			longest = p
+		}
+	}
+	fmt.Fprintf(w, "  GC:\t%d cycles, %v paused, %v longest pause\n", cycles,
+		time.Duration(mem.PauseTotalNs-s.mem.PauseTotalNs), time.Duration(longest))
+	fmt.Fprintf(w, "  Peak goroutines:\t%d\n", s.goroutine)
+}
@@ -0,0 +1,42 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelfStats(t *testing.T) {
+	s := startSelfStats()
+	done := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		go func() { <-done }()
+	}
+	time.Sleep(150 * time.Millisecond)
+	close(done)
+	var w bytes.Buffer
+	s.print(&w)
+	for _, want := range []string{"  CPU:\t", "of GOMAXPROCS", "  Peak heap:\t", "  GC:\t", " cycles, "} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("Expected %q in the statistics, found:\n%s", want, w.String())
+		}
+	}
+	if s.goroutine < 10 {
+		t.Errorf("Expected at least 10 peak goroutines, found %d", s.goroutine)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sschepens/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// targetLine returns the line of a targets file describing a request.
+// The content type is only kept along with a body.
+func targetLine(method, uri, contentType, body string) string {
+	line := strings.ToUpper(method) + " " + uri
+	if body != "" {
+		if contentType != "" {
+			line += " header=" + url.PathEscape("Content-Type:"+contentType)
+		}
+		line += " body=" + url.PathEscape(body)
+	}
+	return line
+}
+
+// parseTargets reads a targets file. Every line that is neither empty nor
+// a comment describes a request as:
+//
+//	METHOD URL [name=NAME] [weight=N] [priority=N] [header=NAME:VALUE]... [body=BODY]
+//
+// The requests are copies of tmpl with the method and URL replaced, and
+// the headers and body set, if given URL escaped, e.g. body=a%20b.
+func parseTargets(r io.Reader, tmpl *fasthttp.Request) ([]boomer.Target, error) {
+	var targets []boomer.Target
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("targets line %d: expected a method and a url", line)
+		}
+		t := boomer.Target{Request: &fasthttp.Request{}}
+		tmpl.CopyTo(t.Request)
+		t.Request.Header.SetMethod(strings.ToUpper(fields[0]))
+		t.Request.SetRequestURI(fields[1])
+		for _, opt := range fields[2:] {
+			kv := strings.SplitN(opt, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("targets line %d: invalid option %q", line, opt)
+			}
+			var err error
+			switch kv[0] {
+			case "name":
+				t.Name = kv[1]
+			case "weight":
+				t.Weight, err = strconv.Atoi(kv[1])
+			case "priority":
+				t.Priority, err = strconv.Atoi(kv[1])
+			case "header":
+				var h string
+				if h, err = url.PathUnescape(kv[1]); err == nil {
+					nv := strings.SplitN(h, ":", 2)
+					if len(nv) != 2 {
+						err = fmt.Errorf("expected NAME:VALUE")
+						break
+					}
+					t.Request.Header.Set(strings.TrimSpace(nv[0]), strings.TrimSpace(nv[1]))
+				}
+			case "tag":
+				// A bare value is the value of the "tag" tag.
+				var tag string
+				if tag, err = url.PathUnescape(kv[1]); err == nil {
+					nv := strings.SplitN(tag, ":", 2)
+					if len(nv) == 1 {
+						nv = []string{"tag", nv[0]}
+					}
+					if boomer.ReservedTag(nv[0]) {
+						err = fmt.Errorf("%s is the name of a built-in breakdown", nv[0])
+						break
+					}
+					if t.Tags == nil {
+						t.Tags = make(map[string]string)
+					}
+					t.Tags[nv[0]] = nv[1]
+				}
+			case "body":
+				var body string
+				if body, err = url.PathUnescape(kv[1]); err == nil {
+					t.Request.SetBodyString(body)
+				}
+			default:
+				err = fmt.Errorf("unknown option")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("targets line %d: invalid option %q: %v", line, opt, err)
+			}
+		}
+		targets = append(targets, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets found")
+	}
+	return targets, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseTargets(t *testing.T) {
+	tmpl := &fasthttp.Request{}
+	tmpl.Header.Set("X-Some", "value")
+	input := `
+# comment
+GET http://example.com/search name=search weight=3 priority=1 tag=search tag=region:eu
+post http://example.com/report header=Content-Type:application%2Fjson body=%7B%22a%22:%201%7D
+`
+	targets, err := parseTargets(strings.NewReader(input), tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, found %v", len(targets))
+	}
+	first, second := targets[0], targets[1]
+	if first.Name != "search" || first.Weight != 3 || first.Priority != 1 {
+		t.Errorf("Options were not parsed correctly: %+v", first)
+	}
+	if len(first.Tags) != 2 || first.Tags["tag"] != "search" || first.Tags["region"] != "eu" || second.Tags != nil {
+		t.Errorf("Tags were not parsed correctly: %v and %v", first.Tags, second.Tags)
+	}
+	if string(second.Request.Header.Method()) != "POST" || second.Request.URI().String() != "http://example.com/report" {
+		t.Errorf("Request was not parsed correctly: %s %s", second.Request.Header.Method(), second.Request.URI())
+	}
+	if string(second.Request.Header.Peek("X-Some")) != "value" {
+		t.Errorf("Expected the template headers to be kept")
+	}
+	if string(second.Request.Header.ContentType()) != "application/json" || string(second.Request.Body()) != `{"a": 1}` {
+		t.Errorf("Expected the header and body to be unescaped, found %q and %q", second.Request.Header.ContentType(), second.Request.Body())
+	}
+
+	for _, bad := range []string{"", "GET", "GET http://example.com weight=x", "GET http://example.com foo=bar", "GET http://example.com header=x", "GET http://example.com body=%zz", "GET http://example.com tag=%zz", "GET http://example.com tag=status:x"} {
+		if _, err := parseTargets(strings.NewReader(bad), tmpl); err == nil {
+			t.Errorf("Invalid targets %q passed parsing", bad)
+		}
+	}
+}
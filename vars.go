@@ -0,0 +1,77 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// varRegexp matches the ${NAME} references expanded by expandVars.
+var varRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandVars replaces the ${NAME} references in s with the value of the
+// secret NAME, or else of the environment variable NAME. A reference to
+// an undefined variable is an error rather than an empty value.
+func expandVars(s string, secrets map[string]string) (string, error) {
+	var err error
+	out := varRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		if v, ok := secrets[name]; ok {
+			return v
+		}
+		if v, ok := os.LookupEnv(name); ok {
+			return v
+		}
+		if err == nil {
+			err = fmt.Errorf("undefined variable %s", name)
+		}
+		return ref
+	})
+	return out, err
+}
+
+// loadSecrets reads the NAME=value lines of the file at path, see
+// -secret-file. Empty lines and lines starting with # are skipped, and
+// the values may be quoted.
+func loadSecrets(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	secrets := make(map[string]string)
+	s := bufio.NewScanner(f)
+	for num := 1; s.Scan(); num++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("%s:%d: expected NAME=value", path, num)
+		}
+		name := strings.TrimSpace(strings.TrimPrefix(line[:i], "export "))
+		value, err := unquoteConfig(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, num, err)
+		}
+		secrets[name] = value
+	}
+	return secrets, s.Err()
+}
@@ -0,0 +1,71 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandVars(t *testing.T) {
+	os.Setenv("PLA_TEST_HOST", "example.com")
+	defer os.Unsetenv("PLA_TEST_HOST")
+	secrets := map[string]string{"API_KEY": "s3cr3t", "PLA_TEST_HOST": "secret.example.com"}
+
+	for s, want := range map[string]string{
+		"https://${PLA_TEST_HOST}/": "https://secret.example.com/",
+		"Authorization: ${API_KEY}": "Authorization: s3cr3t",
+		"${API_KEY}${API_KEY}":      "s3cr3ts3cr3t",
+		"$API_KEY and ${} are kept": "$API_KEY and ${} are kept",
+	} {
+		if got, err := expandVars(s, secrets); err != nil || got != want {
+			t.Errorf("Expected %q to expand to %q, found %q and %v", s, want, got, err)
+		}
+	}
+	if got, _ := expandVars("${PLA_TEST_HOST}", nil); got != "example.com" {
+		t.Errorf("Expected the environment variable, found %q", got)
+	}
+	if _, err := expandVars("${PLA_TEST_UNDEFINED}", secrets); err == nil || !strings.Contains(err.Error(), "PLA_TEST_UNDEFINED") {
+		t.Errorf("Expected an undefined variable to fail, found %v", err)
+	}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secrets.env")
+	ioutil.WriteFile(path, []byte("# Secrets.\nAPI_KEY=s3cr3t\nexport TOKEN = \"a b\"\n\nEMPTY=\n"), 0600)
+
+	secrets, err := loadSecrets(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"API_KEY": "s3cr3t", "TOKEN": "a b", "EMPTY": ""}
+	if !reflect.DeepEqual(secrets, want) {
+		t.Errorf("Expected %v, found %v", want, secrets)
+	}
+
+	ioutil.WriteFile(path, []byte("API_KEY\n"), 0600)
+	if _, err := loadSecrets(path); err == nil {
+		t.Error("Expected a line without a value to fail")
+	}
+}